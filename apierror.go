@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 版本化接口的路径前缀
+const apiV1Prefix = "/v1"
+
+// 错误码，客户端应根据错误码而不是错误信息判断错误类型，已发布的错误码不会修改含义
+// 压缩包和资源限制相关的错误码定义在archivecheck.go和limits.go中
+const (
+	// 通用
+	CodeBadRequest      = "bad_request"
+	CodeInternalError   = "internal_error"
+	CodeNotFound        = "not_found"
+	CodeServiceBusy     = "service_unavailable"
+	CodeInvalidParam    = "invalid_parameter"
+	CodeStorageError    = "storage_error"
+	CodeFeatureDisabled = "feature_disabled"
+
+	// 认证和授权
+	CodeUnauthenticated   = "unauthenticated"
+	CodeInvalidAPIKey     = "invalid_api_key"
+	CodeInvalidToken      = "invalid_token"
+	CodeForbidden         = "forbidden"
+	CodeInsufficientScope = "insufficient_scope"
+
+	// 限流和配额
+	CodeRateLimited          = "rate_limited"
+	CodeDailyConversionQuota = "daily_conversion_quota_exceeded"
+	CodeDailyUploadQuota     = "daily_upload_quota_exceeded"
+
+	// 上传和转换
+	CodeMissingFile             = "missing_file"
+	CodeFileTooLarge            = "file_too_large"
+	CodeUnsupportedInputFormat  = "unsupported_input_format"
+	CodeUnsupportedOutputFormat = "unsupported_output_format"
+	CodeContentMismatch         = "content_format_mismatch"
+	CodeInputFormatNotAllowed   = "input_format_not_allowed"
+	CodeOutputFormatNotAllowed  = "output_format_not_allowed"
+	CodeInvalidTTL              = "invalid_ttl"
+	CodeInsufficientStorage     = "insufficient_storage"
+	CodeLibreOfficeUnavailable  = "libreoffice_unavailable"
+	CodeQueueTimeout            = "queue_timeout"
+	CodeConversionTimeout       = "conversion_timeout"
+	CodeConversionFailed        = "conversion_failed"
+
+	// 文件下载和管理
+	CodeInvalidPath      = "invalid_path"
+	CodeFileNotFound     = "file_not_found"
+	CodeFileExpired      = "file_expired"
+	CodeSignatureMissing = "signature_missing"
+	CodeSignatureInvalid = "signature_invalid"
+	CodeSignatureExpired = "signature_expired"
+)
+
+// APIError 版本化接口返回的错误对象
+type APIError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   string `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// APIErrorResponse 版本化接口的错误响应
+type APIErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// isV1Request 判断请求是否访问版本化接口，根据路径判断，全局中间件返回的错误也使用新的格式
+func isV1Request(c *gin.Context) bool {
+	path := c.Request.URL.Path
+	return path == apiV1Prefix || strings.HasPrefix(path, apiV1Prefix+"/")
+}
+
+// apiPathPrefix 返回当前请求所属接口版本的路径前缀，旧接口返回空字符串
+func apiPathPrefix(c *gin.Context) string {
+	if isV1Request(c) {
+		return apiV1Prefix
+	}
+	return ""
+}
+
+// defaultErrorCode 未指定错误码时根据HTTP状态码推断
+func defaultErrorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthenticated
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusRequestEntityTooLarge:
+		return CodeFileTooLarge
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeServiceBusy
+	}
+	if status >= 500 {
+		return CodeInternalError
+	}
+	return CodeBadRequest
+}
+
+// signatureErrorCode 返回下载链接校验错误对应的错误码
+func signatureErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrSignatureMissing):
+		return CodeSignatureMissing
+	case errors.Is(err, ErrSignatureExpired):
+		return CodeSignatureExpired
+	default:
+		return CodeSignatureInvalid
+	}
+}
+
+// errorBody 根据接口版本生成错误响应体，旧接口保持原有格式
+func errorBody(c *gin.Context, status int, resp ErrorResponse) interface{} {
+	if resp.Code == "" {
+		resp.Code = defaultErrorCode(status)
+	}
+	resp.RequestID = requestID(c)
+	if !isV1Request(c) {
+		return resp
+	}
+	return APIErrorResponse{Error: APIError{
+		Code:      resp.Code,
+		Message:   resp.Error,
+		Details:   resp.Details,
+		RequestID: resp.RequestID,
+	}}
+}
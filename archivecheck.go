@@ -0,0 +1,190 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// 压缩包检查的错误码
+const (
+	CodeArchiveInvalid      = "archive_invalid"
+	CodeArchiveTooLarge     = "archive_too_large"
+	CodeArchiveRatio        = "archive_compression_ratio_exceeded"
+	CodeArchiveTooManyFiles = "archive_too_many_entries"
+	CodeArchiveNested       = "archive_nesting_too_deep"
+)
+
+// 压缩包检查配置
+var (
+	MAX_ARCHIVE_UNCOMPRESSED_BYTES int64 // 解压后的总大小上限
+	MAX_ARCHIVE_COMPRESSION_RATIO  int   // 单个条目和整体的压缩比上限
+	MAX_ARCHIVE_ENTRIES            int   // 条目数上限（包括嵌套压缩包中的条目）
+	MAX_ARCHIVE_NESTING            int   // 允许的嵌套压缩包层数
+)
+
+// 压缩比只对解压后超过该大小的条目检查，避免小文件的高压缩比误报
+const ratioCheckMinBytes = 1024 * 1024
+
+// ArchiveError 压缩包超出限制或格式错误
+type ArchiveError struct {
+	Code string
+	Err  error // 可以翻译的错误详情
+}
+
+func (e *ArchiveError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ArchiveError) Unwrap() error {
+	return e.Err
+}
+
+// initArchiveCheckConfig 读取压缩包检查相关的环境变量
+func initArchiveCheckConfig() {
+	MAX_ARCHIVE_UNCOMPRESSED_BYTES = getEnvInt64("MAX_ARCHIVE_UNCOMPRESSED_BYTES", 1024*1024*1024)
+	MAX_ARCHIVE_COMPRESSION_RATIO = getEnvInt("MAX_ARCHIVE_COMPRESSION_RATIO", 100)
+	MAX_ARCHIVE_ENTRIES = getEnvInt("MAX_ARCHIVE_ENTRIES", 10000)
+	MAX_ARCHIVE_NESTING = getEnvInt("MAX_ARCHIVE_NESTING", 2)
+}
+
+// isZipExt 判断检测到的格式是否为基于ZIP的文档格式
+func isZipExt(ext string) bool {
+	switch ext {
+	case ".docx", ".xlsx", ".pptx", ".odt", ".ods", ".odp":
+		return true
+	}
+	return false
+}
+
+// archiveBudget 检查过程中累计的用量，嵌套压缩包计入同一份额度
+type archiveBudget struct {
+	entries      int
+	uncompressed int64
+}
+
+// inspectArchive 检查基于ZIP的文档是否为压缩炸弹
+// 先按目录中声明的大小快速检查，再实际解压每个条目计数，因为声明的大小可以伪造
+func inspectArchive(r io.ReaderAt, size int64) error {
+	budget := &archiveBudget{}
+	if err := inspectArchiveLevel(r, size, 0, budget); err != nil {
+		return err
+	}
+	if MAX_ARCHIVE_COMPRESSION_RATIO > 0 && budget.uncompressed > ratioCheckMinBytes &&
+		budget.uncompressed > size*int64(MAX_ARCHIVE_COMPRESSION_RATIO) {
+		return &ArchiveError{CodeArchiveRatio, newLocalizedError("detail.archive_total_ratio", MAX_ARCHIVE_COMPRESSION_RATIO)}
+	}
+	return nil
+}
+
+func inspectArchiveLevel(r io.ReaderAt, size int64, depth int, budget *archiveBudget) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return &ArchiveError{CodeArchiveInvalid, newLocalizedError("detail.archive_invalid", err)}
+	}
+
+	budget.entries += len(zr.File)
+	if MAX_ARCHIVE_ENTRIES > 0 && budget.entries > MAX_ARCHIVE_ENTRIES {
+		return &ArchiveError{CodeArchiveTooManyFiles, newLocalizedError("detail.archive_too_many_entries", MAX_ARCHIVE_ENTRIES)}
+	}
+
+	var declared uint64
+	for _, f := range zr.File {
+		declared += f.UncompressedSize64
+	}
+	if MAX_ARCHIVE_UNCOMPRESSED_BYTES > 0 && declared > uint64(MAX_ARCHIVE_UNCOMPRESSED_BYTES-budget.uncompressed) {
+		return &ArchiveError{CodeArchiveTooLarge, newLocalizedError("detail.archive_too_large", MAX_ARCHIVE_UNCOMPRESSED_BYTES)}
+	}
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		data, n, err := readArchiveEntry(f, budget)
+		if err != nil {
+			return err
+		}
+		if MAX_ARCHIVE_COMPRESSION_RATIO > 0 && n > ratioCheckMinBytes &&
+			n > int64(f.CompressedSize64+1)*int64(MAX_ARCHIVE_COMPRESSION_RATIO) {
+			return &ArchiveError{CodeArchiveRatio, newLocalizedError("detail.archive_entry_ratio", f.Name, MAX_ARCHIVE_COMPRESSION_RATIO)}
+		}
+		if data == nil {
+			continue
+		}
+		if depth+1 > MAX_ARCHIVE_NESTING {
+			return &ArchiveError{CodeArchiveNested, newLocalizedError("detail.archive_nesting", MAX_ARCHIVE_NESTING, f.Name)}
+		}
+		if err := inspectArchiveLevel(bytes.NewReader(data), int64(len(data)), depth+1, budget); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readArchiveEntry 解压一个条目并计入总大小，超出上限时立即停止
+// 条目本身是压缩包时返回其内容用于递归检查，否则只计数不保留内容
+func readArchiveEntry(f *zip.File, budget *archiveBudget) ([]byte, int64, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, 0, &ArchiveError{CodeArchiveInvalid, newLocalizedError("detail.archive_entry_unreadable", f.Name, err)}
+	}
+	defer rc.Close()
+
+	var limit int64 = -1
+	var reader io.Reader = rc
+	if MAX_ARCHIVE_UNCOMPRESSED_BYTES > 0 {
+		limit = MAX_ARCHIVE_UNCOMPRESSED_BYTES - budget.uncompressed
+		reader = io.LimitReader(rc, limit+1)
+	}
+
+	head := make([]byte, len(zipMagic))
+	hn, err := io.ReadFull(reader, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, 0, &ArchiveError{CodeArchiveInvalid, newLocalizedError("detail.archive_entry_unreadable", f.Name, err)}
+	}
+	nested := bytes.Equal(head[:hn], zipMagic)
+
+	var buf bytes.Buffer
+	var sink io.Writer = io.Discard
+	if nested {
+		// 嵌套压缩包需要完整读入内存才能检查，限制其大小
+		buf.Write(head)
+		sink = &limitedWriter{w: &buf, n: maxNestedArchiveBytes}
+	}
+	n, err := io.Copy(sink, reader)
+	n += int64(hn)
+	budget.uncompressed += n
+	if err == errNestedArchiveTooLarge {
+		return nil, n, &ArchiveError{CodeArchiveTooLarge, newLocalizedError("detail.archive_nested_too_large", f.Name, maxNestedArchiveBytes)}
+	}
+	if err != nil {
+		return nil, n, &ArchiveError{CodeArchiveInvalid, newLocalizedError("detail.archive_entry_unreadable", f.Name, err)}
+	}
+	if limit >= 0 && n > limit {
+		return nil, n, &ArchiveError{CodeArchiveTooLarge, newLocalizedError("detail.archive_too_large", MAX_ARCHIVE_UNCOMPRESSED_BYTES)}
+	}
+	if nested {
+		return buf.Bytes(), n, nil
+	}
+	return nil, n, nil
+}
+
+// 嵌套压缩包读入内存的大小上限
+const maxNestedArchiveBytes = 64 * 1024 * 1024
+
+var errNestedArchiveTooLarge = errors.New("嵌套压缩包过大")
+
+// limitedWriter 写入超过n字节时返回错误
+type limitedWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > l.n {
+		return 0, errNestedArchiveTooLarge
+	}
+	l.n -= int64(len(p))
+	return l.w.Write(p)
+}
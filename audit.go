@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 审计事件类型
+const (
+	AuditConvert  = "convert"
+	AuditDownload = "download"
+)
+
+// 审计事件结果
+const (
+	AuditSuccess  = "success"  // 请求成功
+	AuditRejected = "rejected" // 请求被拒绝，如认证失败、格式不支持、超出限制
+	AuditFailure  = "failure"  // 服务端处理失败
+)
+
+// 请求上下文中保存审计事件的键
+const auditContextKey = "audit_event"
+
+// 当前写入的审计日志文件名，轮转后的文件名为 audit-<时间>.log
+const (
+	auditFileName       = "audit.log"
+	auditRotatedPrefix  = "audit-"
+	auditRotatedSuffix  = ".log"
+	auditRotatedTimeFmt = "20060102T150405.000000000Z"
+)
+
+// 单次查询最多扫描的审计日志文件数（包括当前文件），从最新的文件开始扫描，更早的记录需要通过to参数查询
+const auditQueryMaxFiles = 10
+
+// 审计日志配置
+var (
+	AUDIT_LOG             bool   // 是否记录审计日志
+	AUDIT_LOG_DIR         string // 审计日志目录
+	AUDIT_LOG_MAX_SIZE_MB int    // 单个审计日志文件的大小上限，超出后轮转
+	AUDIT_LOG_MAX_FILES   int    // 保留的轮转文件数，0表示全部保留
+
+	auditLog *auditLogger
+)
+
+// AuditEvent 一条审计记录，以JSON行的形式追加写入审计日志
+type AuditEvent struct {
+	Time           time.Time `json:"time"`
+	RequestID      string    `json:"request_id,omitempty"`
+	Action         string    `json:"action"`
+	Client         string    `json:"client"`
+	Tenant         string    `json:"tenant,omitempty"`
+	IP             string    `json:"ip"`
+	FilenameSHA256 string    `json:"filename_sha256,omitempty"` // 原始文件名的SHA-256，日志中不保存文件名本身
+	Path           string    `json:"path,omitempty"`            // 转换结果或下载文件在存储中的路径
+	SourceFormat   string    `json:"source_format,omitempty"`
+	TargetFormat   string    `json:"target_format,omitempty"`
+	InputBytes     int64     `json:"input_bytes,omitempty"`
+	OutputBytes    int64     `json:"output_bytes,omitempty"`
+	DurationMS     int64     `json:"duration_ms"`
+	Status         int       `json:"status"`
+	Outcome        string    `json:"outcome"`
+}
+
+// AuditQueryResponse 审计日志查询响应
+type AuditQueryResponse struct {
+	Events    []AuditEvent `json:"events"`
+	Count     int          `json:"count"`
+	Truncated bool         `json:"truncated"` // 匹配的记录超过limit或超出扫描的文件数，只返回最新的部分
+}
+
+// auditLogger 只追加写入的审计日志，超过大小上限时轮转
+type auditLogger struct {
+	mu   sync.Mutex
+	dir  string
+	file *os.File
+	size int64
+}
+
+// initAuditConfig 读取审计日志相关的环境变量并打开审计日志
+func initAuditConfig() {
+	AUDIT_LOG = getEnvBool("AUDIT_LOG", true)
+	AUDIT_LOG_DIR = getEnvString("AUDIT_LOG_DIR", filepath.Join(BASE_DIR, "logs"))
+	AUDIT_LOG_MAX_SIZE_MB = getEnvInt("AUDIT_LOG_MAX_SIZE_MB", 100)
+	AUDIT_LOG_MAX_FILES = getEnvInt("AUDIT_LOG_MAX_FILES", 0)
+
+	if !AUDIT_LOG {
+		log.Println("审计日志未启用")
+		return
+	}
+	logger, err := openAuditLogger(AUDIT_LOG_DIR)
+	if err != nil {
+		log.Fatalf("打开审计日志失败: %v", err)
+	}
+	auditLog = logger
+	log.Printf("审计日志: %s", filepath.Join(AUDIT_LOG_DIR, auditFileName))
+}
+
+func openAuditLogger(dir string) (*auditLogger, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+	l := &auditLogger{dir: dir}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// open 以追加方式打开当前审计日志文件
+func (l *auditLogger) open() error {
+	f, err := os.OpenFile(filepath.Join(l.dir, auditFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file = f
+	l.size = info.Size()
+	return nil
+}
+
+// write 追加一条审计记录
+func (l *auditLogger) write(ctx context.Context, event AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	maxSize := int64(AUDIT_LOG_MAX_SIZE_MB) << 20
+	if maxSize > 0 && l.size > 0 && l.size+int64(len(line)) > maxSize {
+		if err := l.rotate(ctx); err != nil {
+			loggerFrom(ctx).Error("轮转审计日志失败", "error", err)
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+// rotate 将当前文件改名为带轮转时间的文件并重新打开，轮转后的文件不再修改
+func (l *auditLogger) rotate(ctx context.Context) error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	rotated := auditRotatedPrefix + time.Now().UTC().Format(auditRotatedTimeFmt) + auditRotatedSuffix
+	if err := os.Rename(filepath.Join(l.dir, auditFileName), filepath.Join(l.dir, rotated)); err != nil {
+		// 改名失败时继续写入原文件，不能丢失审计记录
+		if openErr := l.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := l.open(); err != nil {
+		return err
+	}
+	l.prune(ctx)
+	return nil
+}
+
+// prune 删除超出保留数量的最旧的轮转文件
+func (l *auditLogger) prune(ctx context.Context) {
+	if AUDIT_LOG_MAX_FILES <= 0 {
+		return
+	}
+	rotated, err := l.rotatedFiles()
+	if err != nil {
+		loggerFrom(ctx).Error("列出审计日志失败", "error", err)
+		return
+	}
+	for len(rotated) > AUDIT_LOG_MAX_FILES {
+		if err := os.Remove(filepath.Join(l.dir, rotated[0])); err != nil {
+			loggerFrom(ctx).Error("删除旧审计日志失败", "file", rotated[0], "error", err)
+		}
+		rotated = rotated[1:]
+	}
+}
+
+// rotatedFiles 返回按轮转时间从旧到新排序的轮转文件名
+func (l *auditLogger) rotatedFiles() ([]string, error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() && strings.HasPrefix(name, auditRotatedPrefix) && strings.HasSuffix(name, auditRotatedSuffix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// auditFilter 审计日志查询条件，空值表示不限制
+type auditFilter struct {
+	from    time.Time
+	to      time.Time
+	action  string
+	client  string
+	tenant  string
+	outcome string
+}
+
+func (f auditFilter) match(e AuditEvent) bool {
+	return (f.from.IsZero() || !e.Time.Before(f.from)) &&
+		(f.to.IsZero() || e.Time.Before(f.to)) &&
+		(f.action == "" || e.Action == f.action) &&
+		(f.client == "" || e.Client == f.client) &&
+		(f.tenant == "" || e.Tenant == f.tenant) &&
+		(f.outcome == "" || e.Outcome == f.outcome)
+}
+
+// query 从最新的文件开始扫描审计日志，返回最新的limit条匹配记录（新的在前）
+// 最多扫描auditQueryMaxFiles个文件，还有未扫描的文件时truncated为true
+func (l *auditLogger) query(filter auditFilter, limit int) ([]AuditEvent, bool, error) {
+	l.mu.Lock()
+	rotated, err := l.rotatedFiles()
+	l.mu.Unlock()
+	if err != nil {
+		return nil, false, err
+	}
+
+	// 轮转文件中的记录都早于文件名中的轮转时间，且不早于上一个文件的轮转时间
+	var files []string
+	var previous time.Time
+	for _, name := range append(rotated, auditFileName) {
+		var rotatedAt time.Time
+		if name != auditFileName {
+			rotatedAt, _ = time.Parse(auditRotatedTimeFmt, strings.TrimSuffix(strings.TrimPrefix(name, auditRotatedPrefix), auditRotatedSuffix))
+		}
+		if (filter.from.IsZero() || rotatedAt.IsZero() || !rotatedAt.Before(filter.from)) &&
+			(filter.to.IsZero() || previous.IsZero() || previous.Before(filter.to)) {
+			files = append(files, name)
+		}
+		if !rotatedAt.IsZero() {
+			previous = rotatedAt
+		}
+	}
+
+	var events []AuditEvent
+	for i := len(files) - 1; i >= 0; i-- {
+		if len(files)-i > auditQueryMaxFiles {
+			return events, true, nil
+		}
+		matched, more, err := scanAuditFile(filepath.Join(l.dir, files[i]), filter, limit-len(events))
+		if err != nil {
+			return nil, false, fmt.Errorf("读取审计日志%s失败: %w", files[i], err)
+		}
+		for j := len(matched) - 1; j >= 0; j-- {
+			events = append(events, matched[j])
+		}
+		if len(events) >= limit {
+			return events, more || i > 0, nil
+		}
+	}
+	return events, false, nil
+}
+
+// scanAuditFile 按时间顺序返回文件中最新的limit条匹配记录，more表示还有更早的匹配记录
+func scanAuditFile(path string, filter auditFilter, limit int) ([]AuditEvent, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// 查询期间文件可能被轮转或清理
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	defer f.Close()
+
+	var events []AuditEvent
+	more := false
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || !filter.match(e) {
+			continue
+		}
+		events = append(events, e)
+		if len(events) > limit {
+			events = events[1:]
+			more = true
+		}
+	}
+	return events, more, scanner.Err()
+}
+
+// hashFilename 返回文件名的SHA-256十六进制摘要
+func hashFilename(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:])
+}
+
+// auditMiddleware 在请求结束后记录审计事件并更新指标，需放在requireScope之前，以便记录被拒绝的请求
+// 处理函数通过auditEventFrom补充文件、格式和大小等信息
+func auditMiddleware(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		event := &AuditEvent{Time: start.UTC(), Action: action}
+		c.Set(auditContextKey, event)
+		c.Next()
+
+		event.RequestID = requestID(c)
+		event.Client = callerIdentity(c)
+		event.Tenant = currentTenant(c).tenantID()
+		event.IP = c.ClientIP()
+		event.DurationMS = time.Since(start).Milliseconds()
+		event.Status = c.Writer.Status()
+		switch {
+		case event.Status < 400:
+			event.Outcome = AuditSuccess
+		case event.Status < 500:
+			event.Outcome = AuditRejected
+		default:
+			event.Outcome = AuditFailure
+		}
+		recordRequestMetrics(event)
+		if auditLog == nil {
+			return
+		}
+		if err := auditLog.write(c.Request.Context(), *event); err != nil {
+			requestLogger(c).Error("写入审计日志失败", "error", err)
+		}
+	}
+}
+
+// auditEventFrom 返回当前请求的审计事件，不在auditMiddleware之后时返回一个不会被记录的事件
+func auditEventFrom(c *gin.Context) *AuditEvent {
+	if v, ok := c.Get(auditContextKey); ok {
+		if e, ok := v.(*AuditEvent); ok {
+			return e
+		}
+	}
+	return &AuditEvent{}
+}
+
+// 审计日志查询处理
+// 属于某个租户的管理员只能查询该租户的记录；未启用认证时任何人都能通过requireScope，
+// 而审计记录包含客户端IP和文件路径，因此不允许查询
+func auditQueryHandler(c *gin.Context) {
+	if !authEnabled() {
+		respondError(c, http.StatusForbidden, ErrorResponse{Error: tr(c, "error.audit_query_requires_auth"), Code: CodeFeatureDisabled})
+		return
+	}
+	if auditLog == nil {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: tr(c, "error.audit_disabled"), Code: CodeFeatureDisabled})
+		return
+	}
+
+	filter := auditFilter{
+		action:  c.Query("action"),
+		client:  c.Query("client"),
+		tenant:  c.Query("tenant"),
+		outcome: c.Query("outcome"),
+	}
+	for _, p := range []struct {
+		name   string
+		target *time.Time
+	}{{"from", &filter.from}, {"to", &filter.to}} {
+		value := c.Query(p.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, ErrorResponse{
+				Error:   tr(c, "error.invalid_time"),
+				Code:    CodeInvalidParam,
+				Details: tr(c, "detail.invalid_time", p.name),
+			})
+			return
+		}
+		*p.target = t
+	}
+	if tenant := currentTenant(c).tenantID(); tenant != "" {
+		if filter.tenant != "" && filter.tenant != tenant {
+			respondError(c, http.StatusForbidden, ErrorResponse{Error: tr(c, "error.forbidden"), Code: CodeForbidden, Details: tr(c, "detail.other_tenant_audit")})
+			return
+		}
+		filter.tenant = tenant
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	events, truncated, err := auditLog.query(filter, limit)
+	if err != nil {
+		requestLogger(c).Error("查询审计日志失败", "error", err)
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: tr(c, "error.audit_query"), Code: CodeInternalError, Details: err.Error()})
+		return
+	}
+	if events == nil {
+		events = []AuditEvent{}
+	}
+	c.JSON(http.StatusOK, AuditQueryResponse{Events: events, Count: len(events), Truncated: truncated})
+}
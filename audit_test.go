@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// writeTestAuditFile 将记录写入审计日志目录下的文件
+func writeTestAuditFile(t *testing.T, dir, name string, events ...AuditEvent) {
+	t.Helper()
+	var lines []byte
+	for _, e := range events {
+		line, err := json.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), lines, 0640); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuditQueryRequiresAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	saved := auditLog
+	t.Cleanup(func() { auditLog = saved })
+	auditLog = &auditLogger{dir: t.TempDir()}
+
+	r := gin.New()
+	r.GET("/admin/audit", requireScope(ScopeAdmin), auditQueryHandler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/audit", nil))
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), CodeFeatureDisabled) {
+		t.Errorf("未启用认证时返回 %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAuditQueryScansNewestFiles(t *testing.T) {
+	dir := t.TempDir()
+	l := &auditLogger{dir: dir}
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	event := func(hour int) AuditEvent {
+		return AuditEvent{Time: start.Add(time.Duration(hour)*time.Hour + 30*time.Minute), Action: AuditConvert, Outcome: AuditSuccess}
+	}
+	// 12个轮转文件和当前文件，每个文件一条记录
+	const rotated = 12
+	for i := 0; i < rotated; i++ {
+		rotatedAt := start.Add(time.Duration(i+1) * time.Hour)
+		writeTestAuditFile(t, dir, auditRotatedPrefix+rotatedAt.Format(auditRotatedTimeFmt)+auditRotatedSuffix, event(i))
+	}
+	writeTestAuditFile(t, dir, auditFileName, event(rotated))
+
+	tests := []struct {
+		name          string
+		filter        auditFilter
+		limit         int
+		wantFirst     int // 第一条（最新的）记录所在的小时
+		wantCount     int
+		wantTruncated bool
+	}{
+		{"超出扫描的文件数", auditFilter{}, 100, rotated, auditQueryMaxFiles, true},
+		{"超出limit", auditFilter{}, 2, rotated, 2, true},
+		{"通过to查询更早的记录", auditFilter{to: start.Add(3 * time.Hour)}, 100, 2, 3, false},
+		{"from之前的文件不扫描", auditFilter{from: start.Add(8 * time.Hour)}, 100, rotated, 5, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, truncated, err := l.query(tt.filter, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != tt.wantCount || truncated != tt.wantTruncated {
+				t.Fatalf("返回 %d 条记录，truncated=%v，期望 %d 条，truncated=%v", len(events), truncated, tt.wantCount, tt.wantTruncated)
+			}
+			if !events[0].Time.Equal(event(tt.wantFirst).Time) {
+				t.Errorf("第一条记录的时间为 %v，期望 %v", events[0].Time, event(tt.wantFirst).Time)
+			}
+			for i := 1; i < len(events); i++ {
+				if !events[i].Time.Before(events[i-1].Time) {
+					t.Errorf("记录没有按时间倒序排列: %v 在 %v 之后", events[i].Time, events[i-1].Time)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// API密钥的权限范围
+const (
+	ScopeConvert  = "convert"
+	ScopeDownload = "download"
+	ScopeAdmin    = "admin"
+)
+
+// 请求上下文中保存已认证请求方的键
+const principalContextKey = "principal"
+
+// API密钥配置
+var (
+	API_KEYS_FILE           string
+	API_KEYS_DIR            string
+	API_KEYS_RELOAD_SECONDS int
+
+	apiKeysMu sync.RWMutex
+	apiKeys   map[string]*APIKey // 键为密钥的SHA-256十六进制摘要
+)
+
+// APIKey 一个API密钥的定义，配置中只保存密钥的SHA-256摘要
+type APIKey struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name,omitempty"`
+	KeySHA256 string   `json:"key_sha256"`
+	Scopes    []string `json:"scopes"`
+	Tenant    string   `json:"tenant,omitempty"`
+	Disabled  bool     `json:"disabled,omitempty"`
+}
+
+// Principal 已认证的请求方，来自API密钥或JWT
+type Principal struct {
+	ID      string   // 用于记录文件归属的唯一标识，如 key:team-a、jwt:user-1
+	Subject string   // API密钥ID或JWT的sub声明
+	Tenant  string   // 所属租户
+	Scopes  []string // 拥有的权限范围
+}
+
+// HasScope 判断请求方是否拥有指定权限，admin拥有所有权限
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// principal 返回密钥对应的请求方
+func (k *APIKey) principal() *Principal {
+	return &Principal{ID: "key:" + k.ID, Subject: k.ID, Tenant: k.Tenant, Scopes: k.Scopes}
+}
+
+// ReloadKeysResponse 重新加载密钥响应
+type ReloadKeysResponse struct {
+	Success bool `json:"success"`
+	Keys    int  `json:"keys"`
+}
+
+// initAuthConfig 读取API密钥相关的环境变量并加载密钥
+func initAuthConfig() {
+	API_KEYS_FILE = getEnvString("API_KEYS_FILE", "")
+	API_KEYS_DIR = getEnvString("API_KEYS_DIR", "")
+	API_KEYS_RELOAD_SECONDS = getEnvInt("API_KEYS_RELOAD_SECONDS", 30)
+
+	if !apiKeysEnabled() {
+		log.Println("未配置API_KEYS_FILE或API_KEYS_DIR，不启用API密钥认证")
+		return
+	}
+	n, err := reloadAPIKeys()
+	if err != nil {
+		log.Fatalf("加载API密钥失败: %v", err)
+	}
+	log.Printf("已启用API密钥认证，加载密钥 %d 个", n)
+}
+
+// authEnabled 是否启用了认证（API密钥或JWT）
+func authEnabled() bool {
+	return apiKeysEnabled() || jwtEnabled()
+}
+
+// apiKeysEnabled 是否配置了API密钥
+func apiKeysEnabled() bool {
+	return API_KEYS_FILE != "" || API_KEYS_DIR != ""
+}
+
+// hashAPIKey 计算密钥的SHA-256十六进制摘要
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// loadAPIKeys 从配置文件和密钥目录读取所有密钥
+// 配置文件为密钥定义的JSON数组，密钥目录中每个.json文件为一个密钥定义
+func loadAPIKeys() (map[string]*APIKey, error) {
+	var defs []*APIKey
+
+	if API_KEYS_FILE != "" {
+		data, err := os.ReadFile(API_KEYS_FILE)
+		if err != nil {
+			return nil, fmt.Errorf("读取密钥文件失败: %w", err)
+		}
+		var fileDefs []*APIKey
+		if err := json.Unmarshal(data, &fileDefs); err != nil {
+			return nil, fmt.Errorf("解析密钥文件失败: %w", err)
+		}
+		defs = append(defs, fileDefs...)
+	}
+
+	if API_KEYS_DIR != "" {
+		paths, err := filepath.Glob(filepath.Join(API_KEYS_DIR, "*.json"))
+		if err != nil {
+			return nil, fmt.Errorf("读取密钥目录失败: %w", err)
+		}
+		for _, p := range paths {
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return nil, fmt.Errorf("读取密钥文件%s失败: %w", p, err)
+			}
+			var def APIKey
+			if err := json.Unmarshal(data, &def); err != nil {
+				return nil, fmt.Errorf("解析密钥文件%s失败: %w", p, err)
+			}
+			if def.ID == "" {
+				def.ID = strings.TrimSuffix(filepath.Base(p), ".json")
+			}
+			defs = append(defs, &def)
+		}
+	}
+
+	keys := make(map[string]*APIKey)
+	ids := make(map[string]bool)
+	for _, def := range defs {
+		if def.ID == "" {
+			return nil, fmt.Errorf("密钥缺少id")
+		}
+		if ids[def.ID] {
+			return nil, fmt.Errorf("密钥id重复: %s", def.ID)
+		}
+		ids[def.ID] = true
+		hash := strings.ToLower(def.KeySHA256)
+		if len(hash) != sha256.Size*2 {
+			return nil, fmt.Errorf("密钥%s的key_sha256格式错误", def.ID)
+		}
+		for _, scope := range def.Scopes {
+			if scope != ScopeConvert && scope != ScopeDownload && scope != ScopeAdmin {
+				return nil, fmt.Errorf("密钥%s包含未知的权限范围: %s", def.ID, scope)
+			}
+		}
+		if def.Tenant != "" && lookupTenant(def.Tenant) == nil {
+			return nil, fmt.Errorf("密钥%s属于未知的租户: %s", def.ID, def.Tenant)
+		}
+		if def.Disabled {
+			continue
+		}
+		keys[hash] = def
+	}
+	return keys, nil
+}
+
+// reloadAPIKeys 重新加载密钥，加载失败时保留原有密钥
+func reloadAPIKeys() (int, error) {
+	keys, err := loadAPIKeys()
+	if err != nil {
+		return 0, err
+	}
+	apiKeysMu.Lock()
+	apiKeys = keys
+	apiKeysMu.Unlock()
+	return len(keys), nil
+}
+
+// apiKeysFingerprint 计算密钥配置文件的修改时间和大小，用于检测变化
+func apiKeysFingerprint() string {
+	var paths []string
+	if API_KEYS_FILE != "" {
+		paths = append(paths, API_KEYS_FILE)
+	}
+	if API_KEYS_DIR != "" {
+		dirPaths, _ := filepath.Glob(filepath.Join(API_KEYS_DIR, "*.json"))
+		paths = append(paths, dirPaths...)
+	}
+	sort.Strings(paths)
+	var b strings.Builder
+	for _, p := range paths {
+		if info, err := os.Stat(p); err == nil {
+			fmt.Fprintf(&b, "%s:%d:%d;", p, info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	return b.String()
+}
+
+// startAPIKeyWatcher 定期检查密钥配置是否变化，变化时自动重新加载
+func startAPIKeyWatcher(ctx context.Context, wg *sync.WaitGroup) {
+	if !apiKeysEnabled() || API_KEYS_RELOAD_SECONDS <= 0 {
+		return
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(time.Duration(API_KEYS_RELOAD_SECONDS) * time.Second)
+		defer ticker.Stop()
+
+		fingerprint := apiKeysFingerprint()
+		for {
+			select {
+			case <-ticker.C:
+				current := apiKeysFingerprint()
+				if current == fingerprint {
+					continue
+				}
+				fingerprint = current
+				if n, err := reloadAPIKeys(); err != nil {
+					log.Printf("密钥配置已变化，但重新加载失败，继续使用原有密钥: %v", err)
+				} else {
+					log.Printf("密钥配置已变化，已重新加载密钥 %d 个", n)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// apiKeyFromRequest 从X-API-Key请求头或Bearer令牌中读取密钥
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := c.GetHeader("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// lookupAPIKey 根据原始密钥查找密钥定义
+func lookupAPIKey(key string) *APIKey {
+	apiKeysMu.RLock()
+	defer apiKeysMu.RUnlock()
+	return apiKeys[hashAPIKey(key)]
+}
+
+// requireScope 返回校验请求方及其权限的中间件，未启用认证时直接放行
+// JWT由jwtMiddleware提前校验，这里只处理API密钥
+func requireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !authEnabled() {
+			c.Next()
+			return
+		}
+
+		p := currentPrincipal(c)
+		if p == nil && apiKeysEnabled() {
+			if raw := apiKeyFromRequest(c); raw != "" {
+				key := lookupAPIKey(raw)
+				if key == nil {
+					requestLogger(c).Warn("无效的API密钥", "ip", c.ClientIP())
+					c.Header("WWW-Authenticate", "Bearer")
+					abortWithError(c, http.StatusUnauthorized, ErrorResponse{Error: tr(c, "error.invalid_api_key"), Code: CodeInvalidAPIKey})
+					return
+				}
+				p = key.principal()
+				c.Set(principalContextKey, p)
+			}
+		}
+		if p == nil {
+			c.Header("WWW-Authenticate", "Bearer")
+			abortWithError(c, http.StatusUnauthorized, ErrorResponse{Error: tr(c, "error.unauthenticated"), Code: CodeUnauthenticated})
+			return
+		}
+		if err := validateTenant(p); err != nil {
+			requestLogger(c).Warn("拒绝请求: 租户不允许", "client", p.ID, "error", err)
+			abortWithError(c, http.StatusForbidden, ErrorResponse{Error: tr(c, "error.forbidden"), Code: CodeForbidden, Details: trError(c, err)})
+			return
+		}
+		if !p.HasScope(scope) {
+			abortWithError(c, http.StatusForbidden, ErrorResponse{
+				Error:   tr(c, "error.forbidden"),
+				Code:    CodeInsufficientScope,
+				Details: tr(c, "detail.insufficient_scope", scope),
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// currentPrincipal 返回当前请求已认证的请求方，未认证时返回nil
+func currentPrincipal(c *gin.Context) *Principal {
+	if v, ok := c.Get(principalContextKey); ok {
+		if p, ok := v.(*Principal); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// 重新加载API密钥处理
+func reloadAPIKeysHandler(c *gin.Context) {
+	n, err := reloadAPIKeys()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: tr(c, "error.reload_keys"), Code: CodeInternalError, Details: err.Error()})
+		return
+	}
+	requestLogger(c).Info("已重新加载API密钥", "keys", n, "client", callerIdentity(c))
+	c.JSON(http.StatusOK, ReloadKeysResponse{Success: true, Keys: n})
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"libreoffice-api/client"
+)
+
+// 命令行子命令
+const convertCommandName = "convert"
+
+// 命令行转换的退出码
+const (
+	exitOK               = 0 // 全部转换成功
+	exitConversionFailed = 1 // 至少一个文件转换失败
+	exitUsage            = 2 // 参数错误或没有可转换的文件
+)
+
+// 异步模式下输出进度的间隔
+const progressInterval = time.Second
+
+// convertCommand 命令行转换的参数
+type convertCommand struct {
+	server     string
+	apiKey     string
+	token      string
+	format     string
+	outDir     string
+	lang       string
+	recursive  bool
+	async      bool
+	jsonOutput bool
+	parallel   int
+	ttl        time.Duration
+	timeout    time.Duration
+
+	stdout      io.Writer
+	stderr      io.Writer
+	mu          sync.Mutex // 保护并发输出
+	localTmpDir string     // 本地转换独用的临时目录，结束时删除
+}
+
+// convertTask 一个待转换的文件
+type convertTask struct {
+	input  string
+	output string
+}
+
+// convertOutcome 单个文件的转换结果，--json时按行输出
+type convertOutcome struct {
+	Input           string `json:"input"`
+	Output          string `json:"output,omitempty"`
+	Success         bool   `json:"success"`
+	DetectedFormat  string `json:"detected_format,omitempty"`
+	FormatCorrected bool   `json:"format_corrected,omitempty"`
+	Code            string `json:"code,omitempty"`
+	Error           string `json:"error,omitempty"`
+	DurationMS      int64  `json:"duration_ms"`
+}
+
+// runConvertCommand 执行convert子命令，返回进程退出码
+func runConvertCommand(args []string) int {
+	cmd := &convertCommand{stdout: os.Stdout, stderr: os.Stderr}
+	return cmd.execute(args)
+}
+
+// execute 解析参数并转换所有文件，返回进程退出码
+func (cmd *convertCommand) execute(args []string) int {
+	inputs, err := cmd.parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintf(cmd.stderr, "错误: %v\n", err)
+		return exitUsage
+	}
+
+	tasks, err := collectConvertTasks(inputs, cmd.recursive, cmd.outDir, cmd.targetExt())
+	if err != nil {
+		fmt.Fprintf(cmd.stderr, "错误: %v\n", err)
+		return exitUsage
+	}
+	if len(tasks) == 0 {
+		fmt.Fprintln(cmd.stderr, "错误: 没有找到可转换的文件")
+		return exitUsage
+	}
+
+	// 没有指定服务地址时在本机转换
+	var convert func(context.Context, convertTask) convertOutcome
+	if cmd.server == "" {
+		if err := cmd.prepareLocal(); err != nil {
+			fmt.Fprintf(cmd.stderr, "错误: %v\n", err)
+			return exitUsage
+		}
+		defer cmd.cleanupLocal()
+		convert = cmd.localConvert
+	} else {
+		c, err := cmd.newClient()
+		if err != nil {
+			fmt.Fprintf(cmd.stderr, "错误: %v\n", err)
+			return exitUsage
+		}
+		convert = func(ctx context.Context, task convertTask) convertOutcome {
+			return cmd.remoteConvert(ctx, c, task)
+		}
+	}
+
+	// Ctrl+C时取消未完成的转换
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	failed := cmd.run(ctx, tasks, convert)
+	fmt.Fprintf(cmd.stderr, "共 %d 个文件，成功 %d 个，失败 %d 个\n", len(tasks), len(tasks)-failed, failed)
+	if failed > 0 {
+		return exitConversionFailed
+	}
+	return exitOK
+}
+
+// newClient 根据命令行参数创建客户端
+func (cmd *convertCommand) newClient() (*client.Client, error) {
+	opts := []client.Option{client.WithUserAgent("libreoffice-api-cli")}
+	if cmd.apiKey != "" {
+		opts = append(opts, client.WithAPIKey(cmd.apiKey))
+	}
+	if cmd.token != "" {
+		opts = append(opts, client.WithBearerToken(cmd.token))
+	}
+	if cmd.lang != "" {
+		opts = append(opts, client.WithLanguage(cmd.lang))
+	}
+	return client.New(cmd.server, opts...)
+}
+
+// parse 解析命令行参数，参数和文件可以交替出现，如convert --to pdf a.docx -o out/
+func (cmd *convertCommand) parse(args []string) ([]string, error) {
+	flags := flag.NewFlagSet(convertCommandName, flag.ContinueOnError)
+	flags.SetOutput(cmd.stderr)
+	flags.StringVar(&cmd.server, "server", os.Getenv("LIBREOFFICE_API_URL"), "服务地址，如http://localhost:15000，默认读取LIBREOFFICE_API_URL；为空时在本机调用LibreOffice转换")
+	flags.StringVar(&cmd.apiKey, "api-key", os.Getenv("LIBREOFFICE_API_KEY"), "API密钥，默认读取LIBREOFFICE_API_KEY")
+	flags.StringVar(&cmd.token, "token", os.Getenv("LIBREOFFICE_API_TOKEN"), "JWT访问令牌，默认读取LIBREOFFICE_API_TOKEN")
+	flags.StringVar(&cmd.format, "to", "", "目标格式，如pdf、docx，可以附带导出过滤器，如pdf:writer_pdf_Export")
+	flags.StringVar(&cmd.outDir, "o", ".", "输出目录")
+	flags.StringVar(&cmd.outDir, "output", ".", "同-o")
+	flags.BoolVar(&cmd.recursive, "r", false, "递归转换目录中支持的文件，输出时保留子目录结构")
+	flags.BoolVar(&cmd.recursive, "recursive", false, "同-r")
+	flags.IntVar(&cmd.parallel, "j", 4, "同时转换的文件数")
+	flags.IntVar(&cmd.parallel, "parallel", 4, "同-j")
+	flags.BoolVar(&cmd.async, "async", false, "在后台提交转换，并定期输出每个文件的上传和转换进度，需要与--server一起使用")
+	flags.DurationVar(&cmd.ttl, "ttl", 0, "转换结果在服务端的保存时间，如30m，默认使用服务端的过期时间，需要与--server一起使用")
+	flags.DurationVar(&cmd.timeout, "timeout", 0, "单个文件的超时时间，如10m；通过服务端转换时默认不限制，本地转换时默认使用CONVERSION_TIMEOUT_SECONDS")
+	flags.StringVar(&cmd.lang, "lang", "", "错误信息的语言，如zh-CN或en")
+	flags.BoolVar(&cmd.jsonOutput, "json", false, "每个文件的结果按行输出为JSON；本地转换总是输出JSON，且不支持--async和--ttl，指定时报错退出")
+	flags.Usage = func() {
+		fmt.Fprintf(cmd.stderr, "用法: %s convert --to <格式> [--server <地址>] [选项] <文件、目录或通配符>...\n", filepath.Base(os.Args[0]))
+		fmt.Fprint(cmd.stderr, "不指定--server时使用与服务相同的配置（.env和环境变量）在本机转换，结果按行输出为JSON\n\n")
+		flags.PrintDefaults()
+		fmt.Fprintf(cmd.stderr, "\n退出码: %d 全部成功，%d 有文件转换失败，%d 参数错误\n", exitOK, exitConversionFailed, exitUsage)
+	}
+
+	var inputs []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			break
+		}
+		inputs = append(inputs, args[0])
+		args = args[1:]
+	}
+
+	if cmd.format == "" {
+		return nil, errors.New("需要通过--to指定目标格式")
+	}
+	if cmd.parallel < 1 {
+		cmd.parallel = 1
+	}
+	if len(inputs) == 0 {
+		return nil, errors.New("需要指定要转换的文件")
+	}
+	return inputs, nil
+}
+
+// targetExt 返回目标格式的扩展名，去掉导出过滤器
+func (cmd *convertCommand) targetExt() string {
+	return strings.ToLower(strings.Split(cmd.format, ":")[0])
+}
+
+// collectConvertTasks 展开命令行中的文件、目录和通配符，生成转换任务
+// 目录中只转换支持的输入格式，并在输出目录中保留相对路径；不同输入的输出路径相同时报错，避免互相覆盖
+func collectConvertTasks(inputs []string, recursive bool, outDir, targetExt string) ([]convertTask, error) {
+	var tasks []convertTask
+	outputs := map[string]string{}
+	add := func(input, rel string) error {
+		output := filepath.Join(outDir, strings.TrimSuffix(rel, filepath.Ext(rel))+"."+targetExt)
+		absInput, _ := filepath.Abs(input)
+		absOutput, _ := filepath.Abs(output)
+		if absInput == absOutput {
+			return fmt.Errorf("%s的输出文件与输入文件相同，请通过-o指定其他输出目录", input)
+		}
+		if prev, ok := outputs[absOutput]; ok {
+			if prevAbs, _ := filepath.Abs(prev); prevAbs == absInput {
+				// 同一个文件被多次指定时只转换一次
+				return nil
+			}
+			return fmt.Errorf("%s和%s的输出文件相同: %s", prev, input, output)
+		}
+		outputs[absOutput] = input
+		tasks = append(tasks, convertTask{input: input, output: output})
+		return nil
+	}
+
+	for _, arg := range inputs {
+		paths := []string{arg}
+		// shell没有展开的通配符（如加了引号或在Windows上）由这里展开
+		if _, err := os.Stat(arg); err != nil && strings.ContainsAny(arg, "*?[") {
+			matches, err := filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("无效的通配符%q: %w", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("没有与%q匹配的文件", arg)
+			}
+			paths = matches
+		}
+
+		for _, p := range paths {
+			info, err := os.Stat(p)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				if err := add(p, filepath.Base(p)); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if !recursive {
+				return nil, fmt.Errorf("%s是目录，转换目录中的文件需要指定-r", p)
+			}
+			err = filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.IsDir() || !isValidInputFormat(strings.ToLower(filepath.Ext(path))) {
+					return nil
+				}
+				rel, err := filepath.Rel(p, path)
+				if err != nil {
+					return err
+				}
+				return add(path, rel)
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return tasks, nil
+}
+
+// run 按并发数转换所有文件并输出结果，返回失败的文件数
+func (cmd *convertCommand) run(ctx context.Context, tasks []convertTask, convert func(context.Context, convertTask) convertOutcome) int {
+	queue := make(chan convertTask)
+	var (
+		wg     sync.WaitGroup
+		failed int
+	)
+	for i := 0; i < cmd.parallel && i < len(tasks); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range queue {
+				outcome := convert(ctx, task)
+				cmd.report(outcome)
+				if !outcome.Success {
+					cmd.mu.Lock()
+					failed++
+					cmd.mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, task := range tasks {
+		queue <- task
+	}
+	close(queue)
+	wg.Wait()
+	return failed
+}
+
+// remoteConvert 通过服务端转换一个文件并下载结果
+func (cmd *convertCommand) remoteConvert(ctx context.Context, c *client.Client, task convertTask) convertOutcome {
+	start := time.Now()
+	if cmd.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cmd.timeout)
+		defer cancel()
+	}
+
+	opts := client.ConvertOptions{Format: cmd.format, TTL: cmd.ttl}
+	var (
+		result *client.ConversionResult
+		err    error
+	)
+	if cmd.async {
+		job := c.ConvertFileAsync(ctx, task.input, opts)
+		result, err = job.Poll(ctx, progressInterval, func(status client.JobStatus) {
+			if !status.Done() {
+				cmd.progress(task, status)
+			}
+		})
+	} else {
+		result, err = c.ConvertFile(ctx, task.input, opts)
+	}
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(task.output), 0755); err == nil {
+			_, err = c.DownloadToFile(ctx, result.DownloadURL, task.output)
+		}
+	}
+
+	outcome := convertOutcome{Input: task.input, Output: task.output, Success: err == nil}
+	if result != nil {
+		outcome.DetectedFormat = result.DetectedFormat
+		outcome.FormatCorrected = result.FormatCorrected
+	}
+	outcome.fail(err)
+	outcome.DurationMS = time.Since(start).Milliseconds()
+	return outcome
+}
+
+// fail 记录转换失败的原因，服务端和本地转换的错误同时记录错误码
+func (o *convertOutcome) fail(err error) {
+	if err == nil {
+		return
+	}
+	o.Success = false
+	o.Output = ""
+	o.Error = err.Error()
+	var apiErr *client.Error
+	var convErr *conversionError
+	switch {
+	case errors.As(err, &apiErr):
+		o.Code = apiErr.Code
+	case errors.As(err, &convErr):
+		o.Code = convErr.code
+	}
+}
+
+// progress 输出异步转换的进度
+func (cmd *convertCommand) progress(task convertTask, status client.JobStatus) {
+	var line string
+	switch {
+	case status.State == client.JobConverting:
+		line = fmt.Sprintf("%s: 转换中，已用时 %s", task.input, time.Since(status.StartedAt).Round(time.Second))
+	case status.TotalBytes > 0:
+		line = fmt.Sprintf("%s: 上传中 %d%% (%d/%d 字节)", task.input, status.BytesSent*100/status.TotalBytes, status.BytesSent, status.TotalBytes)
+	default:
+		line = fmt.Sprintf("%s: 上传中 %d 字节", task.input, status.BytesSent)
+	}
+	cmd.mu.Lock()
+	defer cmd.mu.Unlock()
+	fmt.Fprintln(cmd.stderr, line)
+}
+
+// report 输出单个文件的转换结果
+func (cmd *convertCommand) report(outcome convertOutcome) {
+	cmd.mu.Lock()
+	defer cmd.mu.Unlock()
+	if cmd.jsonOutput {
+		data, _ := json.Marshal(outcome)
+		fmt.Fprintln(cmd.stdout, string(data))
+		return
+	}
+	duration := time.Duration(outcome.DurationMS) * time.Millisecond
+	if outcome.Success {
+		fmt.Fprintf(cmd.stdout, "成功 %s -> %s (%s)\n", outcome.Input, outcome.Output, duration)
+	} else {
+		fmt.Fprintf(cmd.stdout, "失败 %s: %s\n", outcome.Input, outcome.Error)
+	}
+}
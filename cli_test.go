@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// writeTestFiles 在dir下创建文件，路径中的目录自动创建
+func writeTestFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCollectConvertTasks(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir,
+		"docs/a.docx", "docs/b.doc", "docs/sub/c.rtf", "docs/sub/skip.bin",
+		"clash/a.docx", "clash/a.doc", "report.pdf",
+	)
+	in := func(name string) string { return filepath.Join(dir, filepath.FromSlash(name)) }
+	out := filepath.Join(dir, "out")
+
+	tests := []struct {
+		name      string
+		inputs    []string
+		recursive bool
+		outDir    string
+		want      []string // 输入和输出路径，相对于dir，格式为"输入>输出"
+		wantErr   string
+	}{
+		{name: "单个文件", inputs: []string{in("docs/a.docx")}, want: []string{"docs/a.docx>out/a.pdf"}},
+		{name: "重复的输入只转换一次", inputs: []string{in("docs/a.docx"), dir + "/docs/../docs/a.docx", in("docs/a.docx")}, want: []string{"docs/a.docx>out/a.pdf"}},
+		{name: "不同输入的输出相同", inputs: []string{in("clash/a.docx"), in("clash/a.doc")}, wantErr: "的输出文件相同"},
+		{name: "输出与输入相同", inputs: []string{in("report.pdf")}, outDir: dir, wantErr: "的输出文件与输入文件相同"},
+		{name: "展开通配符", inputs: []string{in("docs/*.doc*")}, want: []string{"docs/a.docx>out/a.pdf", "docs/b.doc>out/b.pdf"}},
+		{name: "通配符没有匹配", inputs: []string{in("docs/*.ppt")}, wantErr: "没有与"},
+		{name: "文件不存在", inputs: []string{in("docs/missing.docx")}, wantErr: "no such file"},
+		{name: "目录需要-r", inputs: []string{in("docs")}, wantErr: "需要指定-r"},
+		{
+			name: "递归转换目录", inputs: []string{in("docs")}, recursive: true,
+			want: []string{"docs/a.docx>out/a.pdf", "docs/b.doc>out/b.pdf", "docs/sub/c.rtf>out/sub/c.pdf"},
+		},
+		{name: "递归时输出相同", inputs: []string{in("clash")}, recursive: true, wantErr: "的输出文件相同"},
+		{name: "目录和其中的文件只转换一次", inputs: []string{in("docs"), in("docs/a.docx")}, recursive: true,
+			want: []string{"docs/a.docx>out/a.pdf", "docs/b.doc>out/b.pdf", "docs/sub/c.rtf>out/sub/c.pdf"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outDir := out
+			if tt.outDir != "" {
+				outDir = tt.outDir
+			}
+			tasks, err := collectConvertTasks(tt.inputs, tt.recursive, outDir, "pdf")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("错误 = %v, 期望包含%q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, task := range tasks {
+				input, _ := filepath.Rel(dir, task.input)
+				output, _ := filepath.Rel(dir, task.output)
+				got = append(got, filepath.ToSlash(input)+">"+filepath.ToSlash(output))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("任务 = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertCommandRun(t *testing.T) {
+	var tasks []convertTask
+	for i := 0; i < 20; i++ {
+		tasks = append(tasks, convertTask{input: fmt.Sprintf("f%d.docx", i), output: fmt.Sprintf("f%d.pdf", i)})
+	}
+	var stdout bytes.Buffer
+	cmd := &convertCommand{parallel: 3, jsonOutput: true, stdout: &stdout, stderr: io.Discard}
+
+	var running, maxRunning atomic.Int32
+	failed := cmd.run(context.Background(), tasks, func(ctx context.Context, task convertTask) convertOutcome {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		outcome := convertOutcome{Input: task.input, Output: task.output, Success: true}
+		if strings.HasSuffix(task.input, "3.docx") {
+			outcome.fail(fmt.Errorf("转换失败"))
+		}
+		return outcome
+	})
+
+	if failed != 2 {
+		t.Errorf("失败数 = %d, 期望 2", failed)
+	}
+	if maxRunning.Load() > 3 {
+		t.Errorf("同时转换 %d 个文件，超过并发数 3", maxRunning.Load())
+	}
+	seen := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		var outcome convertOutcome
+		if err := json.Unmarshal([]byte(line), &outcome); err != nil {
+			t.Fatalf("无法解析输出 %q: %v", line, err)
+		}
+		if seen[outcome.Input] {
+			t.Errorf("%s 的结果输出了多次", outcome.Input)
+		}
+		seen[outcome.Input] = true
+		if wantFail := strings.HasSuffix(outcome.Input, "3.docx"); outcome.Success == wantFail || (wantFail && outcome.Output != "") {
+			t.Errorf("结果 = %+v", outcome)
+		}
+	}
+	if len(seen) != len(tasks) {
+		t.Errorf("输出了 %d 个结果, 期望 %d", len(seen), len(tasks))
+	}
+}
+
+// newFakeConvertServer 模拟转换服务，文件名以bad开头时返回415，其余返回固定的转换结果
+func newFakeConvertServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/convert":
+			_, header, err := r.FormFile("file")
+			if err != nil {
+				t.Errorf("读取上传文件失败: %v", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if strings.HasPrefix(header.Filename, "bad") {
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				w.Write([]byte(`{"error":{"code":"content_format_mismatch","message":"内容不符"}}`))
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "filename": header.Filename, "download_url": "/v1/download/result.pdf?sig=x"})
+		case r.URL.Path == "/v1/download/result.pdf":
+			w.Write([]byte("%PDF-1.4"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestConvertCommandExitCodes(t *testing.T) {
+	t.Setenv("LIBREOFFICE_API_URL", "")
+	srv := newFakeConvertServer(t)
+	dir := t.TempDir()
+	writeTestFiles(t, dir, "good.docx", "bad.docx", "docs/a.docx")
+	in := func(name string) string { return filepath.Join(dir, name) }
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    int
+		outputs []string // 期望生成的输出文件
+	}{
+		{"帮助", []string{"-h"}, exitOK, nil},
+		{"缺少目标格式", []string{"--server", srv.URL, in("good.docx")}, exitUsage, nil},
+		{"缺少文件", []string{"--server", srv.URL, "--to", "pdf"}, exitUsage, nil},
+		{"无效的参数", []string{"--server", srv.URL, "--to", "pdf", "--unknown", in("good.docx")}, exitUsage, nil},
+		{"无效的服务地址", []string{"--server", "ftp://localhost", "--to", "pdf", in("good.docx")}, exitUsage, nil},
+		{"目录需要-r", []string{"--server", srv.URL, "--to", "pdf", in("docs")}, exitUsage, nil},
+		{"通配符没有匹配", []string{"--server", srv.URL, "--to", "pdf", in("*.pptx")}, exitUsage, nil},
+		{"全部成功", []string{"--server", srv.URL, "--to", "pdf", in("good.docx"), "-r", in("docs")}, exitOK, []string{"good.pdf", "a.pdf"}},
+		{"部分失败", []string{"--server", srv.URL, "--to", "pdf", "--json", in("good.docx"), in("bad.docx")}, exitConversionFailed, []string{"good.pdf"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outDir := t.TempDir()
+			var stdout, stderr bytes.Buffer
+			cmd := &convertCommand{stdout: &stdout, stderr: &stderr}
+			if got := cmd.execute(append(tt.args, "-o", outDir)); got != tt.want {
+				t.Fatalf("退出码 = %d, 期望 %d\nstdout: %s\nstderr: %s", got, tt.want, stdout.String(), stderr.String())
+			}
+			for _, name := range tt.outputs {
+				data, err := os.ReadFile(filepath.Join(outDir, name))
+				if err != nil || string(data) != "%PDF-1.4" {
+					t.Errorf("输出文件%s: %q, %v", name, data, err)
+				}
+			}
+			entries, _ := os.ReadDir(outDir)
+			if len(entries) != len(tt.outputs) {
+				t.Errorf("输出目录中有 %d 个文件, 期望 %d", len(entries), len(tt.outputs))
+			}
+		})
+	}
+}
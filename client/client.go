@@ -0,0 +1,191 @@
+// Package client 是libreoffice-api的Go客户端，封装了文档转换、结果下载和文件管理接口
+//
+// 客户端使用服务端的/v1接口，遇到429和503响应时按退避策略自动重试，
+// 服务端返回的错误解析为*Error，可以通过错误码判断错误类型：
+//
+//	c, err := client.New("http://localhost:15000", client.WithAPIKey(key))
+//	...
+//	result, err := c.ConvertFile(ctx, "report.docx", client.ConvertOptions{Format: "pdf"})
+//	if client.IsCode(err, client.CodeUnsupportedInputFormat) {
+//		...
+//	}
+//	_, err = c.DownloadToFile(ctx, result.DownloadURL, "report.pdf")
+//
+// 服务端没有异步任务接口，ConvertAsync和ConvertFileAsync只是在客户端的后台goroutine中发送同步的转换请求，
+// Job的状态和Poll的轮询都只在客户端进行，不会查询服务端；连接断开或进程退出后转换结果无法找回，需要重新转换。
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 客户端请求的接口版本前缀
+const apiPrefix = "/v1"
+
+// 默认的User-Agent
+const defaultUserAgent = "libreoffice-api-go-client"
+
+// Client libreoffice-api客户端，可以在多个goroutine中同时使用
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	apiKey     string
+	token      string
+	language   string
+	userAgent  string
+	retry      RetryPolicy
+}
+
+// Option 客户端选项
+type Option func(*Client)
+
+// WithAPIKey 使用API密钥认证，通过X-API-Key请求头发送
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithBearerToken 使用JWT访问令牌认证，通过Authorization请求头发送
+func WithBearerToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithHTTPClient 使用自定义的http.Client，如需要设置代理或TLS配置时
+// 转换大文件可能需要较长时间，不要设置过短的Timeout，应通过context控制单次请求的超时
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithLanguage 设置服务端错误信息的语言，如zh-CN或en
+func WithLanguage(lang string) Option {
+	return func(c *Client) { c.language = lang }
+}
+
+// WithUserAgent 设置User-Agent请求头
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// WithRetryPolicy 设置429和503响应的重试策略，MaxRetries为0表示不重试
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) { c.retry = p }
+}
+
+// New 创建客户端，baseURL为服务地址，如http://localhost:15000，可以包含反向代理的路径前缀
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("无效的服务地址: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("无效的服务地址: %q，应以http://或https://开头", baseURL)
+	}
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		userAgent:  defaultUserAgent,
+		retry:      DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// endpoint 返回接口的完整地址，path以/开头
+func (c *Client) endpoint(path string, query url.Values) string {
+	u := *c.baseURL
+	u.Path = c.baseURL.Path + path
+	u.RawPath = c.baseURL.EscapedPath() + escapePath(path)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// escapePath 逐段转义路径，保留分隔符/
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// setHeaders 设置认证、语言等公共请求头
+func (c *Client) setHeaders(req *http.Request) {
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.language != "" {
+		req.Header.Set("Accept-Language", c.language)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+}
+
+// requestFunc 创建一次请求，重试时会再次调用
+type requestFunc func(ctx context.Context, attempt int) (*http.Request, error)
+
+// do 发送请求，429和503响应按重试策略重试，replayable为false时请求体只能发送一次，不重试
+// 成功时返回响应，调用方负责关闭Body；失败时返回*Error或网络错误
+func (c *Client) do(ctx context.Context, newRequest requestFunc, replayable bool) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest(ctx, attempt)
+		if err != nil {
+			return nil, err
+		}
+		c.setHeaders(req)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode < 400 {
+			return resp, nil
+		}
+
+		apiErr := parseError(resp)
+		resp.Body.Close()
+		if !replayable || !apiErr.Retryable() || attempt >= c.retry.MaxRetries {
+			return nil, apiErr
+		}
+		if err := sleepContext(ctx, c.retry.backoff(attempt, apiErr.RetryAfter)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// getJSON 发送没有请求体的请求，并将JSON响应解析到out
+func (c *Client) getJSON(ctx context.Context, method, path string, query url.Values, out interface{}) error {
+	resp, err := c.do(ctx, func(ctx context.Context, _ int) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, method, c.endpoint(path, query), nil)
+	}, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	return nil
+}
+
+// sleepContext 等待指定时间，context取消时提前返回
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ConvertOptions 转换选项
+type ConvertOptions struct {
+	// Format 目标格式，如pdf、docx，可以附带导出过滤器，如pdf:writer_pdf_Export；为空时服务端默认转换为txt
+	Format string
+	// TTL 转换结果的保存时间，向上取整到分钟，不能超过服务端配置的过期时间；0表示使用服务端的过期时间
+	TTL time.Duration
+	// Progress 上传进度回调，在上传的goroutine中执行，total未知时为-1；重试时从0重新计算
+	Progress func(sent, total int64)
+}
+
+// Convert 上传文件并转换，文件内容从r流式读取，不会整体读入内存
+// filename用于服务端识别文件格式，必须带扩展名；r实现io.Seeker时（如*os.File）遇到429和503会自动重试，否则只发送一次
+func (c *Client) Convert(ctx context.Context, filename string, r io.Reader, opts ConvertOptions) (*ConversionResult, error) {
+	if filename == "" {
+		return nil, errors.New("文件名不能为空，服务端根据扩展名识别文件格式")
+	}
+
+	seeker, replayable := r.(io.Seeker)
+	var start int64
+	total := int64(-1)
+	if replayable {
+		if offset, size, err := seekableSize(seeker); err == nil {
+			start, total = offset, size
+		} else {
+			replayable = false
+		}
+	}
+
+	fields := map[string]string{}
+	if opts.Format != "" {
+		fields["format"] = opts.Format
+	}
+	if opts.TTL > 0 {
+		fields["ttl_minutes"] = strconv.Itoa(int(math.Ceil(opts.TTL.Minutes())))
+	}
+
+	var (
+		body *io.PipeReader
+		done chan struct{}
+	)
+	// 返回前确保上传goroutine已经退出，之后调用方可以安全地关闭r
+	defer func() {
+		if body != nil {
+			body.Close()
+			<-done
+		}
+	}()
+	newRequest := func(ctx context.Context, attempt int) (*http.Request, error) {
+		if attempt > 0 {
+			// 等待上一次请求的上传结束后再回到文件开头
+			body.Close()
+			<-done
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return nil, fmt.Errorf("重新读取文件失败: %w", err)
+			}
+		}
+
+		pr, pw := io.Pipe()
+		mw := multipart.NewWriter(pw)
+		body, done = pr, make(chan struct{})
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint(apiPrefix+"/convert", nil), pr)
+		if err != nil {
+			pr.Close()
+			return nil, err
+		}
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+
+		source := &progressReader{r: r, total: total, progress: opts.Progress}
+		go func(done chan struct{}) {
+			defer close(done)
+			pw.CloseWithError(writeConvertForm(mw, fields, filename, source))
+		}(done)
+		return req, nil
+	}
+
+	resp, err := c.do(ctx, newRequest, replayable)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result ConversionResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析转换结果失败: %w", err)
+	}
+	return &result, nil
+}
+
+// ConvertFile 上传本地文件并转换
+func (c *Client) ConvertFile(ctx context.Context, path string, opts ConvertOptions) (*ConversionResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer f.Close()
+	return c.Convert(ctx, filepath.Base(path), f, opts)
+}
+
+// writeConvertForm 写入转换请求的multipart表单，文件放在最后
+func writeConvertForm(mw *multipart.Writer, fields map[string]string, filename string, r io.Reader) error {
+	for name, value := range fields {
+		if err := mw.WriteField(name, value); err != nil {
+			return err
+		}
+	}
+	part, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return err
+	}
+	return mw.Close()
+}
+
+// seekableSize 返回当前读取位置和剩余的字节数，并恢复读取位置
+func seekableSize(s io.Seeker) (start, size int64, err error) {
+	if start, err = s.Seek(0, io.SeekCurrent); err != nil {
+		return 0, 0, err
+	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err := s.Seek(start, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+	return start, end - start, nil
+}
+
+// progressReader 统计已读取的字节数并回调，读取结束时total未知或为0则以实际大小回调一次
+type progressReader struct {
+	r        io.Reader
+	sent     int64
+	total    int64
+	progress func(sent, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.sent += int64(n)
+	if p.progress != nil {
+		switch {
+		case err == io.EOF && p.total <= 0:
+			p.progress(p.sent, p.sent)
+		case n > 0:
+			p.progress(p.sent, p.total)
+		}
+	}
+	return n, err
+}
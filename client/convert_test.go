@@ -0,0 +1,186 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// readConvertForm 读取转换请求的multipart表单，返回普通字段、文件名和文件内容
+func readConvertForm(t *testing.T, r *http.Request) (map[string]string, string, []byte) {
+	t.Helper()
+	mr, err := r.MultipartReader()
+	if err != nil {
+		t.Errorf("请求不是multipart表单: %v", err)
+		return nil, "", nil
+	}
+	fields := map[string]string{}
+	var filename string
+	var content []byte
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Errorf("读取表单失败: %v", err)
+			return nil, "", nil
+		}
+		data, _ := io.ReadAll(part)
+		if part.FormName() == "file" {
+			filename, content = part.FileName(), data
+		} else {
+			fields[part.FormName()] = string(data)
+		}
+	}
+	return fields, filename, content
+}
+
+func writeResult(w http.ResponseWriter, filename string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ConversionResult{Success: true, Filename: filename, DownloadURL: "/v1/download/a.pdf"})
+}
+
+func TestConvertStreamsMultipart(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 64<<10) // 1MiB
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/prefix/v1/convert" {
+			t.Errorf("请求 %s %s", r.Method, r.URL.Path)
+		}
+		if r.ContentLength != -1 {
+			t.Errorf("请求体应流式发送，ContentLength = %d", r.ContentLength)
+		}
+		if got := r.Header.Get("X-API-Key"); got != "key" {
+			t.Errorf("X-API-Key = %q", got)
+		}
+		fields, filename, data := readConvertForm(t, r)
+		if fields["format"] != "pdf" || fields["ttl_minutes"] != "2" {
+			t.Errorf("表单字段 = %v", fields)
+		}
+		if filename != "report.docx" || !bytes.Equal(data, content) {
+			t.Errorf("上传的文件 %q 大小 %d 与原文件不符", filename, len(data))
+		}
+		writeResult(w, filename)
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL+"/prefix/", WithAPIKey("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lastSent, lastTotal int64
+	result, err := c.Convert(context.Background(), "report.docx", bytes.NewReader(content), ConvertOptions{
+		Format:   "pdf",
+		TTL:      90 * time.Second,
+		Progress: func(sent, total int64) { lastSent, lastTotal = sent, total },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Success || result.Filename != "report.docx" {
+		t.Errorf("转换结果 = %+v", result)
+	}
+	if lastSent != int64(len(content)) || lastTotal != int64(len(content)) {
+		t.Errorf("上传进度 = %d/%d", lastSent, lastTotal)
+	}
+}
+
+func TestConvertRetries(t *testing.T) {
+	content := []byte("hello")
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, filename, data := readConvertForm(t, r)
+		if !bytes.Equal(data, content) {
+			t.Errorf("第%d次请求上传的内容为%q", attempts.Load()+1, data)
+		}
+		switch attempts.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":{"code":"service_unavailable","message":"繁忙"}}`))
+		case 2:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"code":"rate_limited","message":"请求过多"}}`))
+		default:
+			writeResult(w, filename)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, WithRetryPolicy(RetryPolicy{MaxRetries: 3, InitialBackoff: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := c.Convert(context.Background(), "a.txt", bytes.NewReader(content), ConvertOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := attempts.Load(); n != 3 {
+		t.Errorf("请求次数 = %d, 期望 3", n)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("应按Retry-After等待1秒后重试，实际只等待了%v", elapsed)
+	}
+}
+
+func TestConvertRetryLimits(t *testing.T) {
+	tests := []struct {
+		name         string
+		reader       func() io.Reader
+		policy       RetryPolicy
+		wantAttempts int32
+	}{
+		{"不可重放的请求体只发送一次", func() io.Reader { return io.MultiReader(strings.NewReader("hello")) }, RetryPolicy{MaxRetries: 3, InitialBackoff: time.Millisecond}, 1},
+		{"超过重试次数", func() io.Reader { return bytes.NewReader([]byte("hello")) }, RetryPolicy{MaxRetries: 2, InitialBackoff: time.Millisecond}, 3},
+		{"不重试", func() io.Reader { return bytes.NewReader([]byte("hello")) }, RetryPolicy{}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts.Add(1)
+				io.Copy(io.Discard, r.Body)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer srv.Close()
+
+			c, err := New(srv.URL, WithRetryPolicy(tt.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = c.Convert(context.Background(), "a.txt", tt.reader(), ConvertOptions{})
+			if !IsCode(err, CodeServiceBusy) {
+				t.Errorf("错误 = %v", err)
+			}
+			if n := attempts.Load(); n != tt.wantAttempts {
+				t.Errorf("请求次数 = %d, 期望 %d", n, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 5 * time.Second}
+	if got := p.backoff(0, 2*time.Second); got != 2*time.Second {
+		t.Errorf("有Retry-After时等待%v, 期望2s", got)
+	}
+	if got := p.backoff(0, time.Minute); got != 5*time.Second {
+		t.Errorf("Retry-After应受MaxBackoff限制，等待%v", got)
+	}
+	for attempt := 0; attempt < 10; attempt++ {
+		want := p.InitialBackoff << attempt
+		if want > p.MaxBackoff {
+			want = p.MaxBackoff
+		}
+		if got := p.backoff(attempt, 0); got < want/2 || got > want {
+			t.Errorf("第%d次重试等待%v, 期望在[%v, %v]之间", attempt, got, want/2, want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 服务端返回的错误码，与服务端apierror.go中的定义一致
+const (
+	// 通用
+	CodeBadRequest      = "bad_request"
+	CodeInternalError   = "internal_error"
+	CodeNotFound        = "not_found"
+	CodeServiceBusy     = "service_unavailable"
+	CodeInvalidParam    = "invalid_parameter"
+	CodeStorageError    = "storage_error"
+	CodeFeatureDisabled = "feature_disabled"
+
+	// 认证和授权
+	CodeUnauthenticated   = "unauthenticated"
+	CodeInvalidAPIKey     = "invalid_api_key"
+	CodeInvalidToken      = "invalid_token"
+	CodeForbidden         = "forbidden"
+	CodeInsufficientScope = "insufficient_scope"
+
+	// 限流和配额
+	CodeRateLimited          = "rate_limited"
+	CodeDailyConversionQuota = "daily_conversion_quota_exceeded"
+	CodeDailyUploadQuota     = "daily_upload_quota_exceeded"
+
+	// 上传和转换
+	CodeMissingFile             = "missing_file"
+	CodeFileTooLarge            = "file_too_large"
+	CodeUnsupportedInputFormat  = "unsupported_input_format"
+	CodeUnsupportedOutputFormat = "unsupported_output_format"
+	CodeContentMismatch         = "content_format_mismatch"
+	CodeInputFormatNotAllowed   = "input_format_not_allowed"
+	CodeOutputFormatNotAllowed  = "output_format_not_allowed"
+	CodeInvalidTTL              = "invalid_ttl"
+	CodeInsufficientStorage     = "insufficient_storage"
+	CodeLibreOfficeUnavailable  = "libreoffice_unavailable"
+	CodeQueueTimeout            = "queue_timeout"
+	CodeConversionTimeout       = "conversion_timeout"
+	CodeConversionFailed        = "conversion_failed"
+	CodeResourceLimit           = "conversion_resource_limit_exceeded"
+
+	// 压缩包安全检查
+	CodeArchiveInvalid      = "archive_invalid"
+	CodeArchiveTooLarge     = "archive_too_large"
+	CodeArchiveRatio        = "archive_compression_ratio_exceeded"
+	CodeArchiveTooManyFiles = "archive_too_many_entries"
+	CodeArchiveNested       = "archive_nesting_too_deep"
+
+	// 文件下载和管理
+	CodeInvalidPath      = "invalid_path"
+	CodeFileNotFound     = "file_not_found"
+	CodeFileExpired      = "file_expired"
+	CodeSignatureMissing = "signature_missing"
+	CodeSignatureInvalid = "signature_invalid"
+	CodeSignatureExpired = "signature_expired"
+)
+
+// 解析错误响应时最多读取的字节数
+const maxErrorBody = 64 << 10
+
+// Error 服务端返回的错误
+type Error struct {
+	StatusCode int           // HTTP状态码
+	Code       string        // 错误码，如unsupported_input_format
+	Message    string        // 错误信息，语言由WithLanguage决定
+	Details    string        // 详细信息
+	RequestID  string        // 请求ID，反馈问题时请提供
+	RetryAfter time.Duration // Retry-After响应头给出的等待时间，没有时为0
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s (HTTP %d): %s", e.Code, e.StatusCode, e.Message)
+	if e.Details != "" {
+		msg += ": " + e.Details
+	}
+	if e.RequestID != "" {
+		msg += " [request_id=" + e.RequestID + "]"
+	}
+	return msg
+}
+
+// Retryable 判断错误是否可以稍后重试，429和503可以重试，每日配额用完时当天重试没有意义
+func (e *Error) Retryable() bool {
+	switch e.Code {
+	case CodeDailyConversionQuota, CodeDailyUploadQuota:
+		return false
+	}
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusServiceUnavailable
+}
+
+// IsCode 判断err是否为指定错误码的服务端错误
+func IsCode(err error, code string) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// IsNotFound 判断err是否表示文件或接口不存在
+func IsNotFound(err error) bool {
+	return IsCode(err, CodeFileNotFound) || IsCode(err, CodeNotFound)
+}
+
+// parseError 解析错误响应，支持/v1的错误格式和旧接口的错误格式
+// 响应不是JSON时（如反向代理返回的错误页）根据HTTP状态码推断错误码
+func parseError(resp *http.Response) *Error {
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		e.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	var envelope struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err == nil && len(envelope.Error) > 0 {
+		var v1 struct {
+			Code      string `json:"code"`
+			Message   string `json:"message"`
+			Details   string `json:"details"`
+			RequestID string `json:"request_id"`
+		}
+		var legacy struct {
+			Error     string `json:"error"`
+			Code      string `json:"code"`
+			Details   string `json:"details"`
+			RequestID string `json:"request_id"`
+		}
+		if json.Unmarshal(envelope.Error, &v1) == nil {
+			e.Code, e.Message, e.Details = v1.Code, v1.Message, v1.Details
+			if v1.RequestID != "" {
+				e.RequestID = v1.RequestID
+			}
+		} else if json.Unmarshal(body, &legacy) == nil {
+			e.Code, e.Message, e.Details = legacy.Code, legacy.Error, legacy.Details
+			if legacy.RequestID != "" {
+				e.RequestID = legacy.RequestID
+			}
+		}
+	}
+
+	if e.Code == "" {
+		e.Code = defaultErrorCode(resp.StatusCode)
+	}
+	if e.Message == "" {
+		e.Message = strings.TrimSpace(string(body))
+		if e.Message == "" || len(e.Message) > 200 {
+			e.Message = http.StatusText(resp.StatusCode)
+		}
+	}
+	return e
+}
+
+// defaultErrorCode 响应中没有错误码时根据HTTP状态码推断，与服务端的推断规则一致
+func defaultErrorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthenticated
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusRequestEntityTooLarge:
+		return CodeFileTooLarge
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeServiceBusy
+	}
+	if status >= 500 {
+		return CodeInternalError
+	}
+	return CodeBadRequest
+}
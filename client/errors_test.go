@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// errorCodeConsts 解析源文件，返回以Code开头的字符串常量，键为常量名
+func errorCodeConsts(t *testing.T, pattern string) map[string]string {
+	t.Helper()
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	codes := map[string]string{}
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatalf("解析%s失败: %v", path, err)
+		}
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					if !strings.HasPrefix(name.Name, "Code") || i >= len(vs.Values) {
+						continue
+					}
+					lit, ok := vs.Values[i].(*ast.BasicLit)
+					if !ok || lit.Kind != token.STRING {
+						continue
+					}
+					if codes[name.Name], err = strconv.Unquote(lit.Value); err != nil {
+						t.Fatalf("无法解析常量%s: %v", name.Name, err)
+					}
+				}
+			}
+		}
+	}
+	return codes
+}
+
+// 客户端的错误码常量必须与服务端一一对应，服务端的错误码与OpenAPI文档的一致性由服务端的测试保证
+func TestErrorCodesMatchServer(t *testing.T) {
+	server := errorCodeConsts(t, "../*.go")
+	client := errorCodeConsts(t, "errors.go")
+	if len(server) == 0 {
+		t.Fatal("未找到服务端的错误码常量")
+	}
+	for name, value := range server {
+		if got, ok := client[name]; !ok {
+			t.Errorf("客户端缺少错误码%s = %q", name, value)
+		} else if got != value {
+			t.Errorf("错误码%s: 客户端为%q，服务端为%q", name, got, value)
+		}
+	}
+	for name := range client {
+		if _, ok := server[name]; !ok {
+			t.Errorf("服务端没有错误码%s", name)
+		}
+	}
+}
+
+func TestParseErrorFormats(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		want        Error
+	}{
+		{
+			name:        "v1",
+			status:      http.StatusUnsupportedMediaType,
+			contentType: "application/json",
+			body:        `{"error":{"code":"content_format_mismatch","message":"内容不符","details":"扩展名为.docx","request_id":"body-id"}}`,
+			want:        Error{StatusCode: 415, Code: CodeContentMismatch, Message: "内容不符", Details: "扩展名为.docx", RequestID: "body-id"},
+		},
+		{
+			name:        "旧接口",
+			status:      http.StatusBadRequest,
+			contentType: "application/json",
+			body:        `{"error":"无效的ttl_minutes","code":"invalid_ttl","details":"ttl_minutes必须为正整数: x"}`,
+			want:        Error{StatusCode: 400, Code: CodeInvalidTTL, Message: "无效的ttl_minutes", Details: "ttl_minutes必须为正整数: x", RequestID: "header-id"},
+		},
+		{
+			name:        "非JSON",
+			status:      http.StatusBadGateway,
+			contentType: "text/html",
+			body:        "Bad Gateway",
+			want:        Error{StatusCode: 502, Code: CodeInternalError, Message: "Bad Gateway", RequestID: "header-id"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Header().Set("X-Request-ID", "header-id")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			c, err := New(srv.URL, WithRetryPolicy(RetryPolicy{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = c.FileInfo(context.Background(), "a.pdf")
+			if !IsCode(err, tt.want.Code) {
+				t.Fatalf("错误码不符: %v", err)
+			}
+			got := *err.(*Error)
+			if got != tt.want {
+				t.Errorf("解析结果 = %+v\n期望 %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorRetryAfter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"code":"daily_conversion_quota_exceeded","message":"配额已用完"}}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.FileInfo(context.Background(), "a.pdf")
+	apiErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("期望*Error，得到%v", err)
+	}
+	if apiErr.RetryAfter != 7*time.Second {
+		t.Errorf("RetryAfter = %v", apiErr.RetryAfter)
+	}
+	if apiErr.Retryable() {
+		t.Error("每日配额用完不应重试")
+	}
+}
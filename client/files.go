@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ListFilesOptions 文件列表的查询条件
+type ListFilesOptions struct {
+	Prefix   string // 只列出路径以该前缀开头的文件，相对于所在租户的存储路径，如20240101
+	Page     int    // 页码，从1开始，0表示第1页
+	PageSize int    // 每页数量，0表示使用服务端默认值
+}
+
+// ListFiles 列出当前请求方创建的转换结果，服务端未启用认证时返回CodeFeatureDisabled错误
+func (c *Client) ListFiles(ctx context.Context, opts ListFilesOptions) (*FileList, error) {
+	query := url.Values{}
+	if opts.Prefix != "" {
+		query.Set("prefix", opts.Prefix)
+	}
+	if opts.Page > 0 {
+		query.Set("page", strconv.Itoa(opts.Page))
+	}
+	if opts.PageSize > 0 {
+		query.Set("page_size", strconv.Itoa(opts.PageSize))
+	}
+	var list FileList
+	if err := c.getJSON(ctx, http.MethodGet, apiPrefix+"/files", query, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// FileInfo 查询转换结果的信息，path为ConversionResult.DownloadFilename
+func (c *Client) FileInfo(ctx context.Context, path string) (*FileInfo, error) {
+	var info FileInfo
+	if err := c.getJSON(ctx, http.MethodGet, apiPrefix+"/files/"+strings.TrimPrefix(path, "/")+"/info", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// DeleteFile 删除转换结果，path为ConversionResult.DownloadFilename，服务端未启用认证时返回CodeFeatureDisabled错误
+func (c *Client) DeleteFile(ctx context.Context, path string) error {
+	var result struct {
+		Success bool `json:"success"`
+	}
+	return c.getJSON(ctx, http.MethodDelete, apiPrefix+"/files/"+strings.TrimPrefix(path, "/"), nil, &result)
+}
+
+// Download 下载转换结果并写入w，返回写入的字节数
+// downloadURL可以是ConversionResult.DownloadURL，也可以是存储路径（ConversionResult.DownloadFilename）
+func (c *Client) Download(ctx context.Context, downloadURL string, w io.Writer) (int64, error) {
+	target, err := c.resolveDownloadURL(downloadURL)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.do(ctx, func(ctx context.Context, _ int) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	}, true)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("下载文件失败: %w", err)
+	}
+	return n, nil
+}
+
+// DownloadToFile 下载转换结果并保存到path，先写入同目录下的临时文件，下载完成后再重命名，失败时不会留下不完整的文件
+func (c *Client) DownloadToFile(ctx context.Context, downloadURL, path string) (int64, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.part")
+	if err != nil {
+		return 0, fmt.Errorf("创建文件失败: %w", err)
+	}
+	n, err := c.Download(ctx, downloadURL, tmp)
+	if closeErr := tmp.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("写入文件失败: %w", closeErr)
+	}
+	if err == nil {
+		if err = os.Chmod(tmp.Name(), 0644); err == nil {
+			err = os.Rename(tmp.Name(), path)
+		}
+		if err != nil {
+			err = fmt.Errorf("保存文件失败: %w", err)
+		}
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return n, err
+	}
+	return n, nil
+}
+
+// resolveDownloadURL 将下载地址转换为客户端可访问的地址
+// 服务端按请求的Host生成下载地址，经过反向代理时可能无法直接访问，因此只保留其中的路径和签名参数
+func (c *Client) resolveDownloadURL(downloadURL string) (string, error) {
+	u, err := url.Parse(downloadURL)
+	if err != nil {
+		return "", fmt.Errorf("无效的下载地址: %w", err)
+	}
+	if !u.IsAbs() && !strings.HasPrefix(u.Path, "/") {
+		return c.endpoint(apiPrefix+"/download/"+downloadURL, nil), nil
+	}
+	target := *c.baseURL
+	target.Path = c.baseURL.Path + u.Path
+	target.RawPath = c.baseURL.EscapedPath() + u.EscapedPath()
+	target.RawQuery = u.RawQuery
+	return target.String(), nil
+}
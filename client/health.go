@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Ready 查询服务是否就绪，服务未就绪（503）时也返回检查结果，通过Readiness.Status判断
+func (c *Client) Ready(ctx context.Context) (*Readiness, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint("/readyz", nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setHeaders(req)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, parseError(resp)
+	}
+
+	var readiness Readiness
+	if err := json.NewDecoder(resp.Body).Decode(&readiness); err != nil {
+		return nil, fmt.Errorf("解析就绪检查结果失败: %w", err)
+	}
+	return &readiness, nil
+}
+
+// WaitReady 每隔interval查询一次，直到服务就绪或ctx结束，适合在服务刚启动时使用
+// 服务暂时无法连接时继续等待，ctx结束时返回最后一次的错误
+func (c *Client) WaitReady(ctx context.Context, interval time.Duration) error {
+	var lastErr error
+	for {
+		readiness, err := c.Ready(ctx)
+		switch {
+		case err != nil:
+			lastErr = err
+		case readiness.Status == "ready":
+			return nil
+		default:
+			lastErr = fmt.Errorf("服务未就绪: %s", failedChecks(readiness))
+		}
+		if err := sleepContext(ctx, interval); err != nil {
+			return fmt.Errorf("%w: %v", err, lastErr)
+		}
+	}
+}
+
+// failedChecks 返回未通过的检查项，用于错误信息
+func failedChecks(r *Readiness) string {
+	var msg string
+	for _, check := range r.Checks {
+		if check.Status == "ok" {
+			continue
+		}
+		if msg != "" {
+			msg += "; "
+		}
+		msg += check.Name + "=" + check.Status
+		if check.Message != "" {
+			msg += " (" + check.Message + ")"
+		}
+	}
+	return msg
+}
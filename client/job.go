@@ -0,0 +1,152 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// JobState 异步转换的状态
+type JobState string
+
+const (
+	JobUploading  JobState = "uploading"  // 正在上传文件
+	JobConverting JobState = "converting" // 文件已上传，等待服务端完成转换
+	JobSucceeded  JobState = "succeeded"
+	JobFailed     JobState = "failed"
+)
+
+// JobStatus 异步转换的当前状态
+type JobStatus struct {
+	State      JobState
+	Filename   string
+	BytesSent  int64
+	TotalBytes int64 // 文件大小，未知时为-1
+	StartedAt  time.Time
+	FinishedAt time.Time         // 未完成时为零值
+	Result     *ConversionResult // 成功时的转换结果
+	Err        error             // 失败时的错误
+}
+
+// Done 判断转换是否已经结束
+func (s JobStatus) Done() bool {
+	return s.State == JobSucceeded || s.State == JobFailed
+}
+
+// Job 在后台执行的转换
+// 服务端的转换接口是同步的，Job在后台goroutine中发送转换请求，调用方可以随时查询进度、等待结果或取消
+type Job struct {
+	mu     sync.Mutex
+	status JobStatus
+	done   chan struct{}
+	cancel context.CancelFunc
+}
+
+// ConvertAsync 在后台上传并转换，立即返回Job，转换结束前不能关闭或修改r
+func (c *Client) ConvertAsync(ctx context.Context, filename string, r io.Reader, opts ConvertOptions) *Job {
+	return c.startJob(ctx, filename, opts, func(ctx context.Context, opts ConvertOptions) (*ConversionResult, error) {
+		return c.Convert(ctx, filename, r, opts)
+	})
+}
+
+// ConvertFileAsync 在后台上传并转换本地文件
+func (c *Client) ConvertFileAsync(ctx context.Context, path string, opts ConvertOptions) *Job {
+	return c.startJob(ctx, filepath.Base(path), opts, func(ctx context.Context, opts ConvertOptions) (*ConversionResult, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("打开文件失败: %w", err)
+		}
+		defer f.Close()
+		return c.Convert(ctx, filepath.Base(path), f, opts)
+	})
+}
+
+// startJob 在后台goroutine中执行转换，并通过上传进度回调更新状态
+func (c *Client) startJob(ctx context.Context, filename string, opts ConvertOptions, run func(context.Context, ConvertOptions) (*ConversionResult, error)) *Job {
+	ctx, cancel := context.WithCancel(ctx)
+	j := &Job{
+		status: JobStatus{State: JobUploading, Filename: filename, TotalBytes: -1, StartedAt: time.Now()},
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+
+	progress := opts.Progress
+	opts.Progress = func(sent, total int64) {
+		j.mu.Lock()
+		j.status.BytesSent, j.status.TotalBytes = sent, total
+		if total >= 0 && sent >= total {
+			j.status.State = JobConverting
+		} else {
+			j.status.State = JobUploading
+		}
+		j.mu.Unlock()
+		if progress != nil {
+			progress(sent, total)
+		}
+	}
+
+	go func() {
+		defer cancel()
+		result, err := run(ctx, opts)
+		j.mu.Lock()
+		j.status.Result, j.status.Err = result, err
+		j.status.State = JobSucceeded
+		if err != nil {
+			j.status.State = JobFailed
+		}
+		j.status.FinishedAt = time.Now()
+		j.mu.Unlock()
+		close(j.done)
+	}()
+	return j
+}
+
+// Status 返回转换的当前状态
+func (j *Job) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// Done 返回转换结束时关闭的channel
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Cancel 取消转换，已经完成的转换不受影响
+func (j *Job) Cancel() {
+	j.cancel()
+}
+
+// Wait 等待转换结束并返回结果，ctx结束时返回ctx的错误，但不会取消转换
+func (j *Job) Wait(ctx context.Context) (*ConversionResult, error) {
+	select {
+	case <-j.done:
+		status := j.Status()
+		return status.Result, status.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Poll 每隔interval将当前状态传给fn，直到转换结束，结束时再以最终状态调用一次fn，适合显示进度
+func (j *Job) Poll(ctx context.Context, interval time.Duration, fn func(JobStatus)) (*ConversionResult, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-j.done:
+			status := j.Status()
+			fn(status)
+			return status.Result, status.Err
+		case <-ticker.C:
+			fn(j.Status())
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
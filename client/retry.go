@@ -0,0 +1,40 @@
+package client
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy 429和503响应的重试策略
+// 服务端返回Retry-After时按其等待，否则按指数退避加随机抖动等待
+type RetryPolicy struct {
+	MaxRetries     int           // 最多重试次数，0表示不重试
+	InitialBackoff time.Duration // 第一次重试前的等待时间
+	MaxBackoff     time.Duration // 单次等待时间的上限，也限制Retry-After
+}
+
+// DefaultRetryPolicy 默认的重试策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+}
+
+// backoff 返回第attempt次失败后（从0开始）重试前的等待时间
+func (p RetryPolicy) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	wait := retryAfter
+	if wait <= 0 {
+		wait = p.InitialBackoff
+		for i := 0; i < attempt && (p.MaxBackoff <= 0 || wait < p.MaxBackoff); i++ {
+			wait *= 2
+		}
+		// 在[wait/2, wait]之间随机，避免多个客户端同时重试
+		if wait > 1 {
+			wait = wait/2 + rand.N(wait/2+1)
+		}
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	return wait
+}
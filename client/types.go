@@ -0,0 +1,54 @@
+package client
+
+// ConversionResult 转换结果，与服务端的ConversionResponse一致
+type ConversionResult struct {
+	Success           bool   `json:"success"`
+	Filename          string `json:"filename"`            // 上传的原始文件名
+	DownloadURL       string `json:"download_url"`        // 带签名的下载地址
+	DownloadFilename  string `json:"download_filename"`   // 转换结果在服务端的存储路径，用于文件管理接口
+	DownloadURLExpiry string `json:"download_url_expiry"` // 下载地址的签名过期时间
+	DetectedFormat    string `json:"detected_format"`
+	FormatCorrected   bool   `json:"format_corrected,omitempty"` // 扩展名与文件内容不符，已按内容识别的格式转换
+	Text              string `json:"text,omitempty"`             // 转换为txt时的文本内容
+	Expiry            string `json:"expiry"`                     // 转换结果的过期时间
+}
+
+// FileInfo 转换结果文件信息
+type FileInfo struct {
+	Path        string `json:"path"`
+	Filename    string `json:"filename"`
+	Size        int64  `json:"size"`
+	MimeType    string `json:"mime_type"`
+	CreatedAt   string `json:"created_at"`
+	Expiry      string `json:"expiry"`
+	DownloadURL string `json:"download_url"`
+}
+
+// FileList 文件列表
+type FileList struct {
+	Files    []FileInfo `json:"files"`
+	Page     int        `json:"page"`
+	PageSize int        `json:"page_size"`
+	Total    int        `json:"total"`
+}
+
+// ReadinessCheck 一项就绪检查的结果
+type ReadinessCheck struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`
+	CheckedAt  string `json:"checked_at,omitempty"`
+	DurationMS int64  `json:"duration_ms,omitempty"`
+}
+
+// Readiness 就绪检查结果
+type Readiness struct {
+	Status string           `json:"status"` // ready或not_ready
+	Checks []ReadinessCheck `json:"checks"`
+	Pool   struct {
+		Active        int `json:"active"`
+		Queued        int `json:"queued"`
+		MaxConcurrent int `json:"max_concurrent"`
+		MaxQueued     int `json:"max_queued,omitempty"`
+	} `json:"pool"`
+}
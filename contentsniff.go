@@ -0,0 +1,340 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"log"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// 上传文件扩展名与实际内容不符时的处理方式
+const (
+	MismatchCorrect = "correct" // 按实际内容修正扩展名
+	MismatchReject  = "reject"  // 拒绝转换
+)
+
+var CONTENT_TYPE_MISMATCH string
+
+// 用于判断文件类型的文件头
+var (
+	ole2Magic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+	zipMagic  = []byte("PK\x03\x04")
+	pdfMagic  = []byte("%PDF-")
+	rtfMagic  = []byte(`{\rtf`)
+	utf8BOM   = []byte{0xEF, 0xBB, 0xBF}
+	utf16LE   = []byte{0xFF, 0xFE}
+	utf16BE   = []byte{0xFE, 0xFF}
+)
+
+// 读取文件头的字节数
+const sniffLength = 8192
+
+// 可以互相替代的扩展名，内容相同时不视为不符
+var equivalentExts = map[string]string{
+	".htm": ".html",
+	".wps": ".doc",
+}
+
+// initContentSniffConfig 读取内容检测相关的环境变量
+func initContentSniffConfig() {
+	CONTENT_TYPE_MISMATCH = strings.ToLower(getEnvString("CONTENT_TYPE_MISMATCH", MismatchCorrect))
+	if CONTENT_TYPE_MISMATCH != MismatchCorrect && CONTENT_TYPE_MISMATCH != MismatchReject {
+		log.Printf("无效的CONTENT_TYPE_MISMATCH: %q, 使用默认值%s", CONTENT_TYPE_MISMATCH, MismatchCorrect)
+		CONTENT_TYPE_MISMATCH = MismatchCorrect
+	}
+}
+
+// detectContentExt 根据文件内容判断文件类型，返回对应的扩展名，无法识别时返回空字符串
+// claimedExt只用于判断非UTF-8编码的文本，见detectTextExt
+func detectContentExt(r io.ReaderAt, size int64, claimedExt string) (string, error) {
+	head := make([]byte, sniffLength)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	head = head[:n]
+
+	switch {
+	case bytes.HasPrefix(head, ole2Magic):
+		// OLE2复合文档，Word、Excel、PowerPoint 97-2003和WPS文字均为此格式
+		return detectOLE2Ext(r, size), nil
+	case bytes.HasPrefix(head, zipMagic):
+		return detectZipExt(r, size)
+	case bytes.HasPrefix(head, pdfMagic):
+		return ".pdf", nil
+	case bytes.HasPrefix(head, rtfMagic):
+		return ".rtf", nil
+	}
+	return detectTextExt(head, claimedExt), nil
+}
+
+// detectZipExt 根据ZIP中的条目区分OOXML和ODF文档，只读取目录和mimetype条目
+func detectZipExt(r io.ReaderAt, size int64) (string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", nil
+	}
+
+	var hasContentTypes bool
+	for _, f := range zr.File {
+		switch {
+		case f.Name == "mimetype":
+			return odfExt(f), nil
+		case f.Name == "[Content_Types].xml":
+			hasContentTypes = true
+		}
+	}
+	if !hasContentTypes {
+		return "", nil
+	}
+	for _, f := range zr.File {
+		switch {
+		case strings.HasPrefix(f.Name, "word/"):
+			return ".docx", nil
+		case strings.HasPrefix(f.Name, "xl/"):
+			return ".xlsx", nil
+		case strings.HasPrefix(f.Name, "ppt/"):
+			return ".pptx", nil
+		}
+	}
+	return "", nil
+}
+
+// OLE2复合文档中用于区分文档类型的流名称，WPS文字与Word相同使用WordDocument流
+var ole2StreamExts = map[string]string{
+	"WordDocument":        ".doc",
+	"Workbook":            ".xls",
+	"Book":                ".xls", // Excel 5.0/95
+	"PowerPoint Document": ".ppt",
+}
+
+// OLE2复合文档格式的常量
+const (
+	cfbHeaderSize      = 512
+	cfbDirEntrySize    = 128
+	cfbHeaderDIFATLen  = 109        // 文件头中DIFAT的项数
+	cfbMaxRegSect      = 0xFFFFFFFA // 大于该值的扇区号为特殊值
+	cfbStreamObject    = 2
+	cfbMaxChainSectors = 4096 // 读取目录和DIFAT的最大扇区数，防止损坏的文件形成循环
+)
+
+// detectOLE2Ext 读取OLE2复合文档的目录，根据其中的流区分Word、Excel和PowerPoint文档
+// 文件损坏或不包含上述流时返回空字符串
+func detectOLE2Ext(r io.ReaderAt, size int64) string {
+	header := make([]byte, cfbHeaderSize)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return ""
+	}
+	shift := binary.LittleEndian.Uint16(header[0x1E:])
+	if shift != 9 && shift != 12 {
+		return ""
+	}
+	sectorSize := int64(1) << shift
+	numFATSectors := binary.LittleEndian.Uint32(header[0x2C:])
+	dirSector := binary.LittleEndian.Uint32(header[0x30:])
+	difatSector := binary.LittleEndian.Uint32(header[0x44:])
+
+	fatSectors := cfbFATSectors(r, header, sectorSize, numFATSectors, difatSector)
+	entriesPerSector := sectorSize / 4
+	sectorOffset := func(sector uint32) int64 {
+		return (int64(sector) + 1) * sectorSize
+	}
+
+	buf := make([]byte, sectorSize)
+	for i := 0; dirSector <= cfbMaxRegSect && i < cfbMaxChainSectors; i++ {
+		offset := sectorOffset(dirSector)
+		if offset+sectorSize > size {
+			return ""
+		}
+		if _, err := r.ReadAt(buf, offset); err != nil {
+			return ""
+		}
+		for e := int64(0); e+cfbDirEntrySize <= sectorSize; e += cfbDirEntrySize {
+			entry := buf[e : e+cfbDirEntrySize]
+			if entry[0x42] != cfbStreamObject {
+				continue
+			}
+			if ext, ok := ole2StreamExts[cfbEntryName(entry)]; ok {
+				return ext
+			}
+		}
+
+		// 在FAT中查找目录的下一个扇区
+		index := int64(dirSector) / entriesPerSector
+		if index >= int64(len(fatSectors)) {
+			return ""
+		}
+		var next [4]byte
+		if _, err := r.ReadAt(next[:], sectorOffset(fatSectors[index])+int64(dirSector)%entriesPerSector*4); err != nil {
+			return ""
+		}
+		dirSector = binary.LittleEndian.Uint32(next[:])
+	}
+	return ""
+}
+
+// cfbFATSectors 返回FAT所在的扇区号，前109个在文件头中，其余在DIFAT扇区链中
+func cfbFATSectors(r io.ReaderAt, header []byte, sectorSize int64, count, difatSector uint32) []uint32 {
+	var sectors []uint32
+	for i := 0; i < cfbHeaderDIFATLen && uint32(len(sectors)) < count; i++ {
+		sectors = append(sectors, binary.LittleEndian.Uint32(header[0x4C+i*4:]))
+	}
+
+	buf := make([]byte, sectorSize)
+	last := int(sectorSize/4) - 1 // 每个DIFAT扇区的最后一项为下一个DIFAT扇区
+	for n := 0; difatSector <= cfbMaxRegSect && uint32(len(sectors)) < count && n < cfbMaxChainSectors; n++ {
+		if _, err := r.ReadAt(buf, (int64(difatSector)+1)*sectorSize); err != nil {
+			break
+		}
+		for i := 0; i < last && uint32(len(sectors)) < count; i++ {
+			sectors = append(sectors, binary.LittleEndian.Uint32(buf[i*4:]))
+		}
+		difatSector = binary.LittleEndian.Uint32(buf[last*4:])
+	}
+	return sectors
+}
+
+// cfbEntryName 解码目录项中UTF-16LE编码的名称
+func cfbEntryName(entry []byte) string {
+	length := int(binary.LittleEndian.Uint16(entry[0x40:]))
+	// 长度以字节计，包含结尾的空字符
+	if length < 2 || length > 64 || length%2 != 0 {
+		return ""
+	}
+	units := make([]uint16, length/2-1)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(entry[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// odfExt 根据ODF文档的mimetype条目返回扩展名
+func odfExt(f *zip.File) string {
+	rc, err := f.Open()
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+	data, _ := io.ReadAll(io.LimitReader(rc, 128))
+	switch strings.TrimSpace(string(data)) {
+	case "application/vnd.oasis.opendocument.text":
+		return ".odt"
+	case "application/vnd.oasis.opendocument.spreadsheet":
+		return ".ods"
+	case "application/vnd.oasis.opendocument.presentation":
+		return ".odp"
+	}
+	return ""
+}
+
+// detectTextExt 判断文本内容是HTML、XML还是纯文本，包含二进制内容时返回空字符串
+// 带BOM的UTF-16按文本处理；扩展名为.txt、.htm或.html时，不含控制字符的非UTF-8内容也视为文本，
+// 如GBK编码的网页，其他扩展名的文件中这样的内容更可能是二进制数据
+func detectTextExt(head []byte, claimedExt string) string {
+	if bytes.HasPrefix(head, utf16LE) || bytes.HasPrefix(head, utf16BE) {
+		text := decodeUTF16(head)
+		if strings.ContainsRune(text, 0) {
+			return ""
+		}
+		return classifyText(text)
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return ""
+	}
+	text := bytes.TrimPrefix(head, utf8BOM)
+	// 文件头可能在多字节字符中间截断
+	for i := 0; i < utf8.UTFMax && len(text) > 0 && !utf8.Valid(text); i++ {
+		text = text[:len(text)-1]
+	}
+	if !utf8.Valid(text) {
+		if (claimedExt == ".txt" || claimedExt == ".htm" || claimedExt == ".html") && !hasControlBytes(head) {
+			return classifyText(string(head))
+		}
+		return ""
+	}
+	return classifyText(string(text))
+}
+
+// classifyText 根据文本开头的标签区分HTML、XML和纯文本
+func classifyText(text string) string {
+	lower := strings.ToLower(strings.TrimSpace(text))
+	switch {
+	case strings.HasPrefix(lower, "<!doctype html"), strings.HasPrefix(lower, "<html"):
+		return ".html"
+	case strings.HasPrefix(lower, "<?xml"):
+		if strings.Contains(lower, "<html") {
+			return ".html"
+		}
+		return ".xml"
+	case strings.HasPrefix(lower, "<") && (strings.Contains(lower, "<head") || strings.Contains(lower, "<body")):
+		return ".html"
+	}
+	return ".txt"
+}
+
+// decodeUTF16 按BOM指定的字节序解码UTF-16文本，忽略截断的最后一个字节
+func decodeUTF16(head []byte) string {
+	var order binary.ByteOrder = binary.LittleEndian
+	if bytes.HasPrefix(head, utf16BE) {
+		order = binary.BigEndian
+	}
+	head = head[2:]
+	units := make([]uint16, len(head)/2)
+	for i := range units {
+		units[i] = order.Uint16(head[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// hasControlBytes 是否包含除制表、换行、换页和回车以外的控制字符
+func hasControlBytes(data []byte) bool {
+	for _, b := range data {
+		if (b < 0x20 && b != '\t' && b != '\n' && b != '\f' && b != '\r') || b == 0x7F {
+			return true
+		}
+	}
+	return false
+}
+
+// reconcileInputExt 比较上传文件的扩展名和实际内容，返回用于转换的扩展名
+// 纯文本文件可以包含任意文本内容，因此.txt只要求内容是文本；
+// HTML片段不一定以标签开头，因此.html和.xml只要求内容不是二进制或RTF
+func reconcileInputExt(claimedExt, detectedExt string) (string, error) {
+	if detectedExt == "" {
+		return "", newLocalizedError("detail.content_unrecognized", claimedExt)
+	}
+	if !isValidInputFormat(detectedExt) {
+		return "", newLocalizedError("detail.content_unsupported", strings.TrimPrefix(detectedExt, "."))
+	}
+	if sameExt(claimedExt, detectedExt) ||
+		(claimedExt == ".txt" && isTextExt(detectedExt)) ||
+		(isMarkupExt(claimedExt) && (detectedExt == ".txt" || isMarkupExt(detectedExt))) {
+		return claimedExt, nil
+	}
+	if CONTENT_TYPE_MISMATCH == MismatchReject {
+		return "", newLocalizedError("detail.content_mismatch", claimedExt, strings.TrimPrefix(detectedExt, "."))
+	}
+	return detectedExt, nil
+}
+
+func sameExt(a, b string) bool {
+	if v, ok := equivalentExts[a]; ok {
+		a = v
+	}
+	if v, ok := equivalentExts[b]; ok {
+		b = v
+	}
+	return a == b
+}
+
+func isTextExt(ext string) bool {
+	return ext == ".txt" || ext == ".html" || ext == ".xml" || ext == ".rtf"
+}
+
+func isMarkupExt(ext string) bool {
+	return ext == ".html" || ext == ".htm" || ext == ".xml"
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// buildTestCFB 生成只包含目录的最小OLE2复合文档，扇区0为FAT，目录从扇区1开始，
+// 每个流名称占一个目录项，目录项超过一个扇区时按FAT链接
+func buildTestCFB(shift uint16, streams ...string) []byte {
+	sectorSize := 1 << shift
+	entriesPerSector := sectorSize / cfbDirEntrySize
+	entries := 1 + len(streams)
+	dirSectors := (entries + entriesPerSector - 1) / entriesPerSector
+
+	header := make([]byte, cfbHeaderSize)
+	copy(header, ole2Magic)
+	le := binary.LittleEndian
+	le.PutUint16(header[0x18:], 0x3E)
+	le.PutUint16(header[0x1A:], map[uint16]uint16{9: 3, 12: 4}[shift])
+	le.PutUint16(header[0x1C:], 0xFFFE)
+	le.PutUint16(header[0x1E:], shift)
+	le.PutUint16(header[0x20:], 6)
+	le.PutUint32(header[0x2C:], 1) // FAT扇区数
+	le.PutUint32(header[0x30:], 1) // 目录起始扇区
+	le.PutUint32(header[0x38:], 4096)
+	le.PutUint32(header[0x3C:], 0xFFFFFFFE)
+	le.PutUint32(header[0x44:], 0xFFFFFFFE)
+	for i := 0; i < cfbHeaderDIFATLen; i++ {
+		le.PutUint32(header[0x4C+i*4:], 0xFFFFFFFF)
+	}
+	le.PutUint32(header[0x4C:], 0) // FAT位于扇区0
+
+	file := make([]byte, sectorSize*(2+dirSectors))
+	copy(file, header)
+	sector := func(n int) []byte {
+		return file[(n+1)*sectorSize : (n+2)*sectorSize]
+	}
+
+	fat := sector(0)
+	for i := 0; i < sectorSize/4; i++ {
+		le.PutUint32(fat[i*4:], 0xFFFFFFFF)
+	}
+	le.PutUint32(fat, 0xFFFFFFFD)
+	for i := 1; i <= dirSectors; i++ {
+		next := uint32(i + 1)
+		if i == dirSectors {
+			next = 0xFFFFFFFE
+		}
+		le.PutUint32(fat[i*4:], next)
+	}
+
+	names := append([]string{"Root Entry"}, streams...)
+	for i, name := range names {
+		entry := sector(1 + i/entriesPerSector)[(i%entriesPerSector)*cfbDirEntrySize:]
+		units := utf16.Encode([]rune(name))
+		for j, u := range units {
+			le.PutUint16(entry[j*2:], u)
+		}
+		le.PutUint16(entry[0x40:], uint16(len(units)*2+2))
+		if i == 0 {
+			entry[0x42] = 5
+		} else {
+			entry[0x42] = cfbStreamObject
+		}
+	}
+	return file
+}
+
+func buildTestZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectContentExt(t *testing.T) {
+	// 目录跨越多个扇区，WordDocument位于第二个目录扇区
+	multiSector := buildTestCFB(9, "\x01CompObj", "\x05SummaryInformation", "1Table", "Data", "WordDocument")
+	truncated := buildTestCFB(9, "WordDocument")
+	truncated = truncated[:len(truncated)-512]
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"Word", buildTestCFB(9, "\x01CompObj", "WordDocument", "1Table"), ".doc"},
+		{"Word多个目录扇区", multiSector, ".doc"},
+		{"Word 4096字节扇区", buildTestCFB(12, "WordDocument"), ".doc"},
+		{"Excel", buildTestCFB(9, "\x05SummaryInformation", "Workbook"), ".xls"},
+		{"Excel 95", buildTestCFB(9, "Book"), ".xls"},
+		{"PowerPoint", buildTestCFB(9, "Current User", "PowerPoint Document"), ".ppt"},
+		{"其他复合文档", buildTestCFB(9, "__substg1.0_0037001F"), ""},
+		{"目录被截断", truncated, ""},
+		{"只有文件头", append([]byte{}, ole2Magic...), ""},
+		{"docx", buildTestZip(t, map[string]string{"[Content_Types].xml": "", "word/document.xml": ""}), ".docx"},
+		{"xlsx", buildTestZip(t, map[string]string{"[Content_Types].xml": "", "xl/workbook.xml": ""}), ".xlsx"},
+		{"odt", buildTestZip(t, map[string]string{"mimetype": "application/vnd.oasis.opendocument.text"}), ".odt"},
+		{"普通zip", buildTestZip(t, map[string]string{"a.txt": "a"}), ""},
+		{"PDF", []byte("%PDF-1.7\n"), ".pdf"},
+		{"RTF", []byte(`{\rtf1\ansi hello}`), ".rtf"},
+		{"HTML", []byte("<!DOCTYPE html><html></html>"), ".html"},
+		{"XML", []byte(`<?xml version="1.0"?><root/>`), ".xml"},
+		{"文本", []byte("你好，世界"), ".txt"},
+		{"二进制", []byte{0x7F, 'E', 'L', 'F', 0, 0}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := detectContentExt(bytes.NewReader(tt.data), int64(len(tt.data)), "")
+			if err != nil {
+				t.Fatalf("detectContentExt: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("detectContentExt() = %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// encodeTestUTF16 将文本编码为带BOM的UTF-16
+func encodeTestUTF16(text string, order binary.ByteOrder) []byte {
+	data := []byte{0xFF, 0xFE}
+	if order == binary.BigEndian {
+		data = []byte{0xFE, 0xFF}
+	}
+	for _, u := range utf16.Encode([]rune(text)) {
+		data = append(data, 0, 0)
+		order.PutUint16(data[len(data)-2:], u)
+	}
+	return data
+}
+
+func TestDetectTextEncodings(t *testing.T) {
+	// "你好，世界"的GBK编码
+	gbk := []byte{0xC4, 0xE3, 0xBA, 0xC3, 0xA3, 0xAC, 0xCA, 0xC0, 0xBD, 0xE7}
+	gbkHTML := append(append([]byte(`<html><head><meta charset="gbk"></head><body>`), gbk...), "</body></html>"...)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		claimed string
+		want    string
+	}{
+		{"UTF-16LE", encodeTestUTF16("你好，世界\r\n", binary.LittleEndian), ".docx", ".txt"},
+		{"UTF-16BE", encodeTestUTF16("你好，世界", binary.BigEndian), ".txt", ".txt"},
+		{"UTF-16 HTML", encodeTestUTF16("<!DOCTYPE html><html></html>", binary.LittleEndian), ".html", ".html"},
+		{"UTF-16截断", encodeTestUTF16("你好", binary.LittleEndian)[:5], ".txt", ".txt"},
+		{"UTF-16二进制", append(encodeTestUTF16("a", binary.LittleEndian), 0, 0), ".txt", ""},
+		{"GBK文本", gbk, ".txt", ".txt"},
+		{"GBK网页", gbkHTML, ".html", ".html"},
+		{"GBK网页.htm", gbkHTML, ".htm", ".html"},
+		{"GBK内容其他扩展名", gbk, ".docx", ""},
+		{"非UTF-8且包含控制字符", append([]byte{0x1B}, gbk...), ".txt", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := detectContentExt(bytes.NewReader(tt.data), int64(len(tt.data)), tt.claimed)
+			if err != nil {
+				t.Fatalf("detectContentExt: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("detectContentExt(%s) = %q，期望 %q", tt.claimed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectOLE2ExtDirectoryLoop(t *testing.T) {
+	// FAT中目录扇区指向自身时不应无限循环
+	data := buildTestCFB(9, "\x01CompObj", "1Table", "Data", "ObjectPool")
+	binary.LittleEndian.PutUint32(data[512+4:], 1)
+	if got := detectOLE2Ext(bytes.NewReader(data), int64(len(data))); got != "" {
+		t.Errorf("detectOLE2Ext() = %q，期望空字符串", got)
+	}
+}
+
+func TestReconcileInputExtOLE2(t *testing.T) {
+	saved := CONTENT_TYPE_MISMATCH
+	t.Cleanup(func() { CONTENT_TYPE_MISMATCH = saved })
+	CONTENT_TYPE_MISMATCH = MismatchCorrect
+
+	tests := []struct {
+		claimed, detected string
+		want              string
+		wantErr           bool
+	}{
+		{".doc", ".doc", ".doc", false},
+		{".wps", ".doc", ".wps", false},
+		{".doc", ".xls", "", true}, // 改名为.doc的Excel文件不支持转换
+		{".doc", ".ppt", "", true},
+		{".doc", "", "", true},
+	}
+	for _, tt := range tests {
+		got, err := reconcileInputExt(tt.claimed, tt.detected)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("reconcileInputExt(%q, %q) = %q, %v", tt.claimed, tt.detected, got, err)
+		}
+	}
+}
+
+func TestContentErrorDetailsLocalized(t *testing.T) {
+	saved := CONTENT_TYPE_MISMATCH
+	t.Cleanup(func() { CONTENT_TYPE_MISMATCH = saved })
+	CONTENT_TYPE_MISMATCH = MismatchReject
+
+	_, err := reconcileInputExt(".docx", ".pdf")
+	if err == nil {
+		t.Fatal("扩展名与内容不符时应返回错误")
+	}
+	convErr := newConversionError(415, CodeContentMismatch, "error.content_mismatch").withDetailError(err)
+	if got, want := convErr.response(LangEn).Details, "The file extension is .docx, but the content is in pdf format"; got != want {
+		t.Errorf("英文详情 = %q, 期望 %q", got, want)
+	}
+	if got, want := convErr.response(LangZhCN).Details, err.Error(); got != want {
+		t.Errorf("中文详情 = %q, 期望 %q", got, want)
+	}
+
+	archiveErr := inspectArchive(bytes.NewReader([]byte("PK\x03\x04")), 4)
+	convErr = newConversionError(422, CodeArchiveInvalid, "error.archive_limit").withDetailError(archiveErr)
+	if got := convErr.response(LangEn).Details; !strings.HasPrefix(got, "The archive could not be parsed: ") {
+		t.Errorf("压缩包错误的英文详情 = %q", got)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// conversionError 转换流程中的错误，消息在返回时按语言翻译
+// HTTP接口使用请求的语言，命令行转换使用DEFAULT_LANGUAGE
+type conversionError struct {
+	status      int
+	code        string
+	messageID   string
+	messageArgs []interface{}
+	detailID    string // 为空时使用details
+	detailArgs  []interface{}
+	details     string
+}
+
+// newConversionError 创建转换错误，messageID为消息目录中的ID
+func newConversionError(status int, code, messageID string, args ...interface{}) *conversionError {
+	return &conversionError{status: status, code: code, messageID: messageID, messageArgs: args}
+}
+
+// withDetails 设置不需要翻译的详细信息，如底层错误或soffice的输出
+func (e *conversionError) withDetails(details string) *conversionError {
+	e.details = details
+	return e
+}
+
+// withDetailMessage 设置需要翻译的详细信息
+func (e *conversionError) withDetailMessage(id string, args ...interface{}) *conversionError {
+	e.detailID, e.detailArgs = id, args
+	return e
+}
+
+// withDetailError 使用错误作为详细信息，可以翻译的错误按请求语言翻译
+func (e *conversionError) withDetailError(err error) *conversionError {
+	var le *localizedError
+	if errors.As(err, &le) {
+		return e.withDetailMessage(le.id, le.args...)
+	}
+	return e.withDetails(err.Error())
+}
+
+// response 按语言生成错误响应
+func (e *conversionError) response(lang string) ErrorResponse {
+	details := e.details
+	if e.detailID != "" {
+		details = localize(lang, e.detailID, e.detailArgs...)
+	}
+	return ErrorResponse{Error: localize(lang, e.messageID, e.messageArgs...), Code: e.code, Details: details}
+}
+
+func (e *conversionError) Error() string {
+	resp := e.response(DEFAULT_LANGUAGE)
+	if resp.Details == "" {
+		return resp.Error
+	}
+	return resp.Error + ": " + resp.Details
+}
+
+// checkInputFile 校验输入文件的扩展名和内容，返回根据内容识别的扩展名和实际用于转换的扩展名
+// 基于ZIP的文档同时检查压缩包安全限制
+func checkInputFile(ctx context.Context, logger *slog.Logger, r io.ReaderAt, size int64, filename, fileExt string) (string, string, *conversionError) {
+	if !isValidInputFormat(fileExt) {
+		return "", "", newConversionError(http.StatusBadRequest, CodeUnsupportedInputFormat, "error.unsupported_input_format").
+			withDetailMessage("detail.unsupported_input_format", fileExt)
+	}
+
+	// 根据文件内容校验文件类型，扩展名不可信
+	_, span := startSpan(ctx, "upload.inspect", attribute.String("upload.claimed_format", fileExt))
+	detectedExt, err := detectContentExt(r, size, fileExt)
+	if err == nil {
+		span.SetAttributes(attribute.String("upload.detected_format", detectedExt))
+		if isZipExt(detectedExt) {
+			err = inspectArchive(r, size)
+		}
+	}
+	endSpan(span, err)
+	var archiveErr *ArchiveError
+	if errors.As(err, &archiveErr) {
+		// 基于ZIP的文档超出压缩包安全限制
+		logger.Warn("拒绝转换请求: 超出压缩包安全限制", "filename", filename, "code", archiveErr.Code, "error", err)
+		return "", "", newConversionError(http.StatusUnprocessableEntity, archiveErr.Code, "error.archive_limit").withDetailError(err)
+	}
+	if err != nil {
+		return "", "", newConversionError(http.StatusInternalServerError, CodeInternalError, "error.read_upload", err)
+	}
+
+	inputExt, err := reconcileInputExt(fileExt, detectedExt)
+	if err != nil {
+		logger.Warn("拒绝转换请求: 文件内容与格式不符", "filename", filename, "error", err)
+		return "", "", newConversionError(http.StatusUnsupportedMediaType, CodeContentMismatch, "error.content_mismatch").withDetailError(err)
+	}
+	return detectedExt, inputExt, nil
+}
+
+// parseConvertFormat 解析目标格式，返回传给soffice的格式（可以附带导出过滤器）和目标扩展名，默认转换为txt
+// 格式不支持时也返回目标扩展名，用于记录审计日志
+func parseConvertFormat(format string) (string, string, *conversionError) {
+	if format == "" {
+		format = "txt"
+	}
+	targetExt := strings.ToLower(strings.Split(format, ":")[0])
+	if !isValidOutputFormat(targetExt) {
+		return format, targetExt, newConversionError(http.StatusBadRequest, CodeUnsupportedOutputFormat, "error.unsupported_output_format").
+			withDetailMessage("detail.unsupported_output_format", targetExt)
+	}
+	return format, targetExt, nil
+}
+
+// runConversion 在工作目录中执行soffice转换，返回转换结果的路径
+// 转换前等待空闲的转换槽位，使用独立的用户配置目录，并按配置启用沙箱、资源限制和超时
+func runConversion(ctx context.Context, logger *slog.Logger, workDir, filePath, convertFormat, targetExt, uniqueID, tenantID string) (string, *conversionError) {
+	logger.Info("开始转换文件", "path", filePath, "target_format", targetExt, "conversion_id", uniqueID)
+
+	// 构建转换命令
+	convertCmd := []string{
+		profileInstallationArg(uniqueID),
+		"--headless",
+		"--convert-to",
+		convertFormat,
+		filePath,
+		"--outdir",
+		workDir,
+	}
+
+	// 准备独立的用户配置目录，不可信文档模式下禁用宏和外部内容
+	if err := prepareProfile(uniqueID); err != nil {
+		logger.Error("准备LibreOffice用户配置失败", "error", err)
+		return "", newConversionError(http.StatusInternalServerError, CodeConversionFailed, "error.conversion_failed").
+			withDetailMessage("detail.prepare_profile", err)
+	}
+
+	// 等待空闲的转换槽位
+	_, span := startSpan(ctx, "queue.wait", attribute.String("tenant.id", tenantID))
+	queueStart := time.Now()
+	release, err := conversionPool.acquire(ctx, tenantID)
+	queueWaitDuration.Observe(time.Since(queueStart).Seconds())
+	endSpan(span, err)
+	if err != nil {
+		logger.Warn("等待转换槽位失败", "error", err)
+		code := CodeServiceBusy
+		if errors.Is(err, ErrQueueTimeout) {
+			code = CodeQueueTimeout
+		}
+		return "", newConversionError(http.StatusServiceUnavailable, code, "error.service_busy").withDetails(err.Error())
+	}
+
+	logger.Debug("执行转换命令", "command", SOFFICE_PATH+" "+strings.Join(convertCmd, " "))
+
+	// 执行转换命令，启用沙箱时soffice只能访问工作目录和用户配置目录
+	cmd, err := sofficeCommand(uniqueID, convertCmd, []string{workDir, profileDirFor(uniqueID)})
+	if err != nil {
+		release()
+		logger.Error("创建转换命令失败", "error", err)
+		return "", newConversionError(http.StatusInternalServerError, CodeConversionFailed, "error.conversion_failed").withDetails(err.Error())
+	}
+	_, span = startSpan(ctx, "soffice.exec",
+		attribute.String("soffice.path", SOFFICE_PATH),
+		attribute.StringSlice("soffice.args", convertCmd),
+		attribute.Bool("soffice.sandbox", sandboxActive),
+	)
+	sofficeStart := time.Now()
+	output, err := runSofficeWithTimeout(cmd, time.Duration(CONVERSION_TIMEOUT_SECONDS)*time.Second)
+	release()
+	conversionDuration.WithLabelValues(inputFormatLabel(strings.TrimPrefix(filepath.Ext(filePath), ".")), outputFormatLabel(targetExt)).
+		Observe(time.Since(sofficeStart).Seconds())
+	if cmd.ProcessState != nil {
+		span.SetAttributes(attribute.Int("soffice.exit_code", cmd.ProcessState.ExitCode()))
+	}
+	endSpan(span, err)
+	outputStr := string(output)
+
+	// 检查命令是否出错
+	if err != nil {
+		logger.Error("转换过程出错", "error", err, "output", outputStr)
+		if errors.Is(err, errSofficeTimeout) {
+			return "", newConversionError(http.StatusGatewayTimeout, CodeConversionTimeout, "error.conversion_timeout").
+				withDetailMessage("detail.conversion_timeout", CONVERSION_TIMEOUT_SECONDS)
+		}
+		if exceededResourceLimit(err) {
+			return "", newConversionError(http.StatusUnprocessableEntity, CodeResourceLimit, "error.resource_limit").withDetails(err.Error())
+		}
+		return "", newConversionError(http.StatusInternalServerError, CodeConversionFailed, "error.conversion_failed").
+			withDetails(fmt.Sprintf("%v: %s", err, outputStr))
+	}
+
+	// 检查输出中是否包含错误信息
+	if strings.Contains(outputStr, "Error:") || strings.Contains(outputStr, "error") ||
+		strings.Contains(outputStr, "Failed") || strings.Contains(outputStr, "failed") ||
+		strings.Contains(outputStr, "no export filter") {
+		logger.Error("转换过程有错误信息", "output", outputStr)
+		return "", newConversionError(http.StatusInternalServerError, CodeConversionFailed, "error.conversion_failed").
+			withDetailMessage("detail.libreoffice_error", outputStr)
+	}
+
+	// 列出工作目录中的所有文件
+	_, span = startSpan(ctx, "output.search", attribute.String("work_dir", workDir))
+	files, err := os.ReadDir(workDir)
+	if err != nil {
+		endSpan(span, err)
+		return "", newConversionError(http.StatusInternalServerError, CodeInternalError, "error.read_work_dir", err)
+	}
+
+	// 记录所有文件用于调试
+	var allFiles []string
+	for _, f := range files {
+		allFiles = append(allFiles, f.Name())
+	}
+
+	// 查找转换后的输出文件
+	var outputPath string
+
+	for _, file := range files {
+		fileName := file.Name()
+		// 检查文件是否有目标扩展名，且不是原始输入文件
+		if strings.HasSuffix(strings.ToLower(fileName), fmt.Sprintf(".%s", targetExt)) &&
+			fileName != filepath.Base(filePath) {
+			outputPath = filepath.Join(workDir, fileName)
+			logger.Debug("找到转换后的文件", "path", outputPath)
+			break
+		}
+	}
+	span.SetAttributes(attribute.Int("output.candidates", len(files)), attribute.String("output.path", outputPath))
+	span.End()
+
+	// 如果没有找到输出文件，返回错误
+	if outputPath == "" {
+		logger.Error("未找到输出文件", "work_dir_files", allFiles)
+		return "", newConversionError(http.StatusInternalServerError, CodeConversionFailed, "error.output_missing").
+			withDetailMessage("detail.output_missing", targetExt)
+	}
+	return outputPath, nil
+}
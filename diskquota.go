@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 磁盘配额配置
+var (
+	MAX_STORAGE_BYTES   int64 // 存储中转换结果的总大小上限，0表示不限制
+	MIN_FREE_DISK_BYTES int64 // 可用磁盘空间低于该值时拒绝新的转换，0表示不检查
+
+	storageUsageMu sync.RWMutex
+	storageUsage   StorageUsage
+	// 存储中的文件（包括元数据文件），扫描时重建，保存和删除文件时增量更新
+	storageIndex = map[string]StoredFile{}
+
+	// 请求执行一次配额检查，由清理任务goroutine处理
+	quotaCheckCh = make(chan struct{}, 1)
+)
+
+// ErrLowDiskSpace 可用磁盘空间不足
+var ErrLowDiskSpace = errors.New("磁盘空间不足")
+
+// StorageUsage 存储占用情况，Bytes和Files在两次扫描之间按保存和删除的文件增量更新
+type StorageUsage struct {
+	Bytes     int64
+	Files     int // 转换结果文件数，不包括元数据文件
+	ScannedAt time.Time
+}
+
+// DiskUsageInfo 健康检查中的磁盘使用情况
+type DiskUsageInfo struct {
+	StorageBytes    int64  `json:"storage_bytes"`
+	StorageFiles    int    `json:"storage_files"`
+	MaxStorageBytes int64  `json:"max_storage_bytes"`
+	FreeBytes       uint64 `json:"free_bytes"`
+	MinFreeBytes    int64  `json:"min_free_bytes"`
+	LowDiskSpace    bool   `json:"low_disk_space"`
+	ScannedAt       string `json:"scanned_at,omitempty"`
+}
+
+// initDiskQuotaConfig 读取磁盘配额相关的环境变量
+func initDiskQuotaConfig() {
+	MAX_STORAGE_BYTES = getEnvInt64("MAX_STORAGE_BYTES", 0)
+	MIN_FREE_DISK_BYTES = getEnvInt64("MIN_FREE_DISK_BYTES", 0)
+}
+
+// requestQuotaCheck 通知清理任务尽快检查存储配额，不会阻塞
+func requestQuotaCheck() {
+	if MAX_STORAGE_BYTES <= 0 {
+		return
+	}
+	select {
+	case quotaCheckCh <- struct{}{}:
+	default:
+	}
+}
+
+// scanStorageUsage 遍历存储重建文件索引，只列出文件，不读取元数据
+// 多个实例共用对象存储时，其他实例保存和删除的文件在下次扫描时才会计入
+func scanStorageUsage() {
+	index := make(map[string]StoredFile)
+	err := fileStorage.Walk(context.Background(), "", func(file StoredFile) error {
+		index[file.Path] = file
+		return nil
+	})
+	if err != nil {
+		log.Printf("统计存储占用时出错: %v", err)
+		return
+	}
+
+	usage := StorageUsage{ScannedAt: time.Now()}
+	for _, file := range index {
+		usage.Bytes += file.Size
+		if !isMetadataPath(file.Path) {
+			usage.Files++
+		}
+	}
+	storageUsageMu.Lock()
+	storageIndex = index
+	storageUsage = usage
+	storageUsageMu.Unlock()
+}
+
+// recordStoredFile 记录新保存到存储中的文件
+func recordStoredFile(file StoredFile) {
+	storageUsageMu.Lock()
+	defer storageUsageMu.Unlock()
+	if prev, ok := storageIndex[file.Path]; ok {
+		storageUsage.Bytes -= prev.Size
+	} else if !isMetadataPath(file.Path) {
+		storageUsage.Files++
+	}
+	storageIndex[file.Path] = file
+	storageUsage.Bytes += file.Size
+}
+
+// forgetStoredFile 从文件索引中移除已删除的文件
+func forgetStoredFile(relativePath string) {
+	storageUsageMu.Lock()
+	defer storageUsageMu.Unlock()
+	prev, ok := storageIndex[relativePath]
+	if !ok {
+		return
+	}
+	delete(storageIndex, relativePath)
+	storageUsage.Bytes -= prev.Size
+	if !isMetadataPath(relativePath) {
+		storageUsage.Files--
+	}
+}
+
+// enforceStorageQuota 存储占用超过上限时按创建时间从旧到新删除文件，根据文件索引选择文件，不遍历存储
+func enforceStorageQuota() {
+	if MAX_STORAGE_BYTES <= 0 {
+		return
+	}
+	storageUsageMu.RLock()
+	total := storageUsage.Bytes
+	var files []StoredFile
+	if total > MAX_STORAGE_BYTES {
+		for _, file := range storageIndex {
+			if !isMetadataPath(file.Path) {
+				files = append(files, file)
+			}
+		}
+	}
+	storageUsageMu.RUnlock()
+	if total <= MAX_STORAGE_BYTES {
+		return
+	}
+
+	log.Printf("存储占用 %d 字节超过上限 %d 字节，开始删除最旧的文件", total, MAX_STORAGE_BYTES)
+	// 文件保存后不会修改，修改时间即创建时间
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime.Before(files[j].ModTime)
+	})
+	ctx := context.Background()
+	var evicted int
+	for _, file := range files {
+		if currentStorageUsage().Bytes <= MAX_STORAGE_BYTES {
+			break
+		}
+		if err := fileStorage.Delete(ctx, file.Path); err != nil && !errors.Is(err, ErrFileNotFound) {
+			log.Printf("删除文件时出错: %s, %v", file.Path, err)
+			continue
+		}
+		forgetStoredFile(file.Path)
+		if err := fileStorage.Delete(ctx, metadataPath(file.Path)); err == nil || errors.Is(err, ErrFileNotFound) {
+			forgetStoredFile(metadataPath(file.Path))
+		}
+		evicted++
+		log.Printf("因超出存储配额删除文件: %s (%d 字节)", file.Path, file.Size)
+	}
+	log.Printf("配额清理完成，共删除 %d 个文件，当前占用 %d 字节", evicted, currentStorageUsage().Bytes)
+	quotaEvictedFilesTotal.Add(float64(evicted))
+
+	if local, ok := fileStorage.(*localStorage); ok {
+		removeEmptyDirs(local.root)
+	}
+}
+
+// checkFreeDiskSpace 检查临时目录和本地存储目录的可用空间
+func checkFreeDiskSpace(ctx context.Context) error {
+	if MIN_FREE_DISK_BYTES <= 0 {
+		return nil
+	}
+	dirs := []string{TMP_DIR}
+	if local, ok := fileStorage.(*localStorage); ok {
+		dirs = append(dirs, local.root)
+	}
+	for _, dir := range dirs {
+		free, err := diskFreeBytes(dir)
+		if err != nil {
+			loggerFrom(ctx).Warn("获取磁盘可用空间失败", "dir", dir, "error", err)
+			continue
+		}
+		if free < uint64(MIN_FREE_DISK_BYTES) {
+			return fmt.Errorf("%w: %w", ErrLowDiskSpace, newLocalizedError("detail.low_disk_space", dir, free, MIN_FREE_DISK_BYTES))
+		}
+	}
+	return nil
+}
+
+// currentStorageUsage 返回当前的存储占用
+func currentStorageUsage() StorageUsage {
+	storageUsageMu.RLock()
+	defer storageUsageMu.RUnlock()
+	return storageUsage
+}
+
+// currentDiskUsage 汇总健康检查需要的磁盘使用情况
+func currentDiskUsage(ctx context.Context) DiskUsageInfo {
+	usage := currentStorageUsage()
+
+	info := DiskUsageInfo{
+		StorageBytes:    usage.Bytes,
+		StorageFiles:    usage.Files,
+		MaxStorageBytes: MAX_STORAGE_BYTES,
+		MinFreeBytes:    MIN_FREE_DISK_BYTES,
+	}
+	if !usage.ScannedAt.IsZero() {
+		info.ScannedAt = usage.ScannedAt.Format("2006-01-02 15:04:05")
+	}
+	if free, err := diskFreeBytes(TMP_DIR); err == nil {
+		info.FreeBytes = free
+	}
+	if err := checkFreeDiskSpace(ctx); errors.Is(err, ErrLowDiskSpace) {
+		info.LowDiskSpace = true
+	}
+	return info
+}
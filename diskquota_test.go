@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// withTestStorage 使用临时目录作为存储，测试结束后恢复
+func withTestStorage(t *testing.T) *localStorage {
+	t.Helper()
+	saved, savedIndex, savedUsage := fileStorage, storageIndex, storageUsage
+	storage := newLocalStorage(t.TempDir())
+	fileStorage = storage
+	t.Cleanup(func() {
+		fileStorage, storageIndex, storageUsage = saved, savedIndex, savedUsage
+	})
+	return storage
+}
+
+func writeStoredFile(t *testing.T, s *localStorage, relativePath string, size int, modTime time.Time) {
+	t.Helper()
+	p, err := s.LocalPath(relativePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(p, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStorageUsageTracking(t *testing.T) {
+	s := withTestStorage(t)
+	now := time.Now()
+	writeStoredFile(t, s, "20240101/a_1.pdf", 100, now)
+	writeStoredFile(t, s, "20240101/a_1.pdf"+metadataSuffix, 10, now)
+
+	scanStorageUsage()
+	if usage := currentStorageUsage(); usage.Bytes != 110 || usage.Files != 1 {
+		t.Fatalf("扫描后占用 %+v，期望110字节、1个文件", usage)
+	}
+
+	recordStoredFile(StoredFile{Path: "20240102/b_2.pdf", Size: 50, ModTime: now})
+	recordStoredFile(StoredFile{Path: "20240102/b_2.pdf" + metadataSuffix, Size: 5, ModTime: now})
+	// 覆盖同一路径时只计算一次
+	recordStoredFile(StoredFile{Path: "20240102/b_2.pdf", Size: 60, ModTime: now})
+	if usage := currentStorageUsage(); usage.Bytes != 175 || usage.Files != 2 {
+		t.Fatalf("保存后占用 %+v，期望175字节、2个文件", usage)
+	}
+
+	forgetStoredFile("20240101/a_1.pdf")
+	forgetStoredFile("20240101/a_1.pdf")
+	if usage := currentStorageUsage(); usage.Bytes != 75 || usage.Files != 1 {
+		t.Fatalf("删除后占用 %+v，期望75字节、1个文件", usage)
+	}
+}
+
+func TestEnforceStorageQuota(t *testing.T) {
+	s := withTestStorage(t)
+	savedMax := MAX_STORAGE_BYTES
+	t.Cleanup(func() { MAX_STORAGE_BYTES = savedMax })
+
+	base := time.Now().Add(-time.Hour)
+	for i, name := range []string{"oldest_1.pdf", "middle_2.pdf", "newest_3.pdf"} {
+		p := "20240101/" + name
+		writeStoredFile(t, s, p, 100, base.Add(time.Duration(i)*time.Minute))
+		writeStoredFile(t, s, metadataPath(p), 10, base.Add(time.Duration(i)*time.Minute))
+	}
+	scanStorageUsage()
+
+	MAX_STORAGE_BYTES = 250
+	enforceStorageQuota()
+
+	usage := currentStorageUsage()
+	if usage.Bytes != 220 || usage.Files != 2 {
+		t.Errorf("配额清理后占用 %+v，期望220字节、2个文件", usage)
+	}
+	for _, p := range []string{"20240101/oldest_1.pdf", metadataPath("20240101/oldest_1.pdf")} {
+		if _, err := s.Stat(context.Background(), p); err != ErrFileNotFound {
+			t.Errorf("%s应已被删除: %v", p, err)
+		}
+	}
+	for _, p := range []string{"20240101/middle_2.pdf", "20240101/newest_3.pdf"} {
+		if _, err := s.Stat(context.Background(), p); err != nil {
+			t.Errorf("%s不应被删除: %v", p, err)
+		}
+	}
+
+	// 未超出上限时不删除文件
+	MAX_STORAGE_BYTES = 1000
+	enforceStorageQuota()
+	if got := currentStorageUsage().Files; got != 2 {
+		t.Errorf("未超出上限时文件数变为 %d", got)
+	}
+}
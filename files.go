@@ -0,0 +1,271 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FileInfoResponse 转换结果文件信息
+type FileInfoResponse struct {
+	Path        string `json:"path"`
+	Filename    string `json:"filename"`
+	Size        int64  `json:"size"`
+	MimeType    string `json:"mime_type"`
+	CreatedAt   string `json:"created_at"`
+	Expiry      string `json:"expiry"`
+	DownloadURL string `json:"download_url"`
+}
+
+// FileListResponse 文件列表响应
+type FileListResponse struct {
+	Files    []FileInfoResponse `json:"files"`
+	Page     int                `json:"page"`
+	PageSize int                `json:"page_size"`
+	Total    int                `json:"total"`
+}
+
+// DeleteFileResponse 删除文件响应
+type DeleteFileResponse struct {
+	Success bool   `json:"success"`
+	Path    string `json:"path"`
+}
+
+// callerIdentity 返回请求方的身份标识，用于记录和校验文件归属
+// 已认证的请求使用API密钥ID或JWT主体，否则使用客户端IP
+func callerIdentity(c *gin.Context) string {
+	if p := currentPrincipal(c); p != nil {
+		return p.ID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// requireFileOwnerIdentity 未启用认证时请求方只能按客户端IP识别，同一NAT或代理后的客户端会被视为同一请求方，
+// 因此不提供列出和删除文件的接口，返回false时已写入错误响应
+func requireFileOwnerIdentity(c *gin.Context) bool {
+	if authEnabled() {
+		return true
+	}
+	respondError(c, http.StatusForbidden, ErrorResponse{Error: tr(c, "error.file_management_requires_auth"), Code: CodeFeatureDisabled})
+	return false
+}
+
+// formatExpiry 按请求的语言格式化过期时间，nil表示永不过期
+func formatExpiry(c *gin.Context, t *time.Time) string {
+	if t == nil {
+		return tr(c, "misc.never_expires")
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// authorizeFileAccess 校验请求方是否可以访问文件
+// 启用认证时只有文件创建者和拥有admin权限的请求方可以访问；
+// 否则只有持有该文件有效下载签名的请求方可以访问，客户端IP可以伪造，不能据此认定文件创建者
+func authorizeFileAccess(c *gin.Context, relativePath string) (FileMetadata, bool) {
+	if !tenantCanAccess(c, relativePath) {
+		return FileMetadata{}, false
+	}
+	meta, err := loadFileMetadata(c.Request.Context(), relativePath)
+	if err != nil && !errors.Is(err, ErrFileNotFound) {
+		requestLogger(c).Warn("读取文件元数据失败", "path", relativePath, "error", err)
+	}
+	if !authEnabled() {
+		return meta, verifyDownloadSignature(relativePath, c.Query("expires"), c.Query("signature")) == nil
+	}
+	if err == nil && meta.Creator == callerIdentity(c) {
+		return meta, true
+	}
+	p := currentPrincipal(c)
+	return meta, p != nil && p.HasScope(ScopeAdmin)
+}
+
+// buildFileInfo 根据存储信息和元数据构建文件信息
+func buildFileInfo(c *gin.Context, file StoredFile, meta FileMetadata) FileInfoResponse {
+	createdAt := meta.CreatedAt
+	expiresAt := meta.ExpiresAt
+	if createdAt.IsZero() {
+		// 没有元数据的旧文件按修改时间计算
+		createdAt = file.ModTime
+		expiresAt, _ = storedFileExpiry(c.Request.Context(), file)
+	}
+	fileName := path.Base(file.Path)
+	downloadURL, _ := buildDownloadURL(c, file.Path)
+	return FileInfoResponse{
+		Path:        file.Path,
+		Filename:    fileName,
+		Size:        file.Size,
+		MimeType:    detectMimeType(fileName),
+		CreatedAt:   createdAt.Format("2006-01-02 15:04:05"),
+		Expiry:      formatExpiry(c, expiresAt),
+		DownloadURL: downloadURL,
+	}
+}
+
+// fileRequestPath 解析/files/*path路由中的文件路径
+func fileRequestPath(c *gin.Context, suffix string) (string, bool) {
+	p := c.Param("path")
+	if suffix != "" {
+		if !strings.HasSuffix(p, suffix) {
+			return "", false
+		}
+		p = strings.TrimSuffix(p, suffix)
+	}
+	relativePath, err := cleanStoragePath(p)
+	if err != nil || isMetadataPath(relativePath) {
+		return "", false
+	}
+	return relativePath, true
+}
+
+// 文件信息处理，支持GET和HEAD
+func fileInfoHandler(c *gin.Context) {
+	relativePath, ok := fileRequestPath(c, "/info")
+	if !ok {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: tr(c, "error.file_not_found"), Code: CodeFileNotFound})
+		return
+	}
+
+	storedFile, err := fileStorage.Stat(c.Request.Context(), relativePath)
+	if err != nil {
+		if errors.Is(err, ErrFileNotFound) {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: tr(c, "error.file_not_found"), Code: CodeFileNotFound})
+		} else {
+			requestLogger(c).Error("检查文件状态出错", "path", relativePath, "error", err)
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: tr(c, "error.file_access"), Code: CodeStorageError})
+		}
+		return
+	}
+
+	meta, allowed := authorizeFileAccess(c, relativePath)
+	if !allowed {
+		// 不区分无权限和不存在，避免泄露其他人的文件
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: tr(c, "error.file_not_found"), Code: CodeFileNotFound})
+		return
+	}
+
+	info := buildFileInfo(c, storedFile, meta)
+	if c.Request.Method == http.MethodHead {
+		c.Header("X-File-Size", strconv.FormatInt(info.Size, 10))
+		c.Header("X-File-Mime-Type", info.MimeType)
+		c.Header("X-File-Created-At", info.CreatedAt)
+		c.Header("X-File-Expiry", info.Expiry)
+		c.Status(http.StatusOK)
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+// 删除文件处理
+func deleteFileHandler(c *gin.Context) {
+	if !requireFileOwnerIdentity(c) {
+		return
+	}
+	relativePath, ok := fileRequestPath(c, "")
+	if !ok {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: tr(c, "error.file_not_found"), Code: CodeFileNotFound})
+		return
+	}
+
+	if _, err := fileStorage.Stat(c.Request.Context(), relativePath); err != nil {
+		if errors.Is(err, ErrFileNotFound) {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: tr(c, "error.file_not_found"), Code: CodeFileNotFound})
+		} else {
+			requestLogger(c).Error("检查文件状态出错", "path", relativePath, "error", err)
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: tr(c, "error.file_access"), Code: CodeStorageError})
+		}
+		return
+	}
+
+	if _, allowed := authorizeFileAccess(c, relativePath); !allowed {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: tr(c, "error.file_not_found"), Code: CodeFileNotFound})
+		return
+	}
+
+	if err := fileStorage.Delete(c.Request.Context(), relativePath); err != nil {
+		requestLogger(c).Error("删除文件失败", "path", relativePath, "error", err)
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: tr(c, "error.delete_file"), Code: CodeStorageError, Details: err.Error()})
+		return
+	}
+	forgetStoredFile(relativePath)
+	if err := fileStorage.Delete(c.Request.Context(), metadataPath(relativePath)); err != nil && !errors.Is(err, ErrFileNotFound) {
+		requestLogger(c).Warn("删除文件元数据失败", "path", relativePath, "error", err)
+	} else {
+		forgetStoredFile(metadataPath(relativePath))
+	}
+
+	requestLogger(c).Info("已删除文件", "path", relativePath, "client", callerIdentity(c))
+	c.JSON(http.StatusOK, DeleteFileResponse{Success: true, Path: relativePath})
+}
+
+// 文件列表处理，只列出请求方创建的文件
+func listFilesHandler(c *gin.Context) {
+	if !requireFileOwnerIdentity(c) {
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+	// prefix相对于租户的存储路径，只遍历请求方所在租户的文件
+	prefix := strings.TrimPrefix(c.Query("prefix"), "/")
+	if prefix == ".." || strings.HasPrefix(prefix, "../") || strings.Contains(prefix, "/../") {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: tr(c, "error.invalid_path"), Code: CodeInvalidPath})
+		return
+	}
+	if root := currentTenant(c).storagePrefix(); root != "" {
+		prefix = root + "/" + prefix
+	}
+	caller := callerIdentity(c)
+
+	type ownedFile struct {
+		file StoredFile
+		meta FileMetadata
+	}
+	var owned []ownedFile
+	err = fileStorage.Walk(c.Request.Context(), prefix, func(file StoredFile) error {
+		if isMetadataPath(file.Path) || !tenantCanAccess(c, file.Path) {
+			return nil
+		}
+		meta, err := loadFileMetadata(c.Request.Context(), file.Path)
+		if err != nil || meta.Creator != caller {
+			return nil
+		}
+		owned = append(owned, ownedFile{file: file, meta: meta})
+		return nil
+	})
+	if err != nil {
+		requestLogger(c).Error("列出文件失败", "prefix", prefix, "error", err)
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: tr(c, "error.list_files"), Code: CodeStorageError, Details: err.Error()})
+		return
+	}
+
+	// 按创建时间倒序
+	sort.Slice(owned, func(i, j int) bool {
+		return owned[i].meta.CreatedAt.After(owned[j].meta.CreatedAt)
+	})
+
+	response := FileListResponse{
+		Files:    []FileInfoResponse{},
+		Page:     page,
+		PageSize: pageSize,
+		Total:    len(owned),
+	}
+	start := (page - 1) * pageSize
+	for i := start; i < len(owned) && i < start+pageSize; i++ {
+		response.Files = append(response.Files, buildFileInfo(c, owned[i].file, owned[i].meta))
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestFileInfoWithoutAuthRequiresSignature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	storage := withTestStorage(t)
+	withSigningKey(t, "test-key")
+	savedExpiry := DOWNLOAD_URL_EXPIRY_MINUTES
+	DOWNLOAD_URL_EXPIRY_MINUTES = 10
+	t.Cleanup(func() { DOWNLOAD_URL_EXPIRY_MINUTES = savedExpiry })
+
+	const relativePath = "20260101/a.pdf"
+	writeStoredFile(t, storage, relativePath, 10, time.Now())
+	meta := FileMetadata{OriginalFilename: "a.docx", Creator: "ip:192.0.2.1", CreatedAt: time.Now()}
+	if err := saveFileMetadata(context.Background(), relativePath, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.GET("/files/*path", fileInfoHandler)
+	query, _ := signedDownloadQuery(relativePath)
+
+	tests := []struct {
+		name       string
+		query      string
+		forwarded  string
+		wantStatus int
+	}{
+		{"客户端IP与创建者相同", "", "192.0.2.1", http.StatusNotFound},
+		{"有效签名", query.Encode(), "", http.StatusOK},
+		{"无效签名", "expires=" + query.Get("expires") + "&signature=bad", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/files/"+relativePath+"/info?"+tt.query, nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("状态码 = %d, 期望 %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// 就绪检查配置
+var (
+	READINESS_CANARY_INTERVAL_SECONDS int // 金丝雀转换的执行间隔
+	READINESS_CANARY_TIMEOUT_SECONDS  int // 单次金丝雀转换的超时时间
+	READINESS_MAX_QUEUED              int // 排队的转换请求达到该数量时视为未就绪，0表示不检查
+
+	canaryMu     sync.RWMutex
+	canaryResult = ReadinessCheck{Name: "conversion", Status: CheckPending, Message: "尚未执行金丝雀转换"}
+)
+
+// 单项检查的状态
+const (
+	CheckOK      = "ok"
+	CheckFail    = "fail"
+	CheckPending = "pending"
+)
+
+// 金丝雀转换使用的输入内容
+const canaryContent = "libreoffice-api readiness canary\n"
+
+// ReadinessCheck 一项就绪检查的结果
+type ReadinessCheck struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`
+	CheckedAt  string `json:"checked_at,omitempty"`
+	DurationMS int64  `json:"duration_ms,omitempty"`
+}
+
+// PoolState 转换槽位和排队情况
+type PoolState struct {
+	Active        int `json:"active"`
+	Queued        int `json:"queued"`
+	MaxConcurrent int `json:"max_concurrent"`
+	MaxQueued     int `json:"max_queued,omitempty"`
+}
+
+// ReadinessResponse 就绪检查响应
+type ReadinessResponse struct {
+	Status string           `json:"status"` // ready或not_ready
+	Checks []ReadinessCheck `json:"checks"`
+	Pool   PoolState        `json:"pool"`
+}
+
+// initReadinessConfig 读取就绪检查相关的环境变量
+func initReadinessConfig() {
+	READINESS_CANARY_INTERVAL_SECONDS = getEnvInt("READINESS_CANARY_INTERVAL_SECONDS", 60)
+	if READINESS_CANARY_INTERVAL_SECONDS <= 0 {
+		READINESS_CANARY_INTERVAL_SECONDS = 60
+	}
+	READINESS_CANARY_TIMEOUT_SECONDS = getEnvInt("READINESS_CANARY_TIMEOUT_SECONDS", 60)
+	READINESS_MAX_QUEUED = getEnvInt("READINESS_MAX_QUEUED", 0)
+}
+
+// startCanaryScheduler 启动时立即执行一次金丝雀转换，之后定期执行
+func startCanaryScheduler(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(time.Duration(READINESS_CANARY_INTERVAL_SECONDS) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			if check, ok := runCanaryCheck(); ok {
+				canaryMu.Lock()
+				previous := canaryResult.Status
+				canaryResult = check
+				canaryMu.Unlock()
+				if check.Status != previous && check.Status == CheckFail {
+					log.Printf("金丝雀转换失败，服务未就绪: %s", check.Message)
+				} else if check.Status != previous {
+					log.Printf("金丝雀转换成功，耗时 %d 毫秒", check.DurationMS)
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// lastCanaryCheck 返回最近一次金丝雀转换的结果
+func lastCanaryCheck() ReadinessCheck {
+	canaryMu.RLock()
+	defer canaryMu.RUnlock()
+	return canaryResult
+}
+
+// runCanaryCheck 占用一个转换槽位执行一次金丝雀转换并返回检查结果
+// 所有槽位都在使用时不执行转换，返回false，就绪检查继续使用上一次的结果
+func runCanaryCheck() (ReadinessCheck, bool) {
+	release, ok := conversionPool.tryAcquire()
+	if !ok {
+		return ReadinessCheck{}, false
+	}
+	defer release()
+
+	start := time.Now()
+	check := ReadinessCheck{Name: "conversion", Status: CheckOK}
+	if err := runCanaryConversion(); err != nil {
+		check.Status = CheckFail
+		check.Message = err.Error()
+	}
+	check.CheckedAt = start.Format(time.RFC3339)
+	check.DurationMS = time.Since(start).Milliseconds()
+	return check, true
+}
+
+// runCanaryConversion 将一个很小的文本文件转换为PDF，使用与正常转换相同的用户配置、沙箱和资源限制
+func runCanaryConversion() error {
+	if !libreofficeAvailable {
+		return fmt.Errorf("LibreOffice不可用: %s", libreofficeVersion)
+	}
+
+	id := uuid.New().String()
+	workDir, err := beginWork(id)
+	if err != nil {
+		return fmt.Errorf("创建工作目录失败: %w", err)
+	}
+	defer endWork(context.Background(), id)
+
+	inputPath := filepath.Join(workDir, "canary.txt")
+	if err := os.WriteFile(inputPath, []byte(canaryContent), 0644); err != nil {
+		return fmt.Errorf("写入金丝雀文件失败: %w", err)
+	}
+	if err := prepareProfile(id); err != nil {
+		return fmt.Errorf("准备LibreOffice用户配置失败: %w", err)
+	}
+
+	args := []string{profileInstallationArg(id), "--headless", "--convert-to", "pdf", inputPath, "--outdir", workDir}
+	cmd, err := sofficeCommand(id, args, []string{workDir, profileDirFor(id)})
+	if err != nil {
+		return err
+	}
+	timeout := time.Duration(READINESS_CANARY_TIMEOUT_SECONDS) * time.Second
+	output, err := runSofficeWithTimeout(cmd, timeout)
+	if errors.Is(err, errSofficeTimeout) {
+		return fmt.Errorf("金丝雀转换超过%v未完成", timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("soffice执行失败: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	info, err := os.Stat(filepath.Join(workDir, "canary.pdf"))
+	if err != nil || info.Size() == 0 {
+		return fmt.Errorf("未生成转换结果: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// checkDirWritable 在目录中创建并删除一个临时文件，确认目录可写
+func checkDirWritable(name, dir string) ReadinessCheck {
+	check := ReadinessCheck{Name: name, Status: CheckOK}
+	f, err := os.CreateTemp(dir, ".readyz-*")
+	if err == nil {
+		path := f.Name()
+		_, err = f.WriteString("ok")
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if removeErr := os.Remove(path); err == nil {
+			err = removeErr
+		}
+	}
+	if err != nil {
+		check.Status = CheckFail
+		check.Message = fmt.Sprintf("%s不可写: %v", dir, err)
+	}
+	return check
+}
+
+// readinessChecks 汇总当前的就绪检查结果，金丝雀转换使用最近一次的结果，其余检查实时执行
+func readinessChecks(ctx context.Context) ([]ReadinessCheck, PoolState) {
+	checks := []ReadinessCheck{lastCanaryCheck()}
+
+	checks = append(checks, checkDirWritable("tmp_dir", TMP_DIR))
+	if local, ok := fileStorage.(*localStorage); ok {
+		checks = append(checks, checkDirWritable("data_dir", local.root))
+	}
+
+	disk := ReadinessCheck{Name: "disk_space", Status: CheckOK}
+	if err := checkFreeDiskSpace(ctx); err != nil {
+		disk.Status = CheckFail
+		disk.Message = err.Error()
+	}
+	checks = append(checks, disk)
+
+	active, queued := conversionPool.stats()
+	pool := PoolState{Active: active, Queued: queued, MaxConcurrent: MAX_CONCURRENT_CONVERSIONS, MaxQueued: READINESS_MAX_QUEUED}
+	queue := ReadinessCheck{Name: "queue", Status: CheckOK}
+	if READINESS_MAX_QUEUED > 0 && queued >= READINESS_MAX_QUEUED {
+		queue.Status = CheckFail
+		queue.Message = fmt.Sprintf("排队的转换请求数 %d 达到上限 %d", queued, READINESS_MAX_QUEUED)
+	}
+	checks = append(checks, queue)
+	return checks, pool
+}
+
+// isReady 所有检查都通过时服务才就绪，金丝雀转换尚未完成时视为未就绪
+func isReady(checks []ReadinessCheck) bool {
+	for _, check := range checks {
+		if check.Status != CheckOK {
+			return false
+		}
+	}
+	return true
+}
+
+// 存活检查处理，进程能够处理请求即返回200，不检查依赖
+func livezHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// 就绪检查处理，任一检查未通过时返回503
+func readyzHandler(c *gin.Context) {
+	checks, pool := readinessChecks(c.Request.Context())
+	response := ReadinessResponse{Status: "ready", Checks: checks, Pool: pool}
+	status := http.StatusOK
+	if !isReady(checks) {
+		response.Status = "not_ready"
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, response)
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+// 金丝雀转换占用一个转换槽位，没有空闲槽位时跳过
+func TestCanaryCheckUsesConversionSlot(t *testing.T) {
+	withFakeSoffice(t)
+
+	var releases []func()
+	for {
+		release, ok := conversionPool.tryAcquire()
+		if !ok {
+			break
+		}
+		releases = append(releases, release)
+	}
+	if _, ok := runCanaryCheck(); ok {
+		t.Fatal("所有槽位都在使用时不应执行金丝雀转换")
+	}
+
+	releases[0]()
+	check, ok := runCanaryCheck()
+	if !ok || check.Status != CheckOK {
+		t.Fatalf("有空闲槽位时金丝雀转换结果为 %+v, %v", check, ok)
+	}
+	if active, _ := conversionPool.stats(); active != len(releases)-1 {
+		t.Errorf("金丝雀转换后占用 %d 个槽位，期望 %d", active, len(releases)-1)
+	}
+	for _, release := range releases[1:] {
+		release()
+	}
+}
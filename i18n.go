@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 支持的语言
+const (
+	LangZhCN = "zh-CN"
+	LangEn   = "en"
+)
+
+// DEFAULT_LANGUAGE 请求未指定语言或指定的语言不受支持时使用的语言
+var DEFAULT_LANGUAGE string
+
+// 请求上下文中保存协商结果的键
+const languageContextKey = "language"
+
+// 页面模板中的翻译占位符，如 ${t:index.title}
+var templateMessagePattern = regexp.MustCompile(`\$\{t:([a-z0-9_.]+)\}`)
+
+// catalogs 各语言的消息目录，键为消息ID，值可以包含fmt格式化占位符
+var catalogs = map[string]map[string]string{
+	LangZhCN: messagesZhCN,
+	LangEn:   messagesEn,
+}
+
+// initLanguageConfig 读取语言相关的环境变量，并检查各语言的消息目录是否完整
+func initLanguageConfig() {
+	lang, ok := matchLanguage(getEnvString("DEFAULT_LANGUAGE", LangZhCN))
+	if !ok {
+		log.Fatalf("无效的DEFAULT_LANGUAGE: %q，可选值为%s、%s", getEnvString("DEFAULT_LANGUAGE", LangZhCN), LangZhCN, LangEn)
+	}
+	DEFAULT_LANGUAGE = lang
+
+	for lang, catalog := range catalogs {
+		for id := range messagesZhCN {
+			if _, ok := catalog[id]; !ok {
+				log.Printf("警告: %s消息目录缺少%s，将使用%s", lang, id, LangZhCN)
+			}
+		}
+	}
+}
+
+// matchLanguage 将语言标签匹配到支持的语言，只比较主语言部分，如zh-TW匹配zh-CN、en-US匹配en
+func matchLanguage(tag string) (string, bool) {
+	primary := strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(primary, "-_"); i >= 0 {
+		primary = primary[:i]
+	}
+	switch primary {
+	case "zh":
+		return LangZhCN, true
+	case "en":
+		return LangEn, true
+	}
+	return "", false
+}
+
+// parseAcceptLanguage 按q值从高到低返回Accept-Language中的语言标签
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			tags = append(tags, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = t.tag
+	}
+	return result
+}
+
+// negotiateLanguage 根据lang查询参数或Accept-Language请求头选择语言，lang参数优先
+func negotiateLanguage(c *gin.Context) string {
+	if lang, ok := matchLanguage(c.Query("lang")); ok {
+		return lang
+	}
+	for _, tag := range parseAcceptLanguage(c.GetHeader("Accept-Language")) {
+		if lang, ok := matchLanguage(tag); ok {
+			return lang
+		}
+	}
+	return DEFAULT_LANGUAGE
+}
+
+// requestLanguage 返回当前请求使用的语言
+func requestLanguage(c *gin.Context) string {
+	if lang := c.GetString(languageContextKey); lang != "" {
+		return lang
+	}
+	lang := negotiateLanguage(c)
+	c.Set(languageContextKey, lang)
+	return lang
+}
+
+// languageMiddleware 协商请求的语言，并在响应头中说明
+func languageMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := requestLanguage(c)
+		c.Header("Content-Language", lang)
+		c.Header("Vary", "Accept-Language")
+		c.Next()
+	}
+}
+
+// localize 返回指定语言的消息，缺少翻译时使用简体中文，消息ID不存在时返回ID本身
+func localize(lang, id string, args ...interface{}) string {
+	msg, ok := catalogs[lang][id]
+	if !ok {
+		msg, ok = messagesZhCN[id]
+	}
+	if !ok {
+		msg = id
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// tr 返回当前请求语言的消息
+func tr(c *gin.Context, id string, args ...interface{}) string {
+	return localize(requestLanguage(c), id, args...)
+}
+
+// localizedError 可以翻译的错误，Error返回简体中文消息，写入响应时按请求语言翻译
+type localizedError struct {
+	id   string
+	args []interface{}
+}
+
+// newLocalizedError 创建可以翻译的错误，id为消息目录中的ID
+func newLocalizedError(id string, args ...interface{}) error {
+	return &localizedError{id: id, args: args}
+}
+
+func (e *localizedError) Error() string {
+	return localize(LangZhCN, e.id, e.args...)
+}
+
+// localizeError 返回指定语言的错误消息，错误不可翻译时返回原始文本
+func localizeError(lang string, err error) string {
+	var le *localizedError
+	if errors.As(err, &le) {
+		return localize(lang, le.id, le.args...)
+	}
+	return err.Error()
+}
+
+// trError 返回当前请求语言的错误消息
+func trError(c *gin.Context, err error) string {
+	return localizeError(requestLanguage(c), err)
+}
+
+// localizeTemplate 将页面模板中的${t:消息ID}替换为对应语言的文本
+func localizeTemplate(tmpl, lang string) string {
+	return templateMessagePattern.ReplaceAllStringFunc(tmpl, func(m string) string {
+		return localize(lang, templateMessagePattern.FindStringSubmatch(m)[1])
+	})
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWT认证配置
+var (
+	JWT_JWKS_FILE            string
+	JWT_JWKS_URL             string
+	JWT_ISSUER               string
+	JWT_AUDIENCE             string
+	JWT_TENANT_CLAIM         string
+	JWT_SCOPE_CLAIM          string
+	JWT_DEFAULT_SCOPES       []string
+	JWT_LEEWAY_SECONDS       int
+	JWT_JWKS_REFRESH_MINUTES int
+
+	jwksMu        sync.RWMutex
+	jwksKeys      map[string]interface{} // 键为kid
+	jwksFetchedAt time.Time
+
+	// 串行化未知kid触发的重新获取，避免并发请求同时请求JWKS地址
+	jwksRefetchMu        sync.Mutex
+	jwksLastRefetchStart time.Time
+)
+
+// 允许的签名算法
+var jwtValidMethods = []string{"RS256", "ES256"}
+
+// 未知kid触发重新获取JWKS的最小间隔
+const jwksMinRefetchInterval = time.Minute
+
+// jsonWebKey JWKS中的一个公钥
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// initJWTConfig 读取JWT相关的环境变量并加载JWKS
+func initJWTConfig() {
+	JWT_JWKS_FILE = getEnvString("JWT_JWKS_FILE", "")
+	JWT_JWKS_URL = getEnvString("JWT_JWKS_URL", "")
+	JWT_ISSUER = getEnvString("JWT_ISSUER", "")
+	JWT_AUDIENCE = getEnvString("JWT_AUDIENCE", "")
+	JWT_TENANT_CLAIM = getEnvString("JWT_TENANT_CLAIM", "tenant")
+	JWT_SCOPE_CLAIM = getEnvString("JWT_SCOPE_CLAIM", "scope")
+	JWT_DEFAULT_SCOPES = splitList(getEnvString("JWT_DEFAULT_SCOPES", "convert,download"))
+	JWT_LEEWAY_SECONDS = getEnvInt("JWT_LEEWAY_SECONDS", 60)
+	JWT_JWKS_REFRESH_MINUTES = getEnvInt("JWT_JWKS_REFRESH_MINUTES", 60)
+
+	if !jwtEnabled() {
+		log.Println("未配置JWT_JWKS_FILE或JWT_JWKS_URL，不启用JWT认证")
+		return
+	}
+	if err := refreshJWKS(); err != nil {
+		log.Fatalf("加载JWKS失败: %v", err)
+	}
+	log.Printf("已启用JWT认证, issuer=%q, audience=%q", JWT_ISSUER, JWT_AUDIENCE)
+}
+
+// jwtEnabled 是否配置了JWT认证
+func jwtEnabled() bool {
+	return JWT_JWKS_FILE != "" || JWT_JWKS_URL != ""
+}
+
+// splitList 拆分以逗号或空格分隔的列表
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+// refreshJWKS 从文件或URL重新读取JWKS
+func refreshJWKS() error {
+	var data []byte
+	var err error
+	if JWT_JWKS_FILE != "" {
+		data, err = os.ReadFile(JWT_JWKS_FILE)
+		if err != nil {
+			return fmt.Errorf("读取JWKS文件失败: %w", err)
+		}
+	} else {
+		data, err = fetchJWKS(JWT_JWKS_URL)
+		if err != nil {
+			return err
+		}
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	jwksMu.Lock()
+	jwksKeys = keys
+	jwksFetchedAt = time.Now()
+	jwksMu.Unlock()
+	log.Printf("已加载JWKS公钥 %d 个", len(keys))
+	return nil
+}
+
+// fetchJWKS 从URL获取JWKS
+func fetchJWKS(jwksURL string) ([]byte, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(jwksURL)
+	if err != nil {
+		return nil, fmt.Errorf("获取JWKS失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取JWKS失败: HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+}
+
+// parseJWKS 解析JWKS中的RSA和EC公钥
+func parseJWKS(data []byte) (map[string]interface{}, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("解析JWKS失败: %w", err)
+	}
+
+	keys := make(map[string]interface{})
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		var key interface{}
+		var err error
+		switch k.Kty {
+		case "RSA":
+			key, err = parseRSAKey(k)
+		case "EC":
+			key, err = parseECKey(k)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析JWKS公钥%q失败: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS中没有可用的签名公钥")
+	}
+	return keys, nil
+}
+
+func decodeBase64URLInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func parseRSAKey(k jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := decodeBase64URLInt(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeBase64URLInt(k.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() {
+		return nil, errors.New("无效的RSA指数")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func parseECKey(k jsonWebKey) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("不支持的曲线: %s", k.Crv)
+	}
+	x, err := decodeBase64URLInt(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeBase64URLInt(k.Y)
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("公钥不在曲线上")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// lookupJWKSKey 根据kid查找公钥，URL方式下遇到未知kid会重新获取JWKS
+func lookupJWKSKey(ctx context.Context, kid string) (interface{}, error) {
+	jwksMu.RLock()
+	key, ok := jwksKeys[kid]
+	count := len(jwksKeys)
+	var only interface{}
+	if kid == "" && count == 1 {
+		for _, k := range jwksKeys {
+			only = k
+		}
+	}
+	fetchedAt := jwksFetchedAt
+	jwksMu.RUnlock()
+
+	if ok {
+		return key, nil
+	}
+	if only != nil {
+		return only, nil
+	}
+	if JWT_JWKS_URL != "" && time.Since(fetchedAt) > jwksMinRefetchInterval {
+		if key, ok := refetchJWKSForKid(ctx, kid); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("未知的签名公钥: %q", kid)
+}
+
+// refetchJWKSForKid 因未知kid重新获取JWKS并查找公钥
+// 同一时间只有一个请求发起获取，等待中的请求在获取完成后直接使用新的公钥；
+// 无论成功与否，两次获取之间至少间隔jwksMinRefetchInterval
+func refetchJWKSForKid(ctx context.Context, kid string) (interface{}, bool) {
+	jwksRefetchMu.Lock()
+	defer jwksRefetchMu.Unlock()
+
+	jwksMu.RLock()
+	key, ok := jwksKeys[kid]
+	fetchedAt := jwksFetchedAt
+	jwksMu.RUnlock()
+	if ok {
+		return key, true
+	}
+	if time.Since(fetchedAt) <= jwksMinRefetchInterval || time.Since(jwksLastRefetchStart) <= jwksMinRefetchInterval {
+		return nil, false
+	}
+
+	jwksLastRefetchStart = time.Now()
+	if err := refreshJWKS(); err != nil {
+		loggerFrom(ctx).Warn("重新获取JWKS失败", "kid", kid, "error", err)
+		return nil, false
+	}
+	jwksMu.RLock()
+	key, ok = jwksKeys[kid]
+	jwksMu.RUnlock()
+	return key, ok
+}
+
+// startJWKSRefresher 定期重新获取远程JWKS，以支持公钥轮换
+func startJWKSRefresher(ctx context.Context, wg *sync.WaitGroup) {
+	if JWT_JWKS_URL == "" || JWT_JWKS_REFRESH_MINUTES <= 0 {
+		return
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(time.Duration(JWT_JWKS_REFRESH_MINUTES) * time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := refreshJWKS(); err != nil {
+					log.Printf("定期获取JWKS失败，继续使用原有公钥: %v", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// looksLikeJWT 判断Bearer令牌是否为JWT格式
+func looksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// parseJWT 校验JWT签名、签发者、受众和过期时间，返回对应的请求方
+func parseJWT(ctx context.Context, tokenString string) (*Principal, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(jwtValidMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Duration(JWT_LEEWAY_SECONDS) * time.Second),
+	}
+	if JWT_ISSUER != "" {
+		opts = append(opts, jwt.WithIssuer(JWT_ISSUER))
+	}
+	if JWT_AUDIENCE != "" {
+		opts = append(opts, jwt.WithAudience(JWT_AUDIENCE))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return lookupJWKSKey(ctx, kid)
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, errors.New("令牌缺少sub声明")
+	}
+	tenant, _ := claims[JWT_TENANT_CLAIM].(string)
+
+	return &Principal{
+		ID:      "jwt:" + subject,
+		Subject: subject,
+		Tenant:  tenant,
+		Scopes:  jwtScopes(claims),
+	}, nil
+}
+
+// jwtScopes 从令牌的权限声明中提取本服务的权限范围，没有该声明时使用默认权限
+func jwtScopes(claims jwt.MapClaims) []string {
+	var values []string
+	switch v := claims[JWT_SCOPE_CLAIM].(type) {
+	case string:
+		values = splitList(v)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	default:
+		return JWT_DEFAULT_SCOPES
+	}
+
+	var scopes []string
+	for _, s := range values {
+		if s == ScopeConvert || s == ScopeDownload || s == ScopeAdmin {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// jwtMiddleware 校验请求中的JWT，成功后将请求方保存到上下文中供处理函数使用
+// 没有携带JWT的请求交给requireScope继续按API密钥处理
+func jwtMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auth := c.GetHeader("Authorization")
+		if len(auth) <= 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+			c.Next()
+			return
+		}
+		token := strings.TrimSpace(auth[7:])
+		if !looksLikeJWT(token) {
+			c.Next()
+			return
+		}
+
+		p, err := parseJWT(c.Request.Context(), token)
+		if err != nil {
+			requestLogger(c).Warn("JWT校验失败", "ip", c.ClientIP(), "error", err)
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			abortWithError(c, http.StatusUnauthorized, ErrorResponse{Error: tr(c, "error.invalid_token"), Code: CodeInvalidToken, Details: err.Error()})
+			return
+		}
+		c.Set(principalContextKey, p)
+		c.Next()
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testJWTKeys 测试用的签名私钥，键为kid
+type testJWTKeys map[string]crypto.Signer
+
+func newTestJWTKeys(t *testing.T) testJWTKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testJWTKeys{"rsa-1": rsaKey, "ec-1": ecKey}
+}
+
+func base64URLInt(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
+
+// jwks 生成包含全部公钥的JWKS文档
+func (keys testJWTKeys) jwks(t *testing.T) []byte {
+	t.Helper()
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	for kid, key := range keys {
+		switch k := key.(type) {
+		case *rsa.PrivateKey:
+			set.Keys = append(set.Keys, jsonWebKey{Kty: "RSA", Kid: kid, Use: "sig", N: base64URLInt(k.N), E: base64URLInt(big.NewInt(int64(k.E)))})
+		case *ecdsa.PrivateKey:
+			set.Keys = append(set.Keys, jsonWebKey{Kty: "EC", Kid: kid, Crv: "P-256", X: base64URLInt(k.X), Y: base64URLInt(k.Y)})
+		}
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// sign 使用指定kid的私钥签发令牌
+func (keys testJWTKeys) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	var method jwt.SigningMethod = jwt.SigningMethodRS256
+	if _, ok := keys[kid].(*ecdsa.PrivateKey); ok {
+		method = jwt.SigningMethodES256
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	s, err := token.SignedString(keys[kid])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// withTestJWTConfig 设置JWT配置，测试结束后恢复
+func withTestJWTConfig(t *testing.T, jwksFile, jwksURL string) {
+	t.Helper()
+	savedFile, savedURL := JWT_JWKS_FILE, JWT_JWKS_URL
+	savedIssuer, savedAudience := JWT_ISSUER, JWT_AUDIENCE
+	savedTenant, savedScope, savedDefault := JWT_TENANT_CLAIM, JWT_SCOPE_CLAIM, JWT_DEFAULT_SCOPES
+	savedLeeway := JWT_LEEWAY_SECONDS
+	jwksMu.RLock()
+	savedKeys, savedFetchedAt := jwksKeys, jwksFetchedAt
+	jwksMu.RUnlock()
+	jwksRefetchMu.Lock()
+	savedRefetch := jwksLastRefetchStart
+	jwksRefetchMu.Unlock()
+
+	JWT_JWKS_FILE, JWT_JWKS_URL = jwksFile, jwksURL
+	JWT_ISSUER, JWT_AUDIENCE = "https://issuer.example.com", "lapi"
+	JWT_TENANT_CLAIM, JWT_SCOPE_CLAIM = "tenant", "scope"
+	JWT_DEFAULT_SCOPES = []string{ScopeConvert, ScopeDownload}
+	JWT_LEEWAY_SECONDS = 0
+
+	t.Cleanup(func() {
+		JWT_JWKS_FILE, JWT_JWKS_URL = savedFile, savedURL
+		JWT_ISSUER, JWT_AUDIENCE = savedIssuer, savedAudience
+		JWT_TENANT_CLAIM, JWT_SCOPE_CLAIM, JWT_DEFAULT_SCOPES = savedTenant, savedScope, savedDefault
+		JWT_LEEWAY_SECONDS = savedLeeway
+		jwksMu.Lock()
+		jwksKeys, jwksFetchedAt = savedKeys, savedFetchedAt
+		jwksMu.Unlock()
+		jwksRefetchMu.Lock()
+		jwksLastRefetchStart = savedRefetch
+		jwksRefetchMu.Unlock()
+	})
+}
+
+func TestParseJWT(t *testing.T) {
+	keys := newTestJWTKeys(t)
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(file, keys.jwks(t), 0644); err != nil {
+		t.Fatal(err)
+	}
+	withTestJWTConfig(t, file, "")
+	if err := refreshJWKS(); err != nil {
+		t.Fatalf("加载JWKS失败: %v", err)
+	}
+
+	other := newTestJWTKeys(t)
+	now := time.Now()
+	claims := func(overrides jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub":    "user-1",
+			"iss":    "https://issuer.example.com",
+			"aud":    "lapi",
+			"exp":    now.Add(time.Hour).Unix(),
+			"tenant": "acme",
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantErr    bool
+		wantScopes []string
+	}{
+		{"RSA签名", keys.sign(t, "rsa-1", claims(nil)), false, []string{ScopeConvert, ScopeDownload}},
+		{"EC签名", keys.sign(t, "ec-1", claims(nil)), false, []string{ScopeConvert, ScopeDownload}},
+		{"字符串权限", keys.sign(t, "rsa-1", claims(jwt.MapClaims{"scope": "convert admin openid"})), false, []string{ScopeConvert, ScopeAdmin}},
+		{"数组权限", keys.sign(t, "ec-1", claims(jwt.MapClaims{"scope": []string{"download", "profile"}})), false, []string{ScopeDownload}},
+		{"空权限", keys.sign(t, "rsa-1", claims(jwt.MapClaims{"scope": ""})), false, nil},
+		{"其他密钥签名", other.sign(t, "rsa-1", claims(nil)), true, nil},
+		{"未知kid", testJWTKeys{"rsa-9": keys["rsa-1"]}.sign(t, "rsa-9", claims(nil)), true, nil},
+		{"篡改签名", keys.sign(t, "ec-1", claims(nil)) + "x", true, nil},
+		{"签发者不符", keys.sign(t, "rsa-1", claims(jwt.MapClaims{"iss": "https://evil.example.com"})), true, nil},
+		{"受众不符", keys.sign(t, "rsa-1", claims(jwt.MapClaims{"aud": "other"})), true, nil},
+		{"已过期", keys.sign(t, "rsa-1", claims(jwt.MapClaims{"exp": now.Add(-time.Minute).Unix()})), true, nil},
+		{"缺少过期时间", keys.sign(t, "rsa-1", claims(jwt.MapClaims{"exp": nil})), true, nil},
+		{"缺少sub", keys.sign(t, "rsa-1", claims(jwt.MapClaims{"sub": nil})), true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := parseJWT(context.Background(), tt.token)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("应校验失败，实际得到 %+v", p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("校验失败: %v", err)
+			}
+			if p.ID != "jwt:user-1" || p.Subject != "user-1" || p.Tenant != "acme" {
+				t.Errorf("请求方 %+v 与令牌声明不符", p)
+			}
+			if !reflect.DeepEqual(p.Scopes, tt.wantScopes) {
+				t.Errorf("权限 %v，期望 %v", p.Scopes, tt.wantScopes)
+			}
+		})
+	}
+}
+
+func TestParseJWTRejectsHS256(t *testing.T) {
+	keys := newTestJWTKeys(t)
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(file, keys.jwks(t), 0644); err != nil {
+		t.Fatal(err)
+	}
+	withTestJWTConfig(t, file, "")
+	if err := refreshJWKS(); err != nil {
+		t.Fatal(err)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "user-1",
+		"iss": JWT_ISSUER,
+		"aud": JWT_AUDIENCE,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = "rsa-1"
+	s, err := token.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseJWT(context.Background(), s); err == nil {
+		t.Error("HS256令牌应被拒绝")
+	}
+}
+
+func TestLookupJWKSKeyRefetchSerialized(t *testing.T) {
+	keys := newTestJWTKeys(t)
+	rotated := newTestJWTKeys(t)
+	var fetches atomic.Int32
+	var current atomic.Value
+	current.Store(keys.jwks(t))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		w.Write(current.Load().([]byte))
+	}))
+	defer server.Close()
+
+	withTestJWTConfig(t, "", server.URL)
+	if err := refreshJWKS(); err != nil {
+		t.Fatal(err)
+	}
+	fetches.Store(0)
+
+	// 公钥轮换后，上次获取超过最小间隔时，未知kid触发一次重新获取
+	rotatedSet := testJWTKeys{"rsa-2": rotated["rsa-1"]}
+	current.Store(rotatedSet.jwks(t))
+	jwksMu.Lock()
+	jwksFetchedAt = time.Now().Add(-2 * jwksMinRefetchInterval)
+	jwksMu.Unlock()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := lookupJWKSKey(context.Background(), "rsa-2"); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("轮换后的公钥查找失败: %v", err)
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("并发查找应只重新获取1次JWKS，实际 %d 次", n)
+	}
+
+	// 刚获取过时，未知kid不再触发获取
+	if _, err := lookupJWKSKey(context.Background(), "missing"); err == nil {
+		t.Error("未知kid应查找失败")
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("最小间隔内不应再次获取JWKS，实际共 %d 次", n)
+	}
+}
+
+func TestLookupJWKSKeyRefetchFailureThrottled(t *testing.T) {
+	keys := newTestJWTKeys(t)
+	var fetches atomic.Int32
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if fail.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(keys.jwks(t))
+	}))
+	defer server.Close()
+
+	withTestJWTConfig(t, "", server.URL)
+	if err := refreshJWKS(); err != nil {
+		t.Fatal(err)
+	}
+	fetches.Store(0)
+	fail.Store(true)
+	jwksMu.Lock()
+	jwksFetchedAt = time.Now().Add(-2 * jwksMinRefetchInterval)
+	jwksMu.Unlock()
+
+	// 获取失败后，最小间隔内的其他请求不再重试
+	for i := 0; i < 5; i++ {
+		if _, err := lookupJWKSKey(context.Background(), "unknown"); err == nil {
+			t.Fatal("未知kid应查找失败")
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("获取失败后应等待最小间隔再重试，实际获取 %d 次", n)
+	}
+	if _, err := lookupJWKSKey(context.Background(), "rsa-1"); err != nil {
+		t.Errorf("获取失败后应继续使用原有公钥: %v", err)
+	}
+}
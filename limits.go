@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"time"
+)
+
+// 转换进程资源超限的错误码
+const CodeResourceLimit = "conversion_resource_limit_exceeded"
+
+// soffice超过指定时间未结束时返回的错误
+var errSofficeTimeout = errors.New("soffice执行超时")
+
+// soffice进程的资源限制，0表示不限制
+var (
+	SOFFICE_MEMORY_MB   int // 虚拟内存上限
+	SOFFICE_CPU_SECONDS int // CPU时间上限
+	SOFFICE_FILE_MB     int // 单个文件大小上限
+
+	CONVERSION_TIMEOUT_SECONDS int // 单次转换的最长执行时间（墙钟时间），默认不限制
+)
+
+// 以资源限制辅助进程方式运行本程序时的第一个参数
+const limitsHelperArg = "__soffice_limits"
+
+// 传递资源限制配置给辅助进程的环境变量
+const limitsSpecEnv = "SOFFICE_LIMITS_SPEC"
+
+// processLimits 一个进程的资源限制
+type processLimits struct {
+	MemoryMB   int `json:"memory_mb"`
+	CPUSeconds int `json:"cpu_seconds"`
+	FileMB     int `json:"file_mb"`
+}
+
+// initProcessLimitConfig 读取soffice资源限制相关的环境变量
+func initProcessLimitConfig() {
+	SOFFICE_MEMORY_MB = getEnvInt("SOFFICE_MEMORY_MB", 0)
+	SOFFICE_CPU_SECONDS = getEnvInt("SOFFICE_CPU_SECONDS", 300)
+	SOFFICE_FILE_MB = getEnvInt("SOFFICE_FILE_MB", 512)
+	CONVERSION_TIMEOUT_SECONDS = getEnvInt("CONVERSION_TIMEOUT_SECONDS", 0)
+}
+
+// sofficeLimits 返回soffice进程的资源限制
+func sofficeLimits() processLimits {
+	return processLimits{
+		MemoryMB:   SOFFICE_MEMORY_MB,
+		CPUSeconds: SOFFICE_CPU_SECONDS,
+		FileMB:     SOFFICE_FILE_MB,
+	}
+}
+
+// limitsSpec 辅助进程设置资源限制后执行的程序
+type limitsSpec struct {
+	Program string        `json:"program"`
+	Args    []string      `json:"args"`
+	Limits  processLimits `json:"limits"`
+}
+
+// limitedCommand 构建在设置资源限制之后执行program的命令
+// 资源限制由本程序作为辅助进程在exec之前设置，soffice及其派生的进程从启动时起就受到限制；
+// 不支持设置资源限制的系统上直接执行program
+func limitedCommand(program string, args []string, limits processLimits) (*exec.Cmd, error) {
+	if !processLimitsSupported() {
+		return exec.Command(program, args...), nil
+	}
+	program, err := exec.LookPath(program)
+	if err != nil {
+		return nil, fmt.Errorf("找不到soffice: %w", err)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(limitsSpec{Program: program, Args: args, Limits: limits})
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(self, limitsHelperArg)
+	cmd.Env = append(os.Environ(), limitsSpecEnv+"="+string(data))
+	return cmd, nil
+}
+
+// runLimitsHelperFromEnv 作为资源限制辅助进程运行，设置限制后替换为目标进程
+func runLimitsHelperFromEnv() {
+	var spec limitsSpec
+	if err := json.Unmarshal([]byte(os.Getenv(limitsSpecEnv)), &spec); err != nil {
+		fmt.Fprintf(os.Stderr, "解析资源限制配置失败: %v\n", err)
+		os.Exit(126)
+	}
+	if err := runLimitsHelper(spec); err != nil {
+		fmt.Fprintf(os.Stderr, "启动soffice失败: %v\n", err)
+		os.Exit(126)
+	}
+}
+
+// runSofficeWithTimeout 执行soffice并返回合并的输出，超过timeout未结束时结束进程并返回errSofficeTimeout，0表示不限制
+func runSofficeWithTimeout(cmd *exec.Cmd, timeout time.Duration) ([]byte, error) {
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if timeout > 0 {
+		// 结束进程后其子进程可能仍然持有输出管道，最多再等待几秒
+		cmd.WaitDelay = 5 * time.Second
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	var timedOut atomic.Bool
+	if timeout > 0 {
+		timer := time.AfterFunc(timeout, func() {
+			timedOut.Store(true)
+			cmd.Process.Kill()
+		})
+		defer timer.Stop()
+	}
+	err := cmd.Wait()
+	if timedOut.Load() {
+		return output.Bytes(), errSofficeTimeout
+	}
+	return output.Bytes(), err
+}
@@ -0,0 +1,71 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// processLimitsSupported Linux上可以设置进程的资源限制
+func processLimitsSupported() bool {
+	return true
+}
+
+// runLimitsHelper 为当前进程设置资源限制后执行目标程序，成功时不会返回
+func runLimitsHelper(spec limitsSpec) error {
+	if err := setProcessLimits(0, spec.Limits); err != nil {
+		return err
+	}
+	var env []string
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, limitsSpecEnv+"=") {
+			env = append(env, e)
+		}
+	}
+	argv := append([]string{spec.Program}, spec.Args...)
+	return unix.Exec(spec.Program, argv, env)
+}
+
+// setProcessLimits 设置进程的资源限制，pid为0时设置当前进程
+func setProcessLimits(pid int, limits processLimits) error {
+	// CPU时间的硬限制稍大于软限制，使进程先收到SIGXCPU而不是直接被SIGKILL终止
+	values := []struct {
+		resource int
+		value    uint64
+		grace    uint64
+	}{
+		{unix.RLIMIT_AS, uint64(limits.MemoryMB) << 20, 0},
+		{unix.RLIMIT_CPU, uint64(limits.CPUSeconds), 5},
+		{unix.RLIMIT_FSIZE, uint64(limits.FileMB) << 20, 0},
+	}
+	for _, v := range values {
+		if v.value == 0 {
+			continue
+		}
+		if err := unix.Prlimit(pid, v.resource, &unix.Rlimit{Cur: v.value, Max: v.value + v.grace}, nil); err != nil {
+			return fmt.Errorf("设置资源限制失败: %w", err)
+		}
+	}
+	// 不生成core文件
+	return unix.Prlimit(pid, unix.RLIMIT_CORE, &unix.Rlimit{}, nil)
+}
+
+// exceededResourceLimit 判断进程是否因超出CPU时间或文件大小限制而终止
+func exceededResourceLimit(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return false
+	}
+	return status.Signal() == syscall.SIGXCPU || status.Signal() == syscall.SIGXFSZ
+}
@@ -0,0 +1,53 @@
+//go:build linux
+
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimitedCommandAppliesLimitsBeforeExec(t *testing.T) {
+	limits := processLimits{MemoryMB: 512, CPUSeconds: 7}
+	cmd, err := limitedCommand("sh", []string{"-c", "ulimit -v; ulimit -t; ulimit -c; echo ${" + limitsSpecEnv + ":-unset}"}, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := runSofficeWithTimeout(cmd, 10*time.Second)
+	if err != nil {
+		t.Fatalf("执行失败: %v: %s", err, output)
+	}
+	// 内存限制以KB显示，辅助进程的配置不会传给目标程序
+	want := []string{"524288", "7", "0", "unset"}
+	if got := strings.Fields(string(output)); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("目标程序中的资源限制为 %v，期望 %v", got, want)
+	}
+}
+
+func TestLimitedCommandUnlimited(t *testing.T) {
+	script := "ulimit -v; ulimit -t"
+	cmd, err := limitedCommand("sh", []string{"-c", script}, processLimits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := runSofficeWithTimeout(cmd, 10*time.Second)
+	if err != nil {
+		t.Fatalf("执行失败: %v: %s", err, output)
+	}
+	// 未配置限制时与直接执行时相同
+	direct, err := exec.Command("sh", "-c", script).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != string(direct) {
+		t.Errorf("未配置限制时输出 %q，直接执行时为 %q", output, direct)
+	}
+}
+
+func TestLimitedCommandMissingProgram(t *testing.T) {
+	if _, err := limitedCommand("/nonexistent/soffice", nil, processLimits{}); err == nil {
+		t.Error("程序不存在时应返回错误")
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// processLimitsSupported 只在Linux上设置进程的资源限制
+func processLimitsSupported() bool {
+	return false
+}
+
+func runLimitsHelper(spec limitsSpec) error {
+	return errors.New("当前系统不支持设置资源限制")
+}
+
+// setProcessLimits 只在Linux上设置进程的资源限制
+func setProcessLimits(pid int, limits processLimits) error {
+	return nil
+}
+
+func exceededResourceLimit(err error) bool {
+	return false
+}
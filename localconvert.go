@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// initLocalConvertConfig 命令行本地转换读取与服务相同的转换相关配置，不初始化存储、认证等服务端配置
+func initLocalConvertConfig() {
+	initBaseConfig()
+	initWorkDirConfig()
+	initContentSniffConfig()
+	initProfileConfig()
+	initArchiveCheckConfig()
+	initProcessLimitConfig()
+	initPoolConfig()
+	libreofficeAvailable, libreofficeVersion = checkLibreOffice()
+	if libreofficeAvailable {
+		initSandboxConfig()
+	}
+	initLanguageConfig()
+}
+
+// prepareLocal 初始化本地转换，命令行参数优先于环境变量中的配置
+func (cmd *convertCommand) prepareLocal() error {
+	if cmd.async || cmd.ttl > 0 {
+		return errors.New("本地转换不支持--async和--ttl，这两个参数需要与--server一起使用")
+	}
+
+	initLocalConvertConfig()
+	if cmd.lang != "" {
+		lang, ok := matchLanguage(cmd.lang)
+		if !ok {
+			return fmt.Errorf("无效的--lang: %q，可选值为%s、%s", cmd.lang, LangZhCN, LangEn)
+		}
+		DEFAULT_LANGUAGE = lang
+	}
+	if cmd.timeout > 0 {
+		CONVERSION_TIMEOUT_SECONDS = int(math.Ceil(cmd.timeout.Seconds()))
+	}
+	// 超出转换并发数的文件只会在队列中等待，可能因QUEUE_TIMEOUT_SECONDS超时
+	if cmd.parallel > MAX_CONCURRENT_CONVERSIONS {
+		cmd.parallel = MAX_CONCURRENT_CONVERSIONS
+	}
+	if _, _, convErr := parseConvertFormat(cmd.format); convErr != nil {
+		return convErr
+	}
+	// 本地转换只输出JSON，便于脚本处理
+	cmd.jsonOutput = true
+	return cmd.useLocalTmpDir()
+}
+
+// useLocalTmpDir 在TMP_DIR下创建本次命令独用的临时目录，之后的工作目录和用户配置目录都创建在其中
+// 同一主机上的服务与命令行共用TMP_DIR和INSTANCE_ID，服务清理遗留目录和soffice进程时只检查TMP_DIR的第一层，不会影响命令行的转换
+func (cmd *convertCommand) useLocalTmpDir() error {
+	dir, err := os.MkdirTemp(TMP_DIR, "cli_")
+	if err != nil {
+		return fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	TMP_DIR, cmd.localTmpDir = dir, dir
+	return nil
+}
+
+// cleanupLocal 删除本地转换使用的临时目录
+func (cmd *convertCommand) cleanupLocal() {
+	if cmd.localTmpDir == "" {
+		return
+	}
+	if err := os.RemoveAll(cmd.localTmpDir); err != nil {
+		slog.Warn("删除临时目录失败", "dir", cmd.localTmpDir, "error", err)
+	}
+}
+
+// localConvert 在本机转换一个文件，与服务端使用相同的校验、转换参数和soffice执行流程
+func (cmd *convertCommand) localConvert(ctx context.Context, task convertTask) convertOutcome {
+	start := time.Now()
+	outcome := convertOutcome{Input: task.input, Output: task.output, Success: true}
+	detectedExt, formatCorrected, err := convertLocalFile(ctx, task, cmd.format)
+	outcome.DetectedFormat = strings.TrimPrefix(detectedExt, ".")
+	outcome.FormatCorrected = formatCorrected
+	outcome.fail(err)
+	outcome.DurationMS = time.Since(start).Milliseconds()
+	return outcome
+}
+
+// convertLocalFile 校验输入文件，在临时工作目录中转换，并将结果复制到输出路径
+// 返回根据内容识别的扩展名，以及扩展名与内容不符时是否已按内容修正
+func convertLocalFile(ctx context.Context, task convertTask, format string) (string, bool, error) {
+	logger := slog.Default().With("input", task.input)
+	if !libreofficeAvailable {
+		return "", false, newConversionError(http.StatusInternalServerError, CodeLibreOfficeUnavailable, "error.libreoffice_unavailable").
+			withDetails(libreofficeVersion)
+	}
+
+	f, err := os.Open(task.input)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", false, err
+	}
+	if info.Size() > MAX_CONTENT_LENGTH {
+		return "", false, newConversionError(http.StatusRequestEntityTooLarge, CodeFileTooLarge, "error.file_too_large").
+			withDetailMessage("detail.file_too_large", MAX_CONTENT_LENGTH)
+	}
+
+	fileExt := strings.ToLower(filepath.Ext(task.input))
+	detectedExt, inputExt, convErr := checkInputFile(ctx, logger, f, info.Size(), task.input, fileExt)
+	if convErr != nil {
+		return "", false, convErr
+	}
+	formatCorrected := inputExt != fileExt
+	if formatCorrected {
+		logger.Info("文件扩展名与内容不符，已修正", "claimed_format", fileExt, "detected_format", inputExt)
+	}
+	convertFormat, targetExt, convErr := parseConvertFormat(format)
+	if convErr != nil {
+		return detectedExt, formatCorrected, convErr
+	}
+
+	// 与服务端相同，使用唯一ID作为工作目录中的文件名
+	uniqueID := uuid.New().String()
+	workDir, err := beginWork(uniqueID)
+	if err != nil {
+		return detectedExt, formatCorrected, fmt.Errorf("创建工作目录失败: %w", err)
+	}
+	defer endWork(ctx, uniqueID)
+
+	filePath := filepath.Join(workDir, uniqueID+inputExt)
+	if err := copyFile(ctx, task.input, filePath); err != nil {
+		return detectedExt, formatCorrected, err
+	}
+	outputPath, convErr := runConversion(ctx, logger, workDir, filePath, convertFormat, targetExt, uniqueID, "")
+	if convErr != nil {
+		return detectedExt, formatCorrected, convErr
+	}
+
+	if err := os.MkdirAll(filepath.Dir(task.output), 0755); err != nil {
+		return detectedExt, formatCorrected, fmt.Errorf("创建输出目录失败: %w", err)
+	}
+	if err := copyFile(ctx, outputPath, task.output); err != nil {
+		return detectedExt, formatCorrected, err
+	}
+	return detectedExt, formatCorrected, nil
+}
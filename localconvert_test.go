@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSoffice 模拟soffice，将输入文件的内容复制为目标格式的输出文件
+const fakeSoffice = `#!/bin/sh
+[ "$1" = "--version" ] && { echo "LibreOffice 0.0 fake"; exit 0; }
+while [ $# -gt 0 ]; do
+	case "$1" in
+	--convert-to) fmt=$2; shift ;;
+	--outdir) out=$2; shift ;;
+	-*) ;;
+	*) in=$1 ;;
+	esac
+	shift
+done
+base=$(basename "$in")
+ext=${fmt%%:*}
+cat "$in" > "$out/${base%.*}.$ext"
+echo "convert $in -> $out/${base%.*}.$ext"
+`
+
+// withFakeSoffice 使用模拟的soffice和临时目录进行本地转换，测试结束后恢复配置
+func withFakeSoffice(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("模拟的soffice是shell脚本")
+	}
+	withTestWorkDirs(t, "test")
+	path := filepath.Join(t.TempDir(), "soffice")
+	if err := os.WriteFile(path, []byte(fakeSoffice), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	savedPath, savedAvailable, savedSandbox := SOFFICE_PATH, libreofficeAvailable, sandboxActive
+	savedPool, savedMaxLength, savedTimeout, savedMismatch := conversionPool, MAX_CONTENT_LENGTH, CONVERSION_TIMEOUT_SECONDS, CONTENT_TYPE_MISMATCH
+	t.Cleanup(func() {
+		SOFFICE_PATH, libreofficeAvailable, sandboxActive = savedPath, savedAvailable, savedSandbox
+		conversionPool, MAX_CONTENT_LENGTH, CONVERSION_TIMEOUT_SECONDS, CONTENT_TYPE_MISMATCH = savedPool, savedMaxLength, savedTimeout, savedMismatch
+	})
+	SOFFICE_PATH, libreofficeAvailable, sandboxActive = path, true, false
+	conversionPool = &workerPool{global: make(chan struct{}, 2), tenants: map[string]chan struct{}{}}
+	MAX_CONTENT_LENGTH, CONVERSION_TIMEOUT_SECONDS, CONTENT_TYPE_MISMATCH = 1024, 30, MismatchReject
+}
+
+func TestPrepareLocalRejectsServerOptions(t *testing.T) {
+	for _, cmd := range []*convertCommand{{async: true}, {ttl: time.Minute}} {
+		if err := cmd.prepareLocal(); err == nil || !strings.Contains(err.Error(), "--server") {
+			t.Errorf("本地转换应拒绝--async和--ttl，错误为%v", err)
+		}
+	}
+}
+
+func TestLocalConvert(t *testing.T) {
+	withFakeSoffice(t)
+	baseTmp := TMP_DIR
+	dir := t.TempDir()
+	writeTestFiles(t, dir, "a.txt", "b.docx")
+	if err := os.WriteFile(filepath.Join(dir, "large.txt"), make([]byte, 2048), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &convertCommand{format: "pdf"}
+	if err := cmd.useLocalTmpDir(); err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(TMP_DIR) != baseTmp || !strings.HasPrefix(filepath.Base(TMP_DIR), "cli_") {
+		t.Fatalf("本地转换的临时目录 %s 应在 %s 下", TMP_DIR, baseTmp)
+	}
+
+	tests := []struct {
+		input    string
+		wantCode string
+	}{
+		{"a.txt", ""},
+		{"b.docx", CodeContentMismatch}, // 内容为文本
+		{"large.txt", CodeFileTooLarge},
+		{"missing.txt", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			task := convertTask{input: filepath.Join(dir, tt.input), output: filepath.Join(dir, "out", strings.TrimSuffix(tt.input, filepath.Ext(tt.input))+".pdf")}
+			outcome := cmd.localConvert(context.Background(), task)
+			wantSuccess := tt.wantCode == "" && tt.input != "missing.txt"
+			if outcome.Success != wantSuccess || outcome.Code != tt.wantCode {
+				t.Fatalf("结果 = %+v", outcome)
+			}
+			if !wantSuccess {
+				if _, err := os.Stat(task.output); !os.IsNotExist(err) {
+					t.Errorf("失败时不应生成输出文件: %v", err)
+				}
+				return
+			}
+			if outcome.DetectedFormat != "txt" || outcome.Output != task.output {
+				t.Errorf("结果 = %+v", outcome)
+			}
+			if data, err := os.ReadFile(task.output); err != nil || string(data) != "hello" {
+				t.Errorf("输出文件: %q, %v", data, err)
+			}
+		})
+	}
+
+	// 工作目录在每个文件转换后删除，服务清理遗留目录时不会进入命令行的临时目录
+	entries, err := os.ReadDir(TMP_DIR)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("转换结束后临时目录中还有%d项: %v", len(entries), err)
+	}
+	if err := os.MkdirAll(workDirFor("running"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	localTmp := TMP_DIR
+	TMP_DIR = baseTmp
+	sweepOrphanedWork(true)
+	TMP_DIR = localTmp
+	if _, err := os.Stat(workDirFor("running")); err != nil {
+		t.Errorf("服务清理遗留目录时删除了命令行的工作目录: %v", err)
+	}
+
+	cmd.cleanupLocal()
+	if entries, _ := os.ReadDir(baseTmp); len(entries) != 0 {
+		t.Errorf("命令结束后TMP_DIR中还有%d项", len(entries))
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 日志配置
+var (
+	LOG_FORMAT string // text或json
+	LOG_LEVEL  string // debug、info、warn或error
+)
+
+// 请求ID的请求头和响应头
+const requestIDHeader = "X-Request-ID"
+
+// 请求上下文中保存请求ID的键
+const requestIDContextKey = "request_id"
+
+// 客户端提供的请求ID只接受这些字符，避免日志注入
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type requestIDKey struct{}
+
+// initLogConfig 读取日志相关的环境变量并设置默认日志记录器
+// 设置后log包输出的日志也以相同的格式和info级别记录
+func initLogConfig() {
+	LOG_FORMAT = strings.ToLower(getEnvString("LOG_FORMAT", "text"))
+	LOG_LEVEL = strings.ToLower(getEnvString("LOG_LEVEL", "info"))
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(LOG_LEVEL)); err != nil {
+		log.Fatalf("无效的LOG_LEVEL: %q，可选值为debug、info、warn、error", LOG_LEVEL)
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch LOG_FORMAT {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		log.Fatalf("无效的LOG_FORMAT: %q，可选值为text、json", LOG_FORMAT)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+// withRequestID 返回携带请求ID的上下文
+func withRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// requestIDFrom 返回上下文中的请求ID，没有时返回空字符串
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// loggerFrom 返回带有请求ID和trace_id的日志记录器，用于不依赖gin的函数
+func loggerFrom(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if id := requestIDFrom(ctx); id != "" {
+		logger = logger.With("request_id", id)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		logger = logger.With("trace_id", sc.TraceID().String())
+	}
+	return logger
+}
+
+// requestLogger 返回当前请求的日志记录器
+func requestLogger(c *gin.Context) *slog.Logger {
+	return loggerFrom(c.Request.Context())
+}
+
+// requestID 返回当前请求的请求ID
+func requestID(c *gin.Context) string {
+	return c.GetString(requestIDContextKey)
+}
+
+// requestIDMiddleware 使用客户端提供的X-Request-ID或生成新的请求ID，并在响应头中返回
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = uuid.New().String()
+		}
+		c.Set(requestIDContextKey, id)
+		c.Request = c.Request.WithContext(withRequestID(c.Request.Context(), id))
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// accessLogMiddleware 在请求结束后记录访问日志，替代gin默认的文本格式日志
+func accessLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		requestLogger(c).LogAttrs(c.Request.Context(), level, "请求完成",
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Int64("duration_ms", time.Since(start).Milliseconds()),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("response_bytes", c.Writer.Size()),
+		)
+	}
+}
+
+// recoveryMiddleware 处理函数panic时记录堆栈并返回500
+func recoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				if r == http.ErrAbortHandler {
+					panic(r)
+				}
+				requestLogger(c).Error("请求处理发生panic", "error", fmt.Sprint(r), "stack", string(debug.Stack()))
+				if !c.Writer.Written() {
+					abortWithError(c, http.StatusInternalServerError, ErrorResponse{Error: tr(c, "error.internal"), Code: CodeInternalError})
+				} else {
+					c.Abort()
+				}
+			}
+		}()
+		c.Next()
+	}
+}
+
+// respondError 返回错误响应，并附带错误码和当前请求的请求ID
+func respondError(c *gin.Context, status int, resp ErrorResponse) {
+	c.JSON(status, errorBody(c, status, resp))
+}
+
+// abortWithError 返回错误响应并停止执行后续处理函数
+func abortWithError(c *gin.Context, status int, resp ErrorResponse) {
+	c.AbortWithStatusJSON(status, errorBody(c, status, resp))
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -9,6 +10,7 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -20,6 +22,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // 全局配置变量
@@ -28,6 +31,7 @@ var (
 	MAX_CONTENT_LENGTH int64
 	SOFFICE_PATH      string
 	FILE_EXPIRY_HOURS int
+	CLEANUP_INTERVAL_MINUTES int
 	BASE_DIR          string
 	TMP_DIR           string
 	DATA_DIR          string
@@ -37,6 +41,9 @@ var (
 	libreofficeVersion   string
 )
 
+// 上传请求中除文件内容外的表单字段和分隔符允许占用的字节数
+const multipartOverhead = 1024 * 1024
+
 // APIConfig 存储API的配置信息
 type APIConfig struct {
 	Debug           bool   `json:"debug"`
@@ -50,6 +57,80 @@ type APIConfig struct {
 
 // InitConfig 初始化配置
 func InitConfig() {
+	initBaseConfig()
+	if err := os.MkdirAll(DATA_DIR, 0755); err != nil {
+		log.Printf("创建数据目录失败: %v", err)
+	}
+
+	// 初始化存储后端
+	initStorageConfig()
+
+	// 初始化下载链接签名
+	initSigningConfig()
+
+	// 初始化磁盘配额
+	initDiskQuotaConfig()
+
+	// 初始化临时目录清理
+	initWorkDirConfig()
+
+	// 初始化上传内容检测
+	initContentSniffConfig()
+
+	// 初始化LibreOffice用户配置
+	initProfileConfig()
+
+	// 初始化压缩包检查和soffice资源限制
+	initArchiveCheckConfig()
+	initProcessLimitConfig()
+
+	// 初始化租户配置，需在加载API密钥之前
+	initTenantConfig()
+
+	// 初始化转换并发限制
+	initPoolConfig()
+
+	// 初始化API密钥认证
+	initAuthConfig()
+
+	// 初始化JWT认证
+	initJWTConfig()
+	if len(tenants) > 0 && !authEnabled() {
+		log.Println("警告: 已配置租户但未启用认证，所有请求都将使用默认租户")
+	}
+
+	// 初始化限流和每日配额
+	initRateLimitConfig()
+
+	// 初始化审计日志
+	initAuditConfig()
+
+	// 初始化Prometheus指标
+	initMetricsConfig()
+
+	// 检查LibreOffice是否可用
+	libreofficeAvailable, libreofficeVersion = checkLibreOffice()
+
+	// 初始化soffice沙箱，需要在检查LibreOffice之后
+	if libreofficeAvailable {
+		initSandboxConfig()
+	}
+
+	// 初始化链路追踪
+	initTracingConfig()
+
+	// 初始化就绪检查
+	initReadinessConfig()
+
+	// 初始化错误信息和首页的语言
+	initLanguageConfig()
+	
+	log.Printf("配置初始化完成: DEBUG=%v, MAX_CONTENT_LENGTH=%d, SOFFICE_PATH=%s, FILE_EXPIRY_HOURS=%d, PORT=%s",
+		DEBUG, MAX_CONTENT_LENGTH, SOFFICE_PATH, FILE_EXPIRY_HOURS, PORT)
+}
+
+// initBaseConfig 加载.env文件，读取基本配置并创建临时目录，命令行转换也使用这些配置
+func initBaseConfig() {
 	// 加载.env文件
 	envErr := godotenv.Load()
 	if envErr != nil {
@@ -58,6 +139,9 @@ func InitConfig() {
 		log.Println("成功加载.env文件")
 	}
 
+	// 初始化结构化日志，之后的日志都按LOG_FORMAT输出
+	initLogConfig()
+
 	// 设置默认值并从环境变量获取配置
 	debugEnv := os.Getenv("DEBUG")
 	log.Printf("DEBUG环境变量值: %q", debugEnv)
@@ -103,6 +187,12 @@ func InitConfig() {
 		}
 	}
 	
+	// 清理任务检查间隔
+	CLEANUP_INTERVAL_MINUTES = getEnvInt("CLEANUP_INTERVAL_MINUTES", 60)
+	if CLEANUP_INTERVAL_MINUTES <= 0 {
+		CLEANUP_INTERVAL_MINUTES = 60
+	}
+	
 	// 服务端口
 	PORT = os.Getenv("PORT")
 	log.Printf("PORT环境变量值: %q", PORT)
@@ -123,19 +213,65 @@ func InitConfig() {
 	TMP_DIR = filepath.Join(BASE_DIR, "tmp")
 	DATA_DIR = filepath.Join(BASE_DIR, "data")
 
-	// 创建必要的目录
+	// 创建临时目录
 	if err := os.MkdirAll(TMP_DIR, 0755); err != nil {
 		log.Printf("创建临时目录失败: %v", err)
 	}
-	if err := os.MkdirAll(DATA_DIR, 0755); err != nil {
-		log.Printf("创建数据目录失败: %v", err)
+}
+
+// 读取字符串类型的环境变量，为空时使用默认值
+func getEnvString(key, defaultValue string) string {
+	value := os.Getenv(key)
+	log.Printf("%s环境变量值: %q", key, value)
+	if value == "" {
+		return defaultValue
 	}
+	return value
+}
 
-	// 检查LibreOffice是否可用
-	libreofficeAvailable, libreofficeVersion = checkLibreOffice()
-	
-	log.Printf("配置初始化完成: DEBUG=%v, MAX_CONTENT_LENGTH=%d, SOFFICE_PATH=%s, FILE_EXPIRY_HOURS=%d, PORT=%s",
-		DEBUG, MAX_CONTENT_LENGTH, SOFFICE_PATH, FILE_EXPIRY_HOURS, PORT)
+// 读取布尔类型的环境变量，为空或解析失败时使用默认值
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	log.Printf("%s环境变量值: %q", key, value)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("解析%s出错: %v, 使用默认值%v", key, err, defaultValue)
+		return defaultValue
+	}
+	return b
+}
+
+// 读取整数类型的环境变量，为空或解析失败时使用默认值
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	log.Printf("%s环境变量值: %q", key, value)
+	if value == "" {
+		return defaultValue
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("解析%s出错: %v, 使用默认值%d", key, err, defaultValue)
+		return defaultValue
+	}
+	return i
+}
+
+// 读取int64类型的环境变量，为空或解析失败时使用默认值
+func getEnvInt64(key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	log.Printf("%s环境变量值: %q", key, value)
+	if value == "" {
+		return defaultValue
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("解析%s出错: %v, 使用默认值%d", key, err, defaultValue)
+		return defaultValue
+	}
+	return i
 }
 
 // 检查LibreOffice是否可用
@@ -153,11 +289,7 @@ func checkLibreOffice() (bool, string) {
 
 // 定时清理过期文件
 func startCleanupScheduler(ctx context.Context, wg *sync.WaitGroup) {
-	if FILE_EXPIRY_HOURS <= 0 {
-		log.Println("文件永不过期，不启动清理任务")
-		return
-	}
-
+	// 即使全局永不过期，单个文件也可能通过ttl_minutes设置了过期时间，因此始终启动清理任务
 	wg.Add(1)
 	go func() {
 		defer func() {
@@ -165,16 +297,25 @@ func startCleanupScheduler(ctx context.Context, wg *sync.WaitGroup) {
 			wg.Done()
 		}()
 		
-		ticker := time.NewTicker(1 * time.Hour)
+		ticker := time.NewTicker(time.Duration(CLEANUP_INTERVAL_MINUTES) * time.Minute)
 		defer ticker.Stop()
 
-		log.Printf("已启动文件清理任务，过期时间: %d小时", FILE_EXPIRY_HOURS)
+		log.Printf("已启动文件清理任务，全局过期时间: %d小时, 检查间隔: %d分钟", FILE_EXPIRY_HOURS, CLEANUP_INTERVAL_MINUTES)
+
+		// 启动时先统计一次存储占用
+		scanStorageUsage()
+		enforceStorageQuota()
 
 		for {
 			select {
 			case <-ticker.C:
 				log.Println("开始执行文件清理任务")
 				cleanupExpiredFiles()
+				scanStorageUsage()
+				enforceStorageQuota()
+				sweepOrphanedWork(false)
+			case <-quotaCheckCh:
+				enforceStorageQuota()
 			case <-ctx.Done():
 				log.Println("文件清理任务收到取消信号，正在退出")
 				return
@@ -185,44 +326,58 @@ func startCleanupScheduler(ctx context.Context, wg *sync.WaitGroup) {
 
 // 清理过期文件
 func cleanupExpiredFiles() {
-	if FILE_EXPIRY_HOURS <= 0 {
-		log.Println("文件永不过期，跳过清理")
-		return
-	}
-
+	ctx := context.Background()
 	now := time.Now()
-	expiryDuration := time.Duration(FILE_EXPIRY_HOURS) * time.Hour
+	var deleted, failed int
+	defer func() {
+		recordCleanupMetrics(now, deleted, failed)
+	}()
 
-	// 递归遍历目录
-	err := filepath.Walk(DATA_DIR, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	// 遍历存储中的所有文件
+	var expired []string
+	err := fileStorage.Walk(ctx, "", func(file StoredFile) error {
+		// 删除结果文件已不存在的元数据
+		if isMetadataPath(file.Path) {
+			dataPath := strings.TrimSuffix(file.Path, metadataSuffix)
+			if _, err := fileStorage.Stat(ctx, dataPath); errors.Is(err, ErrFileNotFound) {
+				expired = append(expired, file.Path)
+			}
+			return nil
 		}
 
-		// 跳过目录
-		if info.IsDir() {
+		// 按元数据中记录的过期时间检查文件是否过期
+		expiresAt, err := storedFileExpiry(ctx, file)
+		if err != nil {
+			log.Printf("读取文件过期时间出错: %s, %v", file.Path, err)
 			return nil
 		}
-
-		// 检查文件是否过期
-		fileTime := info.ModTime()
-		if now.Sub(fileTime) > expiryDuration {
-			if err := os.Remove(path); err != nil {
-				log.Printf("删除过期文件时出错: %v", err)
-			} else {
-				log.Printf("已删除过期文件: %s", path)
-			}
+		if isExpired(expiresAt, now) {
+			expired = append(expired, file.Path, metadataPath(file.Path))
 		}
-
 		return nil
 	})
 
 	if err != nil {
 		log.Printf("清理过期文件时出错: %v", err)
+		failed++
+	}
+
+	for _, p := range expired {
+		if err := fileStorage.Delete(ctx, p); err != nil {
+			if !errors.Is(err, ErrFileNotFound) {
+				log.Printf("删除过期文件时出错: %v", err)
+				failed++
+			}
+		} else {
+			log.Printf("已删除过期文件: %s", p)
+			deleted++
+		}
 	}
 
-	// 删除空目录
-	removeEmptyDirs(DATA_DIR)
+	// 本地存储需要删除空目录
+	if local, ok := fileStorage.(*localStorage); ok {
+		removeEmptyDirs(local.root)
+	}
 }
 
 // 删除空目录
@@ -262,14 +417,10 @@ func removeEmptyDirs(root string) {
 	}
 }
 
-// 生成输出文件路径
-func generateOutputFilepath(originalFilename, targetExt string) (string, string) {
+// 生成输出文件在存储中的相对路径，prefix为租户的存储路径前缀
+func generateOutputFilepath(prefix, originalFilename, targetExt string) string {
 	// 按日期生成目录
 	dateStr := time.Now().Format("20060102")
-	dateDir := filepath.Join(DATA_DIR, dateStr)
-
-	// 确保日期目录存在
-	os.MkdirAll(dateDir, 0755)
 
 	// 提取原始文件名（不含扩展名）
 	baseName := strings.TrimSuffix(filepath.Base(originalFilename), filepath.Ext(originalFilename))
@@ -278,12 +429,9 @@ func generateOutputFilepath(originalFilename, targetExt string) (string, string)
 
 	// 构建输出文件名
 	outputFilename := fmt.Sprintf("%s_%d.%s", baseName, timestampSuffix, targetExt)
-	outputFilepath := filepath.Join(dateDir, outputFilename)
-
-	// 相对路径（用于构建URL）
-	relativePath := filepath.Join(dateStr, outputFilename)
 
-	return outputFilepath, relativePath
+	// 相对路径（用于存储和构建URL）
+	return path.Join(prefix, dateStr, outputFilename)
 }
 
 // ConversionResponse 转换结果响应
@@ -292,6 +440,9 @@ type ConversionResponse struct {
 	Filename        string `json:"filename"`
 	DownloadURL     string `json:"download_url"`
 	DownloadFilename string `json:"download_filename"`
+	DownloadURLExpiry string `json:"download_url_expiry"`
+	DetectedFormat  string `json:"detected_format"`
+	FormatCorrected bool   `json:"format_corrected,omitempty"`
 	Text            string `json:"text,omitempty"`
 	Expiry          string `json:"expiry"`
 }
@@ -299,7 +450,9 @@ type ConversionResponse struct {
 // ErrorResponse 错误响应
 type ErrorResponse struct {
 	Error   string `json:"error"`
+	Code    string `json:"code,omitempty"`
 	Details string `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
 }
 
 // HealthResponse 健康检查响应
@@ -310,10 +463,11 @@ type HealthResponse struct {
 	DataDir        string `json:"data_dir"`
 	FileExpiryHours int    `json:"file_expiry_hours"`
 	Port           string `json:"port"`
+	Disk           DiskUsageInfo `json:"disk"`
 }
 
-// 辅助函数：复制文件
-func copyFile(src, dst string) error {
+// 辅助函数：复制文件，ctx用于在日志中记录请求ID
+func copyFile(ctx context.Context, src, dst string) error {
 	// 打开源文件
 	source, err := os.Open(src)
 	if err != nil {
@@ -341,7 +495,7 @@ func copyFile(src, dst string) error {
 		return fmt.Errorf("同步文件失败: %w", err)
 	}
 	
-	log.Printf("已将文件从 %s 复制到 %s", src, dst)
+	loggerFrom(ctx).Debug("已复制文件", "src", src, "dst", dst)
 	return nil
 }
 
@@ -414,16 +568,26 @@ func detectMimeType(filePath string) string {
 	}
 }
 
+// 支持的输入格式列表
+var supportedInputFormats = []string{
+	".doc", ".docx", ".wps", ".txt", ".rtf",
+	".html", ".htm",
+	".xml", ".pdf",
+}
+
+// 支持的输出格式列表
+var supportedOutputFormats = []string{
+	"txt", "doc", "docx", "rtf", "odt",
+	"xls", "xlsx", "ods", "csv",
+	"ppt", "pptx", "odp",
+	"html", "htm",
+	"jpg", "jpeg", "png", "gif",
+	"xml", "json", "pdf",
+}
+
 // 验证输入文件格式是否支持
 func isValidInputFormat(fileExt string) bool {
-	// 支持的输入格式列表
-	supportedFormats := []string{
-		".doc", ".docx", ".wps", ".txt",
-		".html", ".htm",
-		".xml", ".pdf",
-	}
-	
-	for _, ext := range supportedFormats {
+	for _, ext := range supportedInputFormats {
 		if ext == fileExt {
 			return true
 		}
@@ -434,17 +598,7 @@ func isValidInputFormat(fileExt string) bool {
 
 // 验证输出文件格式是否支持
 func isValidOutputFormat(format string) bool {
-	// 支持的输出格式列表
-	supportedFormats := []string{
-		"txt", "doc", "docx", "rtf", "odt",
-		"xls", "xlsx", "ods", "csv",
-		"ppt", "pptx", "odp",
-		"html", "htm",
-		"jpg", "jpeg", "png", "gif",
-		"xml", "json", "pdf",
-	}
-	
-	for _, fmt := range supportedFormats {
+	for _, fmt := range supportedOutputFormats {
 		if fmt == format {
 			return true
 		}
@@ -453,7 +607,64 @@ func isValidOutputFormat(format string) bool {
 	return false
 }
 
+// newRouter 创建HTTP路由，注册中间件和所有接口
+func newRouter() *gin.Engine {
+	// 使用结构化的访问日志替代gin默认的日志中间件
+	router := gin.New()
+	// 未认证客户端的身份为其IP，只采信可信代理设置的X-Forwarded-For，未配置时使用连接的对端地址
+	if err := router.SetTrustedProxies(TRUSTED_PROXIES); err != nil {
+		log.Fatalf("无效的TRUSTED_PROXIES: %v", err)
+	}
+	router.Use(requestIDMiddleware(), tracingMiddleware(), accessLogMiddleware(), recoveryMiddleware(), languageMiddleware())
+	
+	// 设置最大multipart表单内存大小
+	router.MaxMultipartMemory = MAX_CONTENT_LENGTH
+	
+	// 启用JWT认证时校验请求中的访问令牌
+	if jwtEnabled() {
+		router.Use(jwtMiddleware())
+	}
+	
+	// 设置API路由
+	router.GET("/", indexHandler)
+	router.GET("/health", healthCheckHandler)
+	router.GET("/livez", livezHandler)
+	router.GET("/readyz", readyzHandler)
+	if METRICS_ENABLED {
+		router.GET("/metrics", requireScope(ScopeAdmin), metricsHandler)
+	}
+	router.GET("/openapi.json", openAPIHandler)
+	router.GET("/docs", docsHandler)
+	// 旧接口保持原有的路径和错误格式，新客户端应使用/v1下的接口
+	registerAPIRoutes(router)
+	registerAPIRoutes(router.Group(apiV1Prefix))
+	router.NoRoute(func(c *gin.Context) {
+		if isV1Request(c) {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: tr(c, "error.route_not_found"), Code: CodeNotFound})
+			return
+		}
+		c.String(http.StatusNotFound, "404 page not found")
+	})
+	
+	return router
+}
+
 func main() {
+	// 作为soffice沙箱的辅助进程运行
+	if len(os.Args) > 1 && os.Args[1] == sandboxHelperArg {
+		runSandboxHelperFromEnv()
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == limitsHelperArg {
+		runLimitsHelperFromEnv()
+		return
+	}
+
+	// 命令行转换，不启动HTTP服务
+	if len(os.Args) > 1 && os.Args[1] == convertCommandName {
+		os.Exit(runConvertCommand(os.Args[2:]))
+	}
+
 	// 初始化配置
 	InitConfig()
 	
@@ -463,35 +674,52 @@ func main() {
 	
 	var wg sync.WaitGroup
 	
+	// 清理上次运行遗留的工作目录和soffice进程
+	sweepOrphanedWork(true)
+	
 	// 启动定时清理任务
 	startCleanupScheduler(ctx, &wg)
 	
+	// 启动API密钥配置监控
+	startAPIKeyWatcher(ctx, &wg)
+	
+	// 启动JWKS定期刷新
+	startJWKSRefresher(ctx, &wg)
+	
+	// 启动金丝雀转换，就绪检查使用最近一次的结果
+	startCanaryScheduler(ctx, &wg)
+	
+	// 收到SIGHUP信号时重新加载API密钥和限流配置
+	reloadSignal := make(chan os.Signal, 1)
+	signal.Notify(reloadSignal, syscall.SIGHUP)
+	go func() {
+		for range reloadSignal {
+			if err := reloadRateLimits(); err != nil {
+				log.Printf("重新加载限流配置失败: %v", err)
+			}
+			if !apiKeysEnabled() {
+				continue
+			}
+			if n, err := reloadAPIKeys(); err != nil {
+				log.Printf("重新加载API密钥失败: %v", err)
+			} else {
+				log.Printf("收到SIGHUP信号，已重新加载API密钥 %d 个", n)
+			}
+		}
+	}()
+	
 	// 设置Gin模式
 	if !DEBUG {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	
-	router := gin.Default()
+	router := newRouter()
 	
-	// 设置最大multipart表单内存大小
-	router.MaxMultipartMemory = MAX_CONTENT_LENGTH
-	
-	// 设置API路由
-	router.GET("/", indexHandler)
-	router.GET("/health", healthCheckHandler)
-	router.POST("/convert", convertDocumentHandler)
-	router.GET("/download/*filename", func(c *gin.Context) {
-		// 去除前导的"/"字符
-		filename := c.Param("filename")
-		if strings.HasPrefix(filename, "/") {
-			filename = filename[1:]
-		}
-		c.Params = append(c.Params, gin.Param{
-			Key:   "filename",
-			Value: filename,
-		})
-		downloadFileHandler(c)
-	})
+	// 生成OpenAPI文档，并检查文档与实际注册的路由是否一致
+	initOpenAPI()
+	for _, problem := range checkOpenAPIRoutes(router.Routes()) {
+		log.Printf("警告: %s", problem)
+	}
 	
 	// 启动服务器
 	log.Printf("启动服务: host=0.0.0.0, port=%s, debug=%v", PORT, DEBUG)
@@ -539,6 +767,9 @@ func main() {
 		log.Printf("服务器关闭异常: %v", err)
 	}
 	
+	// 导出尚未发送的链路追踪数据
+	shutdownTracing(shutdownCtx)
+	
 	// 等待所有goroutine完成，但设置最大等待时间
 	log.Println("等待清理任务完成...")
 	
@@ -563,9 +794,9 @@ func main() {
 // 首页处理
 func indexHandler(c *gin.Context) {
 	html := `
-    <html>
+    <html lang="${LANG}">
         <head>
-            <title>LibreOffice文档转换API</title>
+            <title>${t:index.title}</title>
             <meta charset="utf-8">
             <meta name="viewport" content="width=device-width, initial-scale=1">
             <style>
@@ -661,139 +892,187 @@ func indexHandler(c *gin.Context) {
             </style>
         </head>
         <body>
-            <h1>LibreOffice文档转换API</h1>
-            <p>这是一个基于Go实现的文档转换API服务，可以将各种格式的文档转换为其他格式。</p>
-            环境变量
+            <p style="float: right;"><a href="?lang=zh-CN">中文</a> | <a href="?lang=en">English</a></p>
+            <h1>${t:index.title}</h1>
+            <p>${t:index.intro}</p>
+            ${t:index.env_vars}
 			<pre>
-				DEBUG: 是否开启调试模式 <span id="debugValue">${DEBUG}</span>
-				MAX_CONTENT_LENGTH: 最大上传文件大小 <span id="maxContentLengthValue">${MAX_CONTENT_LENGTH}</span>
-				SOFFICE_PATH: LibreOffice安装路径 <span id="sofficePathValue">${SOFFICE_PATH}</span>
-				FILE_EXPIRY_HOURS: 文件过期时间 <span id="fileExpiryHoursValue">${FILE_EXPIRY_HOURS}</span>
-				PORT: 服务端口 <span id="portValue">${PORT}</span>
+				DEBUG: ${t:index.env_debug} <span id="debugValue">${DEBUG}</span>
+				MAX_CONTENT_LENGTH: ${t:index.env_max_content_length} <span id="maxContentLengthValue">${MAX_CONTENT_LENGTH}</span>
+				SOFFICE_PATH: ${t:index.env_soffice_path} <span id="sofficePathValue">${SOFFICE_PATH}</span>
+				FILE_EXPIRY_HOURS: ${t:index.env_file_expiry_hours} <span id="fileExpiryHoursValue">${FILE_EXPIRY_HOURS}</span>
+				PORT: ${t:index.env_port} <span id="portValue">${PORT}</span>
 			</pre>
             
             <div class="container">
                 <div class="api-doc">
-                    <h2>API文档</h2>
+                    <h2>${t:index.api_doc}</h2>
+                    <p>${t:index.auth_note}</p>
+                    <p>${t:index.v1_note}</p>
+                    <p>${t:index.lang_note}</p>
+                    <p>${t:index.openapi_note}</p>
                     
-                    <h3>1. 文档转换 API</h3>
-                    <p><strong>接口</strong>: <code>POST /convert</code></p>
-                    <p><strong>说明</strong>: 将上传的文档转换为指定格式</p>
-                    <p><strong>请求参数</strong>:</p>
+                    <h3>${t:index.convert_api}</h3>
+                    <p>${t:index.endpoint}: <code>POST /convert</code></p>
+                    <p>${t:index.description}: ${t:index.convert_description}</p>
+                    <p>${t:index.request_params}:</p>
                     <table>
                         <tr>
-                            <th>参数名</th>
-                            <th>类型</th>
-                            <th>必填</th>
-                            <th>说明</th>
+                            ${t:index.param_name}
+                            ${t:index.param_type}
+                            ${t:index.param_required}
+                            ${t:index.param_description}
                         </tr>
                         <tr>
                             <td>file</td>
                             <td>File</td>
-                            <td>是</td>
-                            <td>要转换的文档文件</td>
+                            ${t:index.yes}
+                            <td>${t:index.param_file}</td>
                         </tr>
                         <tr>
                             <td>format</td>
                             <td>String</td>
-                            <td>否</td>
-                            <td>目标格式，默认为txt</td>
+                            ${t:index.no}
+                            <td>${t:index.param_format}</td>
+                        </tr>
+                        <tr>
+                            <td>ttl_minutes</td>
+                            <td>Integer</td>
+                            ${t:index.no}
+                            <td>${t:index.param_ttl}</td>
                         </tr>
                     </table>
                     
-                    <p><strong>支持的格式</strong>:</p>
+                    <p>${t:index.supported_formats}:</p>
                     <ul>
-                        <li>文本格式: <code>txt</code></li>
-                        <li>PDF格式: <code>pdf</code></li>
-                        <li>Word格式: <code>docx</code></li>
-                        <li>Excel格式: <code>xlsx</code></li>
-                        <li>HTML格式: <code>html</code></li>
-                        <li>更多格式参考LibreOffice文档</li>
+                        <li>${t:index.format_text} <code>txt</code></li>
+                        <li>${t:index.format_pdf} <code>pdf</code></li>
+                        <li>${t:index.format_word} <code>docx</code></li>
+                        <li>${t:index.format_excel} <code>xlsx</code></li>
+                        <li>${t:index.format_html} <code>html</code></li>
+                        <li>${t:index.format_more}</li>
                     </ul>
                     
-                    <p><strong>注意事项</strong>:</p>
+                    <p>${t:index.notes}:</p>
                     <ul>
-                        <li>并非所有格式都可以互相转换，转换能力取决于LibreOffice的支持情况</li>
-                        <li>PDF转Word等复杂转换可能无法保留原始格式</li>
-                        <li>转换失败时会返回详细的错误信息</li>
-                        <li>大文件转换可能需要较长时间</li>
-                        <li>建议在转换前备份原始文件</li>
+                        <li>${t:index.note_capability}</li>
+                        <li>${t:index.note_fidelity}</li>
+                        <li>${t:index.note_errors}</li>
+                        <li>${t:index.note_detection}</li>
+                        <li>${t:index.note_duration}</li>
+                        <li>${t:index.note_backup}</li>
                     </ul>
                     
-                    <p><strong>响应示例</strong>:</p>
+                    <p>${t:index.example_response}:</p>
                     <pre>{
   "success": true,
-  "filename": "原始文件名.docx",
-  "download_url": "http://localhost:${PORT}/download/20231201/文件名_1701410000000.pdf",
-  "download_filename": "20231201/文件名_1701410000000.pdf",
+  "filename": "${t:index.example_source}.docx",
+  "download_url": "http://localhost:${PORT}/download/20231201/${t:index.example_output}.pdf?expires=1701496800&signature=...",
+  "download_filename": "20231201/${t:index.example_output}.pdf",
+  "download_url_expiry": "2023-12-02 10:00:00",
+  "detected_format": "docx",
   "expiry": "2023-12-02 10:00:00"
 }</pre>
                     
-                    <h3>2. 文件下载 API</h3>
-                    <p><strong>接口</strong>: <code>GET /download/:filename</code></p>
-                    <p><strong>说明</strong>: 下载已转换的文件</p>
-                    <p><strong>请求参数</strong>: </p>
+                    <h3>${t:index.download_api}</h3>
+                    <p>${t:index.endpoint}: <code>GET /download/:filename</code></p>
+                    <p>${t:index.description}: ${t:index.download_description}</p>
+                    <p>${t:index.request_params}: </p>
                     <table>
                         <tr>
-                            <th>参数名</th>
-                            <th>类型</th>
-                            <th>必填</th>
-                            <th>说明</th>
+                            ${t:index.param_name}
+                            ${t:index.param_type}
+                            ${t:index.param_required}
+                            ${t:index.param_description}
                         </tr>
                         <tr>
                             <td>filename</td>
                             <td>String</td>
-                            <td>是</td>
-                            <td>文件路径，格式为 日期/文件名，如：20231201/example_123456789.pdf</td>
+                            ${t:index.yes}
+                            <td>${t:index.param_filename}</td>
+                        </tr>
+                        <tr>
+                            <td>expires</td>
+                            <td>Integer</td>
+                            ${t:index.no}
+                            <td>${t:index.param_expires}</td>
+                        </tr>
+                        <tr>
+                            <td>signature</td>
+                            <td>String</td>
+                            ${t:index.no}
+                            <td>${t:index.param_signature}</td>
                         </tr>
                     </table>
                     
-                    <h3>3. 健康检查 API</h3>
-                    <p><strong>接口</strong>: <code>GET /health</code></p>
-                    <p><strong>说明</strong>: 检查服务健康状态</p>
-                    <p><strong>响应示例</strong>:</p>
+                    <h3>${t:index.health_api}</h3>
+                    <p>${t:index.endpoint}: <code>GET /health</code></p>
+                    <p>${t:index.description}: ${t:index.health_description}</p>
+                    <p>${t:index.example_response}:</p>
                     <pre>{
   "status": "healthy",
   "libreoffice": true,
   "version": "LibreOffice 7.5.3",
   "data_dir": "/app/data",
-  "file_expiry_hours": 24
+  "file_expiry_hours": 24,
+  "disk": {
+    "storage_bytes": 10485760,
+    "storage_files": 42,
+    "max_storage_bytes": 0,
+    "free_bytes": 53687091200,
+    "min_free_bytes": 268435456,
+    "low_disk_space": false,
+    "scanned_at": "2023-12-01 10:00:00"
+  }
 }</pre>
+                    
+                    <p>${t:index.endpoint}: <code>GET /livez</code></p>
+                    <p>${t:index.description}: ${t:index.livez_description}</p>
+                    <p>${t:index.endpoint}: <code>GET /readyz</code></p>
+                    <p>${t:index.description}: ${t:index.readyz_description}</p>
+                    
+                    <h3>${t:index.files_api}</h3>
+                    <p>${t:index.endpoint}: <code>GET /files?page=1&amp;page_size=20&amp;prefix=20231201</code></p>
+                    <p>${t:index.description}: ${t:index.files_list_description}</p>
+                    <p>${t:index.endpoint}: <code>GET /files/:filename/info${t:index.or}HEAD /files/:filename/info</code></p>
+                    <p>${t:index.description}: ${t:index.files_info_description}</p>
+                    <p>${t:index.endpoint}: <code>DELETE /files/:filename</code></p>
+                    <p>${t:index.description}: ${t:index.files_delete_description}</p>
                 </div>
                 
                 <div class="test-form">
-                    <h2>转换测试</h2>
+                    <h2>${t:index.try_it}</h2>
                     <form id="convertForm" enctype="multipart/form-data">
                         <div>
-                            <label for="file">选择文件:</label>
+                            <label for="file">${t:index.choose_file}</label>
                             <input type="file" id="file" name="file" required>
                         </div>
                         <div>
-                            <label for="format">转换格式:</label>
+                            <label for="format">${t:index.target_format}</label>
                             <select id="format" name="format">
-                                <option value="txt">文本 (txt)</option>
+                                <option value="txt">${t:index.option_txt}</option>
                                 <option value="pdf">PDF (pdf)</option>
-                                <option value="docx">Word 文档 (docx)</option>
-                                <option value="doc">Word 97-2003 文档 (doc)</option>
-                                <option value="odt">OpenDocument 文本 (odt)</option>
-                                <option value="rtf">富文本格式 (rtf)</option>
-                                <option value="xlsx">Excel 表格 (xlsx)</option>
-                                <option value="xls">Excel 97-2003 表格 (xls)</option>
-                                <option value="ods">OpenDocument 表格 (ods)</option>
-                                <option value="csv">CSV 表格 (csv)</option>
-                                <option value="pptx">PowerPoint 演示文稿 (pptx)</option>
-                                <option value="ppt">PowerPoint 97-2003 演示文稿 (ppt)</option>
-                                <option value="odp">OpenDocument 演示文稿 (odp)</option>
-                                <option value="html">HTML 网页 (html)</option>
+                                <option value="docx">${t:index.option_docx}</option>
+                                <option value="doc">${t:index.option_doc}</option>
+                                <option value="odt">${t:index.option_odt}</option>
+                                <option value="rtf">${t:index.option_rtf}</option>
+                                <option value="xlsx">${t:index.option_xlsx}</option>
+                                <option value="xls">${t:index.option_xls}</option>
+                                <option value="ods">${t:index.option_ods}</option>
+                                <option value="csv">${t:index.option_csv}</option>
+                                <option value="pptx">${t:index.option_pptx}</option>
+                                <option value="ppt">${t:index.option_ppt}</option>
+                                <option value="odp">${t:index.option_odp}</option>
+                                <option value="html">${t:index.option_html}</option>
                             </select>
                         </div>
                         <div style="margin-top: 20px;">
-                            <button type="submit" class="btn">开始转换</button>
+                            <button type="submit" class="btn">${t:index.start}</button>
                         </div>
                     </form>
                     
                     <div id="result">
-                        <h3>转换结果</h3>
+                        <h3>${t:index.result}</h3>
                         <div id="resultContent"></div>
                     </div>
                     
@@ -806,7 +1085,7 @@ func indexHandler(c *gin.Context) {
                             var formatInput = document.getElementById('format');
                             
                             if (fileInput.files.length === 0) {
-                                alert('请选择要转换的文件');
+                                alert('${t:index.js_choose_file}');
                                 return;
                             }
                             
@@ -815,10 +1094,10 @@ func indexHandler(c *gin.Context) {
                             
                             var resultDiv = document.getElementById('result');
                             var resultContent = document.getElementById('resultContent');
-                            resultContent.innerHTML = '<p>正在转换，请稍候...</p>';
+                            resultContent.innerHTML = '<p>${t:index.js_converting}</p>';
                             resultDiv.style.display = 'block';
                             
-                            fetch('/convert', {
+                            fetch('/convert?lang=${LANG}', {
                                 method: 'POST',
                                 body: formData
                             })
@@ -828,18 +1107,18 @@ func indexHandler(c *gin.Context) {
                             .then(function(data) {
                                 if (data.success) {
                                     var html = '<div class="success">' +
-                                        '<p>转换成功!</p>' +
-                                        '<p>原始文件: ' + data.filename + '</p>' +
-                                        '<p>格式: ' + formatInput.value + '</p>';
+                                        '<p>${t:index.js_success}</p>' +
+                                        '<p>${t:index.js_source}' + data.filename + '</p>' +
+                                        '<p>${t:index.js_format}' + formatInput.value + '</p>';
                                     
                                     if (data.expiry) {
-                                        html += '<p>过期时间: ' + data.expiry + '</p>';
+                                        html += '<p>${t:index.js_expiry}' + data.expiry + '</p>';
                                     }
                                     
-                                    html += '<p><a href="' + data.download_url + '" target="_blank" class="btn">下载文件</a></p>';
+                                    html += '<p><a href="' + data.download_url + '" target="_blank" class="btn">${t:index.js_download}</a></p>';
                                     
                                     if (data.text) {
-                                        html += '<h4>文件内容预览:</h4>' +
+                                        html += '<h4>${t:index.js_preview}</h4>' +
                                             '<pre style="max-height: 300px; overflow: auto;">' + data.text + '</pre>';
                                     }
                                     
@@ -847,14 +1126,14 @@ func indexHandler(c *gin.Context) {
                                     resultContent.innerHTML = html;
                                 } else {
                                     resultContent.innerHTML = '<div class="error">' +
-                                        '<p>转换失败: ' + data.error + '</p>' +
-                                        (data.details ? '<p>详情: ' + data.details + '</p>' : '') +
+                                        '<p>${t:index.js_failed}' + data.error + '</p>' +
+                                        (data.details ? '<p>${t:index.js_details}' + data.details + '</p>' : '') +
                                         '</div>';
                                 }
                             })
                             .catch(function(error) {
                                 resultContent.innerHTML = '<div class="error">' +
-                                    '<p>请求失败: ' + error.message + '</p>' +
+                                    '<p>${t:index.js_request_failed}' + error.message + '</p>' +
                                     '</div>';
                             });
                         });
@@ -863,12 +1142,15 @@ func indexHandler(c *gin.Context) {
             </div>
             
             <footer style="margin-top: 50px; border-top: 1px solid #eee; padding-top: 20px; text-align: center; color: #777;">
-                <p>LibreOffice文档转换API | Go版本 | 支持多种文档格式转换</p>
+                <p>${t:index.footer}</p>
             </footer>
         </body>
     </html>
     `
 	
+	lang := requestLanguage(c)
+	html = localizeTemplate(html, lang)
+	html = strings.ReplaceAll(html, "${LANG}", lang)
 	html = strings.ReplaceAll(html, "${DEBUG}", strconv.FormatBool(DEBUG))
 	html = strings.ReplaceAll(html, "${MAX_CONTENT_LENGTH}", strconv.Itoa(int(MAX_CONTENT_LENGTH)))
 	html = strings.ReplaceAll(html, "${SOFFICE_PATH}", SOFFICE_PATH)
@@ -879,15 +1161,51 @@ func indexHandler(c *gin.Context) {
 	c.String(http.StatusOK, html)
 }
 
-// 健康检查处理
+// registerAPIRoutes 注册转换、下载、文件管理和管理接口，旧接口和/v1接口使用相同的处理函数
+func registerAPIRoutes(r gin.IRouter) {
+	r.POST("/convert", auditMiddleware(AuditConvert), requireScope(ScopeConvert), rateLimitMiddleware(), convertDocumentHandler)
+	r.GET("/download/*filename", auditMiddleware(AuditDownload), requireScope(ScopeDownload), func(c *gin.Context) {
+		// 去除前导的"/"字符
+		filename := c.Param("filename")
+		if strings.HasPrefix(filename, "/") {
+			filename = filename[1:]
+		}
+		c.Params = append(c.Params, gin.Param{
+			Key:   "filename",
+			Value: filename,
+		})
+		downloadFileHandler(c)
+	})
+	
+	// 文件管理API
+	r.GET("/files", requireScope(ScopeDownload), listFilesHandler)
+	r.GET("/files/*path", requireScope(ScopeDownload), fileInfoHandler)
+	r.HEAD("/files/*path", requireScope(ScopeDownload), fileInfoHandler)
+	r.DELETE("/files/*path", requireScope(ScopeDownload), deleteFileHandler)
+	
+	// 管理API
+	r.POST("/admin/keys/reload", requireScope(ScopeAdmin), reloadAPIKeysHandler)
+	r.GET("/admin/audit", requireScope(ScopeAdmin), auditQueryHandler)
+}
+
+// 健康检查处理，保留用于兼容，容器编排的探针应使用/livez和/readyz
 func healthCheckHandler(c *gin.Context) {
+	disk := currentDiskUsage(c.Request.Context())
+	status := "healthy"
+	if disk.LowDiskSpace {
+		status = "degraded"
+	}
+	if lastCanaryCheck().Status == CheckFail {
+		status = "unhealthy"
+	}
 	response := HealthResponse{
-		Status:         "healthy",
+		Status:         status,
 		LibreOffice:    libreofficeAvailable,
 		Version:        libreofficeVersion,
 		DataDir:        DATA_DIR,
 		FileExpiryHours: FILE_EXPIRY_HOURS,
 		Port:           PORT,
+		Disk:           disk,
 	}
 	c.JSON(http.StatusOK, response)
 }
@@ -896,7 +1214,8 @@ func healthCheckHandler(c *gin.Context) {
 func downloadFileHandler(c *gin.Context) {
 	// 获取文件路径参数
 	filename := c.Param("filename")
-	log.Printf("接收到下载请求，路径: %s", filename)
+	logger := requestLogger(c)
+	logger.Debug("接收到下载请求", "path", filename)
 	
 	// 去除前导斜杠（如果有）
 	filename = strings.TrimPrefix(filename, "/")
@@ -904,65 +1223,97 @@ func downloadFileHandler(c *gin.Context) {
 	// 解码URL，处理可能的URL编码
 	decodedFilename, err := url.QueryUnescape(filename)
 	if err != nil {
-		log.Printf("URL解码失败: %v, 原始URL: %s", err, filename)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的文件路径格式"})
+		logger.Warn("URL解码失败", "path", filename, "error", err)
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: tr(c, "error.invalid_path_format"), Code: CodeInvalidPath})
 		return
 	}
 	
 	// 安全检查：防止目录遍历攻击
 	if strings.Contains(decodedFilename, "..") || strings.Contains(decodedFilename, "\\") {
-		log.Printf("检测到潜在的安全问题: %s", decodedFilename)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的文件路径"})
+		logger.Warn("检测到潜在的安全问题", "path", decodedFilename)
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: tr(c, "error.invalid_path"), Code: CodeInvalidPath})
 		return
 	}
 	
 	// 确保路径非空
 	if decodedFilename == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "未指定文件路径"})
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: tr(c, "error.path_missing"), Code: CodeInvalidPath})
 		return
 	}
 	
-	// 分析路径格式
-	parts := strings.Split(decodedFilename, "/")
-	
-	// 构建完整的文件系统路径
-	var filePath string
-	if len(parts) >= 2 {
-		// 如果包含至少一个斜杠，假设格式为dateDir/filename
-		dateDir, fileName := parts[0], parts[1]
-		filePath = filepath.Join(DATA_DIR, dateDir, fileName)
-		log.Printf("使用日期目录格式: %s/%s", dateDir, fileName)
-	} else {
-		// 否则视为直接的文件名
-		filePath = filepath.Join(DATA_DIR, decodedFilename)
-		log.Printf("使用直接文件名: %s", decodedFilename)
+	// 校验下载链接签名
+	relativePath, err := cleanStoragePath(decodedFilename)
+	if err != nil || isMetadataPath(relativePath) {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: tr(c, "error.invalid_path"), Code: CodeInvalidPath})
+		return
+	}
+	auditEventFrom(c).Path = relativePath
+	signature := c.Query("signature")
+	if signature != "" || REQUIRE_SIGNED_DOWNLOADS {
+		if err := verifyDownloadSignature(relativePath, c.Query("expires"), signature); err != nil {
+			logger.Warn("下载链接校验失败", "path", relativePath, "error", err)
+			respondError(c, http.StatusForbidden, ErrorResponse{Error: tr(c, "error."+signatureErrorCode(err)), Code: signatureErrorCode(err)})
+			return
+		}
 	}
 	
-	// 检查文件是否存在
-	fileInfo, err := os.Stat(filePath)
+	// 从存储中获取文件信息
+	storedFile, err := fileStorage.Stat(c.Request.Context(), relativePath)
 	if err != nil {
-		if os.IsNotExist(err) {
-			log.Printf("文件不存在: %s", filePath)
-			c.JSON(http.StatusNotFound, ErrorResponse{Error: "文件不存在"})
+		if errors.Is(err, ErrFileNotFound) {
+			logger.Info("文件不存在", "path", relativePath)
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: tr(c, "error.file_not_found"), Code: CodeFileNotFound})
 		} else {
-			log.Printf("检查文件状态出错: %v", err)
-			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "文件访问错误"})
+			logger.Error("检查文件状态出错", "path", relativePath, "error", err)
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: tr(c, "error.file_access"), Code: CodeStorageError})
 		}
 		return
 	}
 	
-	// 确保不是目录
-	if fileInfo.IsDir() {
-		log.Printf("请求的路径是目录: %s", filePath)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无法下载目录"})
+	// 不允许下载其他租户的文件
+	if !tenantCanAccess(c, relativePath) {
+		logger.Warn("拒绝跨租户下载", "path", relativePath, "client", callerIdentity(c))
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: tr(c, "error.file_not_found"), Code: CodeFileNotFound})
+		return
+	}
+	
+	// 启用认证时只有文件创建者可以下载
+	if authEnabled() {
+		if _, allowed := authorizeFileAccess(c, relativePath); !allowed {
+			logger.Warn("拒绝下载他人的文件", "path", relativePath, "client", callerIdentity(c))
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: tr(c, "error.file_not_found"), Code: CodeFileNotFound})
+			return
+		}
+	}
+	
+	// 检查文件是否已过期，清理任务可能尚未执行
+	expiresAt, err := storedFileExpiry(c.Request.Context(), storedFile)
+	if err != nil {
+		logger.Warn("读取文件过期时间出错", "path", relativePath, "error", err)
+	} else if isExpired(expiresAt, time.Now()) {
+		logger.Info("文件已过期", "path", relativePath)
+		respondError(c, http.StatusGone, ErrorResponse{Error: tr(c, "error.file_expired"), Code: CodeFileExpired})
 		return
 	}
 	
 	// 获取文件名用于下载头
-	fileName := filepath.Base(filePath)
+	fileName := path.Base(storedFile.Path)
+	auditEventFrom(c).OutputBytes = storedFile.Size
 	
 	// 检测MIME类型
-	mimeType := detectMimeType(filePath)
+	mimeType := detectMimeType(fileName)
+	
+	// 对象存储可以直接重定向到预签名URL
+	if S3_PRESIGN_DOWNLOAD {
+		presignedURL, err := fileStorage.PresignURL(c.Request.Context(), storedFile.Path, fileName, time.Duration(S3_PRESIGN_EXPIRY_MINUTES)*time.Minute)
+		if err != nil {
+			logger.Warn("生成预签名URL失败", "path", storedFile.Path, "error", err)
+		} else if presignedURL != "" {
+			logger.Info("重定向到预签名URL", "path", storedFile.Path)
+			c.Redirect(http.StatusFound, presignedURL)
+			return
+		}
+	}
 	
 	// 设置响应头
 	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
@@ -971,33 +1322,84 @@ func downloadFileHandler(c *gin.Context) {
 	}
 	
 	// 向日志记录成功的下载请求
-	log.Printf("提供文件下载: %s (大小: %d 字节, 类型: %s)", filePath, fileInfo.Size(), mimeType)
+	logger.Info("提供文件下载", "path", storedFile.Path, "size", storedFile.Size, "mime_type", mimeType)
+	
+	// 本地存储直接发送文件，支持断点续传
+	if local, ok := fileStorage.(*localStorage); ok {
+		filePath, _ := local.LocalPath(storedFile.Path)
+		c.File(filePath)
+		return
+	}
 	
-	// 发送文件
-	c.File(filePath)
+	// 其他存储以流的方式发送
+	reader, err := fileStorage.Open(c.Request.Context(), storedFile.Path)
+	if err != nil {
+		logger.Error("打开存储文件失败", "path", storedFile.Path, "error", err)
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: tr(c, "error.file_access"), Code: CodeStorageError})
+		return
+	}
+	defer reader.Close()
+	c.DataFromReader(http.StatusOK, storedFile.Size, mimeType, reader, nil)
 }
 
 // 文档转换处理
 func convertDocumentHandler(c *gin.Context) {
 	// 检查LibreOffice是否可用
 	if !libreofficeAvailable {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error:   "LibreOffice未安装或配置错误",
+		respondError(c, http.StatusInternalServerError, ErrorResponse{
+			Error:   tr(c, "error.libreoffice_unavailable"),
+			Code:    CodeLibreOfficeUnavailable,
 			Details: libreofficeVersion,
 		})
 		return
 	}
 	
+	logger := requestLogger(c)
+	
+	// 按租户限制上传文件大小
+	tenant := currentTenant(c)
+	maxSize := tenant.maxContentLength()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+multipartOverhead)
+	
 	// 获取上传的文件
+	ctx := c.Request.Context()
+	_, span := startSpan(ctx, "upload.receive")
 	file, header, err := c.Request.FormFile("file")
+	if err == nil {
+		span.SetAttributes(attribute.Int64("upload.size", header.Size))
+	}
+	endSpan(span, err)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "没有上传文件"})
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondError(c, http.StatusRequestEntityTooLarge, ErrorResponse{
+				Error: tr(c, "error.file_too_large"),
+				Code:  CodeFileTooLarge,
+				Details: tr(c, "detail.file_too_large", maxSize),
+			})
+			return
+		}
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: tr(c, "error.missing_file"), Code: CodeMissingFile})
 		return
 	}
 	defer file.Close()
 	
 	if header.Filename == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "文件名为空"})
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: tr(c, "error.empty_filename"), Code: CodeMissingFile})
+		return
+	}
+	
+	if header.Size > maxSize {
+		respondError(c, http.StatusRequestEntityTooLarge, ErrorResponse{
+			Error: tr(c, "error.file_too_large"),
+			Code:  CodeFileTooLarge,
+			Details: tr(c, "detail.file_too_large", maxSize),
+		})
+		return
+	}
+	
+	// 按实际文件大小统计每日上传数据量
+	if !recordInputBytes(c, header.Size) {
 		return
 	}
 	
@@ -1005,186 +1407,186 @@ func convertDocumentHandler(c *gin.Context) {
 	originalFilename := header.Filename
 	fileExt := strings.ToLower(filepath.Ext(originalFilename))
 	
-	// 验证文件类型
-	if !isValidInputFormat(fileExt) {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: "不支持的输入文件格式",
-			Details: fmt.Sprintf("不支持将%s格式转换为其他格式", fileExt),
+	audit := auditEventFrom(c)
+	audit.FilenameSHA256 = hashFilename(originalFilename)
+	audit.InputBytes = header.Size
+	audit.SourceFormat = strings.TrimPrefix(fileExt, ".")
+	
+	// 校验文件类型和内容
+	detectedExt, inputExt, convErr := checkInputFile(ctx, logger, file, header.Size, originalFilename, fileExt)
+	if convErr != nil {
+		respondError(c, convErr.status, convErr.response(requestLanguage(c)))
+		return
+	}
+	
+	formatCorrected := inputExt != fileExt
+	if formatCorrected {
+		logger.Info("文件扩展名与内容不符，已修正", "filename", originalFilename, "claimed_format", fileExt, "detected_format", inputExt)
+		fileExt = inputExt
+		audit.SourceFormat = strings.TrimPrefix(fileExt, ".")
+	}
+	
+	// 租户可以进一步限制输入格式
+	if !tenant.allowsInputFormat(fileExt) {
+		respondError(c, http.StatusForbidden, ErrorResponse{
+			Error: tr(c, "error.input_format_not_allowed"),
+			Code:  CodeInputFormatNotAllowed,
+			Details: tr(c, "detail.input_format_not_allowed", tenant.tenantID(), fileExt),
 		})
 		return
 	}
 	
 	// 获取转换格式，默认为txt
-	convertFormat := c.PostForm("format")
-	if convertFormat == "" {
-		convertFormat = "txt"
+	convertFormat, targetExt, convErr := parseConvertFormat(c.PostForm("format"))
+	audit.TargetFormat = targetExt
+	if convErr != nil {
+		respondError(c, convErr.status, convErr.response(requestLanguage(c)))
+		return
 	}
 	
-	// 从格式中提取扩展名
-	targetExt := strings.ToLower(strings.Split(convertFormat, ":")[0])
+	// 租户可以进一步限制输出格式
+	if !tenant.allowsOutputFormat(targetExt) {
+		respondError(c, http.StatusForbidden, ErrorResponse{
+			Error: tr(c, "error.output_format_not_allowed"),
+			Code:  CodeOutputFormatNotAllowed,
+			Details: tr(c, "detail.output_format_not_allowed", tenant.tenantID(), targetExt),
+		})
+		return
+	}
 	
-	// 验证输出格式是否支持
-	if !isValidOutputFormat(targetExt) {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: "不支持的输出格式",
-			Details: fmt.Sprintf("不支持转换为%s格式", targetExt),
+	// 获取文件保存时间（分钟），只能比租户的过期时间更短
+	ttlMinutes, err := parseTTLMinutes(c.PostForm("ttl_minutes"), tenant.fileExpiryHours())
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{
+			Error: tr(c, "error.invalid_ttl"),
+			Code:  CodeInvalidTTL,
+			Details: trError(c, err),
 		})
 		return
 	}
 	
-	log.Printf("文件转换: %s (%s) -> %s", originalFilename, fileExt, targetExt)
+	logger.Info("文件转换", "filename", originalFilename, "source_format", fileExt, "target_format", targetExt, "size", header.Size)
+	
+	// 可用磁盘空间不足时拒绝新的转换
+	if err := checkFreeDiskSpace(c.Request.Context()); err != nil {
+		logger.Warn("拒绝转换请求: 磁盘空间不足", "error", err)
+		respondError(c, http.StatusInsufficientStorage, ErrorResponse{
+			Error: tr(c, "error.insufficient_storage"),
+			Code:  CodeInsufficientStorage,
+			Details: trError(c, err),
+		})
+		return
+	}
 	
 	// 使用唯一ID作为文件名，避免中文文件名问题
 	uniqueID := uuid.New().String()
 	safeFilename := fmt.Sprintf("%s%s", uniqueID, fileExt)
 	
 	// 在tmp目录下创建一个新的子目录用于此次转换
-	workDir := filepath.Join(TMP_DIR, fmt.Sprintf("work_%s", uniqueID))
-	os.MkdirAll(workDir, 0755)
+	workDir, err := beginWork(uniqueID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: tr(c, "error.create_work_dir", err), Code: CodeInternalError})
+		return
+	}
+	
+	// 清理临时目录
+	defer endWork(c.Request.Context(), uniqueID)
 	
 	// 保存上传的文件
 	filePath := filepath.Join(workDir, safeFilename)
+	_, span = startSpan(ctx, "file.save", attribute.String("file.path", filePath))
 	dst, err := os.Create(filePath)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: fmt.Sprintf("创建文件失败: %v", err)})
+		endSpan(span, err)
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: tr(c, "error.create_file", err), Code: CodeInternalError})
 		return
 	}
 	
 	// 复制文件内容
 	if _, err = io.Copy(dst, file); err != nil {
 		dst.Close()
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: fmt.Sprintf("保存文件失败: %v", err)})
+		endSpan(span, err)
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: tr(c, "error.save_upload", err), Code: CodeInternalError})
 		return
 	}
 	dst.Close()
+	endSpan(span, nil)
 	
 	// 转换文件并响应
-	response, statusCode := convertFile(workDir, filePath, originalFilename, convertFormat, targetExt, uniqueID, c)
-	
-	// 清理临时目录
-	defer func() {
-		if err := os.RemoveAll(workDir); err != nil {
-			log.Printf("清理临时目录时出错: %v", err)
-		} else {
-			log.Printf("已清理临时目录: %s", workDir)
-		}
-	}()
-	
-	c.JSON(statusCode, response)
+	response, statusCode := convertFile(workDir, filePath, originalFilename, convertFormat, targetExt, uniqueID, ttlMinutes, c)
+	_, span = startSpan(ctx, "response")
+	defer span.End()
+	switch resp := response.(type) {
+	case ConversionResponse:
+		resp.DetectedFormat = strings.TrimPrefix(detectedExt, ".")
+		resp.FormatCorrected = formatCorrected
+		c.JSON(statusCode, resp)
+	case ErrorResponse:
+		respondError(c, statusCode, resp)
+	default:
+		c.JSON(statusCode, response)
+	}
 }
 
 // 文件转换处理
-func convertFile(workDir, filePath, originalFilename, convertFormat, targetExt, uniqueID string, c *gin.Context) (interface{}, int) {
-	// 直接使用LibreOffice进行格式转换
-	log.Printf("开始转换文件: %s 为 %s 格式", filePath, targetExt)
+func convertFile(workDir, filePath, originalFilename, convertFormat, targetExt, uniqueID string, ttlMinutes int, c *gin.Context) (interface{}, int) {
+	logger := requestLogger(c)
+	tenant := currentTenant(c)
+	ctx := c.Request.Context()
 	
-	// 构建转换命令
-	convertCmd := []string{
-		"--headless",
-		"--convert-to",
-		convertFormat,
-		filePath,
-		"--outdir",
-		workDir,
+	// 直接使用LibreOffice进行格式转换
+	outputPath, convErr := runConversion(ctx, logger, workDir, filePath, convertFormat, targetExt, uniqueID, tenant.tenantID())
+	if convErr != nil {
+		return convErr.response(requestLanguage(c)), convErr.status
 	}
 	
-	log.Printf("执行转换命令: %s %s", SOFFICE_PATH, strings.Join(convertCmd, " "))
-	
-	// 执行转换命令
-	cmd := exec.Command(SOFFICE_PATH, convertCmd...)
-	output, err := cmd.CombinedOutput()
-	outputStr := string(output)
-	
-	// 检查命令是否出错
+	// 生成持久化存储路径
+	relativePath := generateOutputFilepath(tenant.storagePrefix(), originalFilename, targetExt)
+	
+	// 将转换后的文件从临时目录保存到存储后端
+	saveCtx, span := startSpan(ctx, "storage.copy",
+		attribute.String("storage.backend", fileStorage.Name()),
+		attribute.String("storage.path", relativePath),
+	)
+	err := fileStorage.Save(saveCtx, outputPath, relativePath)
+	endSpan(span, err)
 	if err != nil {
-		log.Printf("转换过程出错: %v, 输出: %s", err, outputStr)
+		logger.Error("保存转换结果失败", "path", relativePath, "error", err)
 		return ErrorResponse{
-			Error:   "文件转换失败",
-			Details: fmt.Sprintf("%v: %s", err, outputStr),
+			Error:   tr(c, "error.save_output"),
+			Code:    CodeStorageError,
+			Details: tr(c, "detail.save_output", err),
 		}, http.StatusInternalServerError
 	}
-	
-	// 检查输出中是否包含错误信息
-	if strings.Contains(outputStr, "Error:") || strings.Contains(outputStr, "error") || 
-	   strings.Contains(outputStr, "Failed") || strings.Contains(outputStr, "failed") ||
-	   strings.Contains(outputStr, "no export filter") {
-		log.Printf("转换过程有错误信息: %s", outputStr)
-		return ErrorResponse{
-			Error:   "文件转换失败",
-			Details: fmt.Sprintf("LibreOffice报告错误: %s", outputStr),
-		}, http.StatusInternalServerError
-	}
-	
-	// 列出工作目录中的所有文件
-	files, err := os.ReadDir(workDir)
-	if err != nil {
-		return ErrorResponse{Error: fmt.Sprintf("读取工作目录失败: %v", err)}, http.StatusInternalServerError
-	}
-	
-	// 记录所有文件用于调试
-	var allFiles []string
-	for _, f := range files {
-		allFiles = append(allFiles, f.Name())
+	audit := auditEventFrom(c)
+	audit.Path = relativePath
+	if info, err := os.Stat(outputPath); err == nil {
+		audit.OutputBytes = info.Size()
+		recordStoredFile(StoredFile{Path: relativePath, Size: info.Size(), ModTime: time.Now()})
 	}
 	
-	// 查找转换后的输出文件
-	var outputPath string
+	// 新文件可能使存储超出配额
+	requestQuotaCheck()
 	
-	for _, file := range files {
-		fileName := file.Name()
-		// 检查文件是否有目标扩展名，且不是原始输入文件
-		if strings.HasSuffix(strings.ToLower(fileName), fmt.Sprintf(".%s", targetExt)) &&
-		   fileName != filepath.Base(filePath) {
-			outputPath = filepath.Join(workDir, fileName)
-			log.Printf("找到转换后的文件: %s", outputPath)
-			break
-		}
-	}
-	
-	// 如果没有找到输出文件，返回错误
-	if outputPath == "" {
-		errorDetails := fmt.Sprintf("在工作目录中未找到以 .%s 结尾的转换输出文件。可能的原因:\n", targetExt)
-		errorDetails += "1. 文件格式不支持转换到目标格式\n"
-		errorDetails += "2. 文件可能已损坏或格式不兼容\n"
-		errorDetails += "3. LibreOffice未能正确执行转换\n"
-		
-		log.Printf("未找到输出文件。工作目录中的文件: %v", allFiles)
-		return ErrorResponse{
-			Error:   "转换后的文件未找到",
-			Details: errorDetails,
-		}, http.StatusInternalServerError
+	// 记录文件元数据，用于文件管理API和过期清理
+	now := time.Now()
+	meta := FileMetadata{
+		OriginalFilename: originalFilename,
+		SourceFormat:     strings.TrimPrefix(filepath.Ext(filePath), "."),
+		TargetFormat:     targetExt,
+		Creator:          callerIdentity(c),
+		CreatedAt:        now,
+		ExpiresAt:        computeExpiresAt(now, tenant.fileExpiryHours(), ttlMinutes),
 	}
-	
-	// 生成持久化存储路径
-	finalOutputPath, relativePath := generateOutputFilepath(originalFilename, targetExt)
-	
-	// 将转换后的文件从临时目录复制到持久化存储目录
-	if err := copyFile(outputPath, finalOutputPath); err != nil {
-		return ErrorResponse{
-			Error:   "保存文件失败",
-			Details: fmt.Sprintf("无法将文件复制到最终位置: %v", err),
-		}, http.StatusInternalServerError
+	if _, options, found := strings.Cut(convertFormat, ":"); found {
+		meta.Options = options
 	}
-	
-	// 生成下载URL
-	scheme := "http"
-	if c.Request.TLS != nil {
-		scheme = "https"
+	if err := saveFileMetadata(ctx, relativePath, meta); err != nil {
+		logger.Warn("保存文件元数据失败", "path", relativePath, "error", err)
 	}
-	host := c.Request.Host
-	
-	// 构建下载URL（确保路径格式正确）
-	cleanRelativePath := strings.TrimPrefix(relativePath, "/")
-	downloadURL := fmt.Sprintf("%s://%s/download/%s", scheme, host, cleanRelativePath)
 	
-	// 计算过期时间
-	var expiryInfo string
-	if FILE_EXPIRY_HOURS > 0 {
-		expiryTime := time.Now().Add(time.Duration(FILE_EXPIRY_HOURS) * time.Hour)
-		expiryInfo = expiryTime.Format("2006-01-02 15:04:05")
-	} else {
-		expiryInfo = "永不过期"
-	}
+	// 生成带签名的下载URL
+	downloadURL, downloadURLExpiry := buildDownloadURL(c, relativePath)
 	
 	// 构建响应对象
 	response := ConversionResponse{
@@ -1192,20 +1594,21 @@ func convertFile(workDir, filePath, originalFilename, convertFormat, targetExt,
 		Filename:        originalFilename,
 		DownloadURL:     downloadURL,
 		DownloadFilename: relativePath,
-		Expiry:          expiryInfo,
+		DownloadURLExpiry: downloadURLExpiry.Format("2006-01-02 15:04:05"),
+		Expiry:          formatExpiry(c, meta.ExpiresAt),
 	}
 	
 	// 如果输出是文本格式，读取文本内容
 	if targetExt == "txt" {
-		textBytes, err := os.ReadFile(finalOutputPath)
+		textBytes, err := os.ReadFile(outputPath)
 		if err == nil {
 			response.Text = string(textBytes)
-			log.Printf("已读取文本内容，长度: %d 字节", len(response.Text))
+			logger.Debug("已读取文本内容", "bytes", len(response.Text))
 		} else {
-			log.Printf("读取文本内容失败: %v", err)
+			logger.Warn("读取文本内容失败", "error", err)
 		}
 	}
 	
-	log.Printf("转换完成: %s -> %s, 下载URL: %s", originalFilename, targetExt, downloadURL)
+	logger.Info("转换完成", "filename", originalFilename, "target_format", targetExt, "path", relativePath)
 	return response, http.StatusOK
 } 
\ No newline at end of file
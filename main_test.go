@@ -0,0 +1,15 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// TestMain 测试程序被作为辅助进程重新执行时，与main一样转入辅助进程的逻辑
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && os.Args[1] == limitsHelperArg {
+		runLimitsHelperFromEnv()
+		return
+	}
+	os.Exit(m.Run())
+}
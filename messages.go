@@ -0,0 +1,389 @@
+package main
+
+// messagesZhCN 简体中文消息目录，其他语言缺少的消息使用该目录中的文本
+var messagesZhCN = map[string]string{
+	// 错误信息
+	"error.file_not_found":                  "文件不存在",
+	"error.file_access":                     "文件访问错误",
+	"error.conversion_failed":               "文件转换失败",
+	"error.forbidden":                       "权限不足",
+	"error.invalid_path":                    "无效的文件路径",
+	"error.invalid_path_format":             "无效的文件路径格式",
+	"error.path_missing":                    "未指定文件路径",
+	"error.file_too_large":                  "文件过大",
+	"error.read_upload":                     "读取上传文件失败: %v",
+	"error.create_work_dir":                 "创建工作目录失败: %v",
+	"error.create_file":                     "创建文件失败: %v",
+	"error.save_upload":                     "保存文件失败: %v",
+	"error.read_work_dir":                   "读取工作目录失败: %v",
+	"error.save_output":                     "保存文件失败",
+	"error.reload_keys":                     "重新加载API密钥失败",
+	"error.unauthenticated":                 "缺少API密钥或访问令牌",
+	"error.invalid_api_key":                 "无效的API密钥",
+	"error.invalid_token":                   "无效的访问令牌",
+	"error.input_format_not_allowed":        "租户不允许该输入格式",
+	"error.output_format_not_allowed":       "租户不允许该输出格式",
+	"error.insufficient_storage":            "磁盘空间不足，暂时无法转换",
+	"error.missing_file":                    "没有上传文件",
+	"error.empty_filename":                  "文件名为空",
+	"error.audit_query":                     "查询审计日志失败",
+	"error.audit_disabled":                  "审计日志未启用",
+	"error.audit_query_requires_auth":       "未启用认证时不能查询审计日志",
+	"error.file_management_requires_auth":   "未启用认证时不能列出或删除文件",
+	"error.invalid_time":                    "无效的时间参数",
+	"error.internal":                        "服务器内部错误",
+	"error.invalid_ttl":                     "无效的文件保存时间",
+	"error.archive_limit":                   "文件超出压缩包安全限制",
+	"error.file_expired":                    "文件已过期",
+	"error.content_mismatch":                "文件内容与格式不符",
+	"error.route_not_found":                 "接口不存在",
+	"error.delete_file":                     "删除文件失败",
+	"error.list_files":                      "列出文件失败",
+	"error.unsupported_input_format":        "不支持的输入文件格式",
+	"error.unsupported_output_format":       "不支持的输出格式",
+	"error.output_missing":                  "转换后的文件未找到",
+	"error.service_busy":                    "服务繁忙，请稍后重试",
+	"error.conversion_timeout":              "文件转换超时",
+	"error.resource_limit":                  "文件转换超出资源限制",
+	"error.libreoffice_unavailable":         "LibreOffice未安装或配置错误",
+	"error.signature_missing":               "下载链接缺少签名",
+	"error.signature_invalid":               "下载链接签名无效",
+	"error.signature_expired":               "下载链接已过期",
+	"error.rate_limited":                    "请求过于频繁",
+	"error.daily_conversion_quota_exceeded": "已超出每日转换次数配额",
+	"error.daily_upload_quota_exceeded":     "已超出每日上传数据量配额",
+
+	// 错误详情，可以包含格式化参数
+	"detail.file_too_large":            "上传文件不能超过%d字节",
+	"detail.insufficient_scope":        "需要%s权限",
+	"detail.conversion_timeout":        "转换超过%d秒未完成",
+	"detail.retry_after":               "请在%d秒后重试",
+	"detail.output_format_not_allowed": "租户%s不允许转换为%s格式",
+	"detail.input_format_not_allowed":  "租户%s不允许转换%s格式的文件",
+	"detail.save_output":               "无法将文件复制到最终位置: %v",
+	"detail.prepare_profile":           "准备LibreOffice用户配置失败: %v",
+	"detail.unsupported_output_format": "不支持转换为%s格式",
+	"detail.unsupported_input_format":  "不支持将%s格式转换为其他格式",
+	"detail.libreoffice_error":         "LibreOffice报告错误: %s",
+	"detail.invalid_time":              "%s必须为RFC3339格式，如2006-01-02T15:04:05Z",
+	"detail.other_tenant_audit":        "不能查询其他租户的审计日志",
+	"detail.output_missing":            "在工作目录中未找到以 .%s 结尾的转换输出文件。可能的原因:\n1. 文件格式不支持转换到目标格式\n2. 文件可能已损坏或格式不兼容\n3. LibreOffice未能正确执行转换\n",
+	"detail.invalid_ttl":               "ttl_minutes必须为正整数: %s",
+	"detail.ttl_too_long":              "ttl_minutes不能超过过期时间%d分钟",
+	"detail.low_disk_space":            "%s 可用 %d 字节，低于阈值 %d 字节",
+	"detail.unknown_tenant":            "未知的租户: %s",
+	"detail.content_unrecognized":      "无法识别文件内容，文件扩展名为%s",
+	"detail.content_unsupported":       "文件实际内容为%s格式，不支持转换",
+	"detail.content_mismatch":          "文件扩展名为%s，但实际内容为%s格式",
+	"detail.archive_invalid":           "无法解析压缩包: %v",
+	"detail.archive_entry_unreadable":  "无法读取条目%s: %v",
+	"detail.archive_too_many_entries":  "压缩包条目数超过上限%d",
+	"detail.archive_too_large":         "压缩包解压后超过%d字节",
+	"detail.archive_nested_too_large":  "嵌套压缩包%s超过%d字节",
+	"detail.archive_total_ratio":       "压缩包整体压缩比超过%d",
+	"detail.archive_entry_ratio":       "条目%s的压缩比超过%d",
+	"detail.archive_nesting":           "压缩包嵌套超过%d层: %s",
+
+	// 响应中的其他文本
+	"misc.never_expires": "永不过期",
+
+	// 首页，可以包含HTML
+	"index.footer":                   "LibreOffice文档转换API | Go版本 | 支持多种文档格式转换",
+	"index.title":                    "LibreOffice文档转换API",
+	"index.intro":                    "这是一个基于Go实现的文档转换API服务，可以将各种格式的文档转换为其他格式。",
+	"index.env_debug":                "是否开启调试模式",
+	"index.env_max_content_length":   "最大上传文件大小",
+	"index.env_soffice_path":         "LibreOffice安装路径",
+	"index.env_file_expiry_hours":    "文件过期时间",
+	"index.env_port":                 "服务端口",
+	"index.env_vars":                 "环境变量",
+	"index.api_doc":                  "API文档",
+	"index.auth_note":                "如果服务启用了API密钥认证，请通过 <code>X-API-Key</code> 请求头或 <code>Authorization: Bearer</code> 提供密钥。",
+	"index.v1_note":                  "以下接口同时提供在 <code>/v1</code> 下（如 <code>POST /v1/convert</code>），<code>/v1</code> 接口的错误响应为 <code>{\"error\": {\"code\", \"message\", \"details\", \"request_id\"}}</code>，请根据 <code>code</code> 判断错误类型。",
+	"index.lang_note":                "错误信息的语言可以通过 <code>Accept-Language</code> 请求头或 <code>lang</code> 查询参数（<code>zh-CN</code>、<code>en</code>）选择，错误码不随语言变化。",
+	"index.openapi_note":             "机器可读的接口描述见 <a href=\"/openapi.json\">/openapi.json</a>（OpenAPI 3），也可以在 <a href=\"/docs\">/docs</a> 页面直接调试接口。",
+	"index.convert_api":              "1. 文档转换 API",
+	"index.convert_description":      "将上传的文档转换为指定格式",
+	"index.endpoint":                 "<strong>接口</strong>",
+	"index.description":              "<strong>说明</strong>",
+	"index.request_params":           "<strong>请求参数</strong>",
+	"index.supported_formats":        "<strong>支持的格式</strong>",
+	"index.notes":                    "<strong>注意事项</strong>",
+	"index.example_response":         "<strong>响应示例</strong>",
+	"index.param_name":               "<th>参数名</th>",
+	"index.param_type":               "<th>类型</th>",
+	"index.param_required":           "<th>必填</th>",
+	"index.param_description":        "<th>说明</th>",
+	"index.yes":                      "<td>是</td>",
+	"index.no":                       "<td>否</td>",
+	"index.param_file":               "要转换的文档文件",
+	"index.param_format":             "目标格式，默认为txt",
+	"index.param_ttl":                "文件保存时间（分钟），只能比全局过期时间FILE_EXPIRY_HOURS更短",
+	"index.format_text":              "文本格式:",
+	"index.format_pdf":               "PDF格式:",
+	"index.format_word":              "Word格式:",
+	"index.format_excel":             "Excel格式:",
+	"index.format_html":              "HTML格式:",
+	"index.format_more":              "更多格式参考LibreOffice文档",
+	"index.note_capability":          "并非所有格式都可以互相转换，转换能力取决于LibreOffice的支持情况",
+	"index.note_fidelity":            "PDF转Word等复杂转换可能无法保留原始格式",
+	"index.note_errors":              "转换失败时会返回详细的错误信息",
+	"index.note_detection":           "服务会根据文件内容识别实际格式（<code>detected_format</code>），扩展名与内容不符时默认按实际格式转换（<code>format_corrected</code>），无法识别或不支持的内容返回415",
+	"index.note_duration":            "大文件转换可能需要较长时间",
+	"index.note_backup":              "建议在转换前备份原始文件",
+	"index.example_source":           "原始文件名",
+	"index.example_output":           "文件名_1701410000000",
+	"index.download_api":             "2. 文件下载 API",
+	"index.download_description":     "下载已转换的文件",
+	"index.param_filename":           "文件路径，格式为 日期/文件名，如：20231201/example_123456789.pdf",
+	"index.param_expires":            "下载链接过期时间（Unix时间戳），由转换接口返回的download_url携带",
+	"index.param_signature":          "下载链接签名，开启REQUIRE_SIGNED_DOWNLOADS后为必填",
+	"index.health_api":               "3. 健康检查 API",
+	"index.health_description":       "检查服务健康状态",
+	"index.livez_description":        "存活检查，进程能够处理请求时返回200",
+	"index.readyz_description":       "就绪检查，包括定期执行的金丝雀转换、TMP_DIR和DATA_DIR是否可写、磁盘剩余空间以及转换槽位和排队情况，任一检查未通过时返回503",
+	"index.files_api":                "4. 文件管理 API",
+	"index.files_list_description":   "分页列出当前请求方转换生成的文件",
+	"index.or":                       "</code>、<code>",
+	"index.files_info_description":   "查询文件大小、MIME类型、创建时间和过期时间，HEAD请求通过X-File-*响应头返回",
+	"index.files_delete_description": "立即删除已转换的文件。仅文件创建者，或携带该文件下载链接中expires和signature参数的请求可以访问",
+	"index.try_it":                   "转换测试",
+	"index.choose_file":              "选择文件:",
+	"index.target_format":            "转换格式:",
+	"index.option_txt":               "文本 (txt)",
+	"index.option_doc":               "Word 97-2003 文档 (doc)",
+	"index.option_docx":              "Word 文档 (docx)",
+	"index.option_odt":               "OpenDocument 文本 (odt)",
+	"index.option_rtf":               "富文本格式 (rtf)",
+	"index.option_xls":               "Excel 97-2003 表格 (xls)",
+	"index.option_xlsx":              "Excel 表格 (xlsx)",
+	"index.option_ods":               "OpenDocument 表格 (ods)",
+	"index.option_csv":               "CSV 表格 (csv)",
+	"index.option_ppt":               "PowerPoint 97-2003 演示文稿 (ppt)",
+	"index.option_pptx":              "PowerPoint 演示文稿 (pptx)",
+	"index.option_odp":               "OpenDocument 演示文稿 (odp)",
+	"index.option_html":              "HTML 网页 (html)",
+	"index.start":                    "开始转换",
+	"index.result":                   "转换结果",
+	"index.js_choose_file":           "请选择要转换的文件",
+	"index.js_converting":            "正在转换，请稍候...",
+	"index.js_success":               "转换成功!",
+	"index.js_source":                "原始文件: ",
+	"index.js_format":                "格式: ",
+	"index.js_expiry":                "过期时间: ",
+	"index.js_download":              "下载文件",
+	"index.js_preview":               "文件内容预览:",
+	"index.js_failed":                "转换失败: ",
+	"index.js_details":               "详情: ",
+	"index.js_request_failed":        "请求失败: ",
+
+	// 接口调试页面
+	"docs.title":             "接口调试",
+	"docs.intro":             "根据 <a href=\"/openapi.json\">/openapi.json</a> 生成，可以直接发送请求调试接口。",
+	"docs.auth_note":         "启用认证时填写API密钥或访问令牌，只保存在当前浏览器标签页中。",
+	"docs.loading":           "正在加载接口文档...",
+	"docs.param_name":        "参数",
+	"docs.param_in":          "位置",
+	"docs.param_value":       "值",
+	"docs.param_description": "说明",
+	"docs.send":              "发送请求",
+	"docs.sending":           "正在请求...",
+	"docs.missing":           "缺少必填参数: ",
+	"docs.response":          "响应",
+	"docs.download":          "下载响应内容",
+	"docs.request_failed":    "请求失败: ",
+}
+
+// messagesEn 英文消息目录
+var messagesEn = map[string]string{
+	// 错误信息
+	"error.file_not_found":                  "File not found",
+	"error.file_access":                     "Failed to access the file",
+	"error.conversion_failed":               "Conversion failed",
+	"error.forbidden":                       "Permission denied",
+	"error.invalid_path":                    "Invalid file path",
+	"error.invalid_path_format":             "Invalid file path format",
+	"error.path_missing":                    "No file path given",
+	"error.file_too_large":                  "File too large",
+	"error.read_upload":                     "Failed to read the uploaded file: %v",
+	"error.create_work_dir":                 "Failed to create the work directory: %v",
+	"error.create_file":                     "Failed to create the file: %v",
+	"error.save_upload":                     "Failed to save the file: %v",
+	"error.read_work_dir":                   "Failed to read the work directory: %v",
+	"error.save_output":                     "Failed to save the file",
+	"error.reload_keys":                     "Failed to reload API keys",
+	"error.unauthenticated":                 "Missing API key or access token",
+	"error.invalid_api_key":                 "Invalid API key",
+	"error.invalid_token":                   "Invalid access token",
+	"error.input_format_not_allowed":        "The tenant does not allow this input format",
+	"error.output_format_not_allowed":       "The tenant does not allow this output format",
+	"error.insufficient_storage":            "Not enough disk space, conversions are temporarily unavailable",
+	"error.missing_file":                    "No file was uploaded",
+	"error.empty_filename":                  "The file name is empty",
+	"error.audit_query":                     "Failed to query the audit log",
+	"error.audit_disabled":                  "The audit log is not enabled",
+	"error.audit_query_requires_auth":       "Querying the audit log requires authentication to be enabled",
+	"error.file_management_requires_auth":   "Listing and deleting files requires authentication to be enabled",
+	"error.invalid_time":                    "Invalid time parameter",
+	"error.internal":                        "Internal server error",
+	"error.invalid_ttl":                     "Invalid file retention time",
+	"error.archive_limit":                   "The file exceeds the archive safety limits",
+	"error.file_expired":                    "The file has expired",
+	"error.content_mismatch":                "The file content does not match its format",
+	"error.route_not_found":                 "No such endpoint",
+	"error.delete_file":                     "Failed to delete the file",
+	"error.list_files":                      "Failed to list files",
+	"error.unsupported_input_format":        "Unsupported input file format",
+	"error.unsupported_output_format":       "Unsupported output format",
+	"error.output_missing":                  "The converted file was not found",
+	"error.service_busy":                    "The service is busy, please retry later",
+	"error.conversion_timeout":              "The conversion timed out",
+	"error.resource_limit":                  "The conversion exceeded its resource limits",
+	"error.libreoffice_unavailable":         "LibreOffice is not installed or misconfigured",
+	"error.signature_missing":               "The download link is not signed",
+	"error.signature_invalid":               "The download link signature is invalid",
+	"error.signature_expired":               "The download link has expired",
+	"error.rate_limited":                    "Too many requests",
+	"error.daily_conversion_quota_exceeded": "Daily conversion quota exceeded",
+	"error.daily_upload_quota_exceeded":     "Daily upload volume quota exceeded",
+
+	// 错误详情，可以包含格式化参数
+	"detail.file_too_large":            "The uploaded file must not exceed %d bytes",
+	"detail.insufficient_scope":        "The %s scope is required",
+	"detail.conversion_timeout":        "The conversion did not finish within %d seconds",
+	"detail.retry_after":               "Retry in %d seconds",
+	"detail.output_format_not_allowed": "Tenant %s may not convert to %s",
+	"detail.input_format_not_allowed":  "Tenant %s may not convert %s files",
+	"detail.save_output":               "Could not copy the file to its final location: %v",
+	"detail.prepare_profile":           "Failed to prepare the LibreOffice user profile: %v",
+	"detail.unsupported_output_format": "Conversion to %s is not supported",
+	"detail.unsupported_input_format":  "Conversion from %s is not supported",
+	"detail.libreoffice_error":         "LibreOffice reported an error: %s",
+	"detail.invalid_time":              "%s must be in RFC3339 format, e.g. 2006-01-02T15:04:05Z",
+	"detail.other_tenant_audit":        "The audit log of other tenants cannot be queried",
+	"detail.output_missing":            "No output file ending in .%s was found in the work directory. Possible causes:\n1. The file cannot be converted to the target format\n2. The file may be corrupt or incompatible\n3. LibreOffice did not perform the conversion\n",
+	"detail.invalid_ttl":               "ttl_minutes must be a positive integer: %s",
+	"detail.ttl_too_long":              "ttl_minutes must not exceed the file expiry of %d minutes",
+	"detail.low_disk_space":            "%s has %d bytes free, below the threshold of %d bytes",
+	"detail.unknown_tenant":            "Unknown tenant: %s",
+	"detail.content_unrecognized":      "The file content could not be recognized, the file extension is %s",
+	"detail.content_unsupported":       "The file content is in %s format, which cannot be converted",
+	"detail.content_mismatch":          "The file extension is %s, but the content is in %s format",
+	"detail.archive_invalid":           "The archive could not be parsed: %v",
+	"detail.archive_entry_unreadable":  "Entry %s could not be read: %v",
+	"detail.archive_too_many_entries":  "The archive has more than %d entries",
+	"detail.archive_too_large":         "The archive exceeds %d bytes when uncompressed",
+	"detail.archive_nested_too_large":  "The nested archive %s exceeds %d bytes",
+	"detail.archive_total_ratio":       "The overall compression ratio of the archive exceeds %d",
+	"detail.archive_entry_ratio":       "The compression ratio of entry %s exceeds %d",
+	"detail.archive_nesting":           "Archives are nested more than %d levels deep: %s",
+
+	// 响应中的其他文本
+	"misc.never_expires": "never",
+
+	// 首页，可以包含HTML
+	"index.footer":                   "LibreOffice Document Conversion API | Go edition | Converts between many document formats",
+	"index.title":                    "LibreOffice Document Conversion API",
+	"index.intro":                    "A document conversion API service written in Go that converts documents between many formats.",
+	"index.env_debug":                "Debug mode",
+	"index.env_max_content_length":   "Maximum upload size",
+	"index.env_soffice_path":         "LibreOffice executable path",
+	"index.env_file_expiry_hours":    "File expiry (hours)",
+	"index.env_port":                 "Service port",
+	"index.env_vars":                 "Environment variables",
+	"index.api_doc":                  "API Documentation",
+	"index.auth_note":                "If API key authentication is enabled, provide the key in the <code>X-API-Key</code> header or as <code>Authorization: Bearer</code>.",
+	"index.v1_note":                  "The endpoints below are also available under <code>/v1</code> (e.g. <code>POST /v1/convert</code>). Errors from <code>/v1</code> endpoints look like <code>{\"error\": {\"code\", \"message\", \"details\", \"request_id\"}}</code>; use <code>code</code> to tell errors apart.",
+	"index.lang_note":                "Choose the language of error messages with the <code>Accept-Language</code> header or the <code>lang</code> query parameter (<code>zh-CN</code>, <code>en</code>). Error codes are the same in every language.",
+	"index.openapi_note":             "A machine-readable description is available at <a href=\"/openapi.json\">/openapi.json</a> (OpenAPI 3), and you can try the API on the <a href=\"/docs\">/docs</a> page.",
+	"index.convert_api":              "1. Document Conversion API",
+	"index.convert_description":      "Converts the uploaded document to the requested format",
+	"index.endpoint":                 "<strong>Endpoint</strong>",
+	"index.description":              "<strong>Description</strong>",
+	"index.request_params":           "<strong>Parameters</strong>",
+	"index.supported_formats":        "<strong>Supported formats</strong>",
+	"index.notes":                    "<strong>Notes</strong>",
+	"index.example_response":         "<strong>Example response</strong>",
+	"index.param_name":               "<th>Name</th>",
+	"index.param_type":               "<th>Type</th>",
+	"index.param_required":           "<th>Required</th>",
+	"index.param_description":        "<th>Description</th>",
+	"index.yes":                      "<td>Yes</td>",
+	"index.no":                       "<td>No</td>",
+	"index.param_file":               "The document to convert",
+	"index.param_format":             "Target format, txt by default",
+	"index.param_ttl":                "How long to keep the converted file (minutes); can only be shorter than the global FILE_EXPIRY_HOURS",
+	"index.format_text":              "Text:",
+	"index.format_pdf":               "PDF:",
+	"index.format_word":              "Word:",
+	"index.format_excel":             "Excel:",
+	"index.format_html":              "HTML:",
+	"index.format_more":              "See the LibreOffice documentation for more formats",
+	"index.note_capability":          "Not every format can be converted to every other format; it depends on what LibreOffice supports",
+	"index.note_fidelity":            "Complex conversions such as PDF to Word may not preserve the original layout",
+	"index.note_errors":              "Failed conversions return detailed error information",
+	"index.note_detection":           "The service detects the actual format from the file content (<code>detected_format</code>). When the extension does not match the content, the detected format is used by default (<code>format_corrected</code>); unrecognized or unsupported content returns 415",
+	"index.note_duration":            "Large files may take a while to convert",
+	"index.note_backup":              "Keep a backup of the original file before converting",
+	"index.example_source":           "original",
+	"index.example_output":           "original_1701410000000",
+	"index.download_api":             "2. File Download API",
+	"index.download_description":     "Downloads a converted file",
+	"index.param_filename":           "File path in the form date/filename, e.g. 20231201/example_123456789.pdf",
+	"index.param_expires":            "Expiry of the download link (Unix timestamp), included in the download_url returned by the conversion API",
+	"index.param_signature":          "Download link signature, required when REQUIRE_SIGNED_DOWNLOADS is enabled",
+	"index.health_api":               "3. Health Check API",
+	"index.health_description":       "Reports the health of the service",
+	"index.livez_description":        "Liveness check; returns 200 while the process can serve requests",
+	"index.readyz_description":       "Readiness check covering a periodic canary conversion, whether TMP_DIR and DATA_DIR are writable, free disk space, and conversion slot and queue state; returns 503 when any check fails",
+	"index.files_api":                "4. File Management API",
+	"index.files_list_description":   "Lists, page by page, the files converted by the caller",
+	"index.or":                       "</code>, <code>",
+	"index.files_info_description":   "Returns the file size, MIME type, creation time and expiry; HEAD requests return them in X-File-* response headers",
+	"index.files_delete_description": "Deletes a converted file immediately. Only the creator of the file, or a request carrying the expires and signature parameters of its download link, may do this",
+	"index.try_it":                   "Try It",
+	"index.choose_file":              "File:",
+	"index.target_format":            "Target format:",
+	"index.option_txt":               "Text (txt)",
+	"index.option_doc":               "Word 97-2003 document (doc)",
+	"index.option_docx":              "Word document (docx)",
+	"index.option_odt":               "OpenDocument text (odt)",
+	"index.option_rtf":               "Rich Text Format (rtf)",
+	"index.option_xls":               "Excel 97-2003 spreadsheet (xls)",
+	"index.option_xlsx":              "Excel spreadsheet (xlsx)",
+	"index.option_ods":               "OpenDocument spreadsheet (ods)",
+	"index.option_csv":               "CSV spreadsheet (csv)",
+	"index.option_ppt":               "PowerPoint 97-2003 presentation (ppt)",
+	"index.option_pptx":              "PowerPoint presentation (pptx)",
+	"index.option_odp":               "OpenDocument presentation (odp)",
+	"index.option_html":              "HTML web page (html)",
+	"index.start":                    "Convert",
+	"index.result":                   "Result",
+	"index.js_choose_file":           "Please choose a file to convert",
+	"index.js_converting":            "Converting, please wait...",
+	"index.js_success":               "Converted!",
+	"index.js_source":                "Original file: ",
+	"index.js_format":                "Format: ",
+	"index.js_expiry":                "Expires: ",
+	"index.js_download":              "Download",
+	"index.js_preview":               "Preview:",
+	"index.js_failed":                "Conversion failed: ",
+	"index.js_details":               "Details: ",
+	"index.js_request_failed":        "Request failed: ",
+
+	// 接口调试页面
+	"docs.title":             "API Explorer",
+	"docs.intro":             "Generated from <a href=\"/openapi.json\">/openapi.json</a>; send requests to try the API directly.",
+	"docs.auth_note":         "When authentication is enabled, enter an API key or access token. They are kept only in this browser tab.",
+	"docs.loading":           "Loading the API description...",
+	"docs.param_name":        "Parameter",
+	"docs.param_in":          "In",
+	"docs.param_value":       "Value",
+	"docs.param_description": "Description",
+	"docs.send":              "Send",
+	"docs.sending":           "Sending...",
+	"docs.missing":           "Missing required parameters: ",
+	"docs.response":          "Response",
+	"docs.download":          "Download response",
+	"docs.request_failed":    "Request failed: ",
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 元数据文件后缀，与转换结果保存在同一目录
+const metadataSuffix = ".meta.json"
+
+// FileMetadata 转换结果的附加信息，以JSON文件保存在结果文件旁边
+type FileMetadata struct {
+	OriginalFilename string     `json:"original_filename"`
+	SourceFormat     string     `json:"source_format"`
+	TargetFormat     string     `json:"target_format"`
+	Options          string     `json:"options,omitempty"` // LibreOffice过滤器参数，如 writer_pdf_Export
+	Creator          string     `json:"creator"`
+	CreatedAt        time.Time  `json:"created_at"`
+	ExpiresAt        *time.Time `json:"expires_at"` // 为空表示永不过期
+}
+
+// metadataPath 返回转换结果对应的元数据文件路径
+func metadataPath(relativePath string) string {
+	return relativePath + metadataSuffix
+}
+
+// isMetadataPath 判断路径是否为元数据文件
+func isMetadataPath(relativePath string) bool {
+	return strings.HasSuffix(relativePath, metadataSuffix)
+}
+
+// saveFileMetadata 保存转换结果的元数据
+func saveFileMetadata(ctx context.Context, relativePath string, meta FileMetadata) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("序列化元数据失败: %w", err)
+	}
+	if err := fileStorage.SaveData(ctx, metadataPath(relativePath), data); err != nil {
+		return err
+	}
+	recordStoredFile(StoredFile{Path: metadataPath(relativePath), Size: int64(len(data)), ModTime: time.Now()})
+	return nil
+}
+
+// loadFileMetadata 读取转换结果的元数据，不存在时返回ErrFileNotFound
+func loadFileMetadata(ctx context.Context, relativePath string) (FileMetadata, error) {
+	var meta FileMetadata
+	reader, err := fileStorage.Open(ctx, metadataPath(relativePath))
+	if err != nil {
+		return meta, err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return meta, err
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, fmt.Errorf("解析元数据失败: %w", err)
+	}
+	return meta, nil
+}
+
+// computeExpiresAt 根据过期时间（小时）和请求的TTL计算过期时间，返回nil表示永不过期
+func computeExpiresAt(now time.Time, expiryHours, ttlMinutes int) *time.Time {
+	var expiresAt time.Time
+	switch {
+	case ttlMinutes > 0:
+		expiresAt = now.Add(time.Duration(ttlMinutes) * time.Minute)
+	case expiryHours > 0:
+		expiresAt = now.Add(time.Duration(expiryHours) * time.Hour)
+	default:
+		return nil
+	}
+	return &expiresAt
+}
+
+// parseTTLMinutes 解析请求中的ttl_minutes参数，只允许比过期时间（小时）更短
+func parseTTLMinutes(value string, expiryHours int) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	ttl, err := strconv.Atoi(value)
+	if err != nil || ttl <= 0 {
+		return 0, newLocalizedError("detail.invalid_ttl", value)
+	}
+	if expiryHours > 0 && ttl > expiryHours*60 {
+		return 0, newLocalizedError("detail.ttl_too_long", expiryHours*60)
+	}
+	return ttl, nil
+}
+
+// storedFileExpiry 返回文件的过期时间，nil表示永不过期
+// 优先使用元数据中记录的过期时间，没有元数据的旧文件按修改时间和所属租户的过期时间计算
+func storedFileExpiry(ctx context.Context, file StoredFile) (*time.Time, error) {
+	meta, err := loadFileMetadata(ctx, file.Path)
+	if err == nil {
+		return meta.ExpiresAt, nil
+	}
+	if !errors.Is(err, ErrFileNotFound) {
+		return nil, err
+	}
+	expiryHours := tenantOfPath(file.Path).fileExpiryHours()
+	if expiryHours <= 0 {
+		return nil, nil
+	}
+	expiresAt := file.ModTime.Add(time.Duration(expiryHours) * time.Hour)
+	return &expiresAt, nil
+}
+
+// isExpired 判断过期时间是否已过
+func isExpired(expiresAt *time.Time, now time.Time) bool {
+	return expiresAt != nil && now.After(*expiresAt)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// 指标名称前缀
+const metricsNamespace = "libreoffice_api_"
+
+// 指标配置
+var METRICS_ENABLED bool
+
+// 直方图的桶上限
+var (
+	durationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+	sizeBuckets     = []float64{1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20, 64 << 20, 256 << 20, 1 << 30}
+)
+
+// 指标注册表，只包含本服务的指标
+var metricsRegistry = prometheus.NewRegistry()
+
+// 服务暴露的指标
+var (
+	conversionsTotal   = newCounterVec("conversions_total", "转换请求数", "source_format", "target_format", "outcome")
+	conversionDuration = newHistogramVec("conversion_duration_seconds", "soffice执行转换的耗时", durationBuckets, "source_format", "target_format")
+	queueWaitDuration  = newHistogram("queue_wait_seconds", "等待空闲转换槽位的耗时", durationBuckets)
+	uploadSize         = newHistogram("upload_size_bytes", "上传文件的大小", sizeBuckets)
+	outputSize         = newHistogramVec("output_size_bytes", "转换结果的大小", sizeBuckets, "target_format")
+	downloadsTotal     = newCounterVec("downloads_total", "下载请求数", "outcome")
+	downloadBytesTotal = newCounter("download_bytes_total", "成功下载的文件总大小")
+
+	cleanupRunsTotal         = newCounter("cleanup_runs_total", "过期文件清理任务的执行次数")
+	cleanupDeletedFilesTotal = newCounter("cleanup_deleted_files_total", "清理任务删除的过期文件数（包括元数据文件）")
+	cleanupErrorsTotal       = newCounter("cleanup_errors_total", "清理任务中删除文件失败的次数")
+	cleanupDuration          = newHistogram("cleanup_duration_seconds", "过期文件清理任务的耗时", durationBuckets)
+	cleanupLastRun           = newGauge("cleanup_last_run_timestamp_seconds", "最近一次清理任务完成的时间")
+	quotaEvictedFilesTotal   = newCounter("quota_evicted_files_total", "因超出存储配额删除的文件数")
+
+	conversionsActive = newGaugeFunc("conversions_active", "正在运行的soffice转换进程数", func() float64 {
+		active, _ := conversionPool.stats()
+		return float64(active)
+	})
+	conversionsQueued = newGaugeFunc("conversions_queued", "等待空闲转换槽位的请求数", func() float64 {
+		_, queued := conversionPool.stats()
+		return float64(queued)
+	})
+	storageBytes = newGaugeFunc("storage_bytes", "存储中所有文件的总大小", func() float64 {
+		return float64(currentStorageUsage().Bytes)
+	})
+	storageFiles = newGaugeFunc("storage_files", "存储中的转换结果文件数", func() float64 {
+		return float64(currentStorageUsage().Files)
+	})
+	storageScanTime = newGaugeFunc("storage_scan_timestamp_seconds", "最近一次扫描存储的时间", func() float64 {
+		return unixSeconds(currentStorageUsage().ScannedAt)
+	})
+)
+
+// initMetricsConfig 读取指标相关的环境变量
+func initMetricsConfig() {
+	METRICS_ENABLED = getEnvBool("METRICS_ENABLED", true)
+	if METRICS_ENABLED {
+		log.Println("已启用Prometheus指标: /metrics，启用认证时需要admin权限")
+	}
+}
+
+// 以下函数创建带有服务前缀的指标并登记到metricsRegistry
+
+func newCounter(name, help string) prometheus.Counter {
+	c := prometheus.NewCounter(prometheus.CounterOpts{Name: metricsNamespace + name, Help: help})
+	metricsRegistry.MustRegister(c)
+	return c
+}
+
+func newCounterVec(name, help string, labels ...string) *prometheus.CounterVec {
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{Name: metricsNamespace + name, Help: help}, labels)
+	metricsRegistry.MustRegister(c)
+	return c
+}
+
+func newGauge(name, help string) prometheus.Gauge {
+	g := prometheus.NewGauge(prometheus.GaugeOpts{Name: metricsNamespace + name, Help: help})
+	metricsRegistry.MustRegister(g)
+	return g
+}
+
+// newGaugeFunc 创建在采集时才计算值的仪表
+func newGaugeFunc(name, help string, fn func() float64) prometheus.GaugeFunc {
+	g := prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: metricsNamespace + name, Help: help}, fn)
+	metricsRegistry.MustRegister(g)
+	return g
+}
+
+func newHistogram(name, help string, buckets []float64) prometheus.Histogram {
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{Name: metricsNamespace + name, Help: help, Buckets: buckets})
+	metricsRegistry.MustRegister(h)
+	return h
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+	h := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: metricsNamespace + name, Help: help, Buckets: buckets}, labels)
+	metricsRegistry.MustRegister(h)
+	return h
+}
+
+// unixSeconds 返回时间对应的Unix时间戳（秒），零值返回0
+func unixSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / 1e9
+}
+
+// formatLabel 将格式转换为指标标签值，不支持的格式统一记为other，避免客户端提交的任意扩展名产生大量时间序列
+func formatLabel(format string, valid func(string) bool) string {
+	switch {
+	case format == "":
+		return "unknown"
+	case valid(format):
+		return format
+	}
+	return "other"
+}
+
+func inputFormatLabel(format string) string {
+	return formatLabel(format, func(f string) bool { return isValidInputFormat("." + f) })
+}
+
+func outputFormatLabel(format string) string {
+	return formatLabel(format, isValidOutputFormat)
+}
+
+// recordRequestMetrics 根据请求结束时的审计事件更新转换和下载指标
+func recordRequestMetrics(event *AuditEvent) {
+	switch event.Action {
+	case AuditConvert:
+		target := outputFormatLabel(event.TargetFormat)
+		conversionsTotal.WithLabelValues(inputFormatLabel(event.SourceFormat), target, event.Outcome).Inc()
+		if event.InputBytes > 0 {
+			uploadSize.Observe(float64(event.InputBytes))
+		}
+		if event.Outcome == AuditSuccess {
+			outputSize.WithLabelValues(target).Observe(float64(event.OutputBytes))
+		}
+	case AuditDownload:
+		downloadsTotal.WithLabelValues(event.Outcome).Inc()
+		if event.Outcome == AuditSuccess {
+			downloadBytesTotal.Add(float64(event.OutputBytes))
+		}
+	}
+}
+
+// recordCleanupMetrics 记录一次过期文件清理任务的结果
+func recordCleanupMetrics(start time.Time, deleted, failed int) {
+	cleanupRunsTotal.Inc()
+	cleanupDeletedFilesTotal.Add(float64(deleted))
+	cleanupErrorsTotal.Add(float64(failed))
+	cleanupDuration.Observe(time.Since(start).Seconds())
+	cleanupLastRun.Set(unixSeconds(time.Now()))
+}
+
+// metricsHandler 以Prometheus文本格式输出指标
+var metricsHandler = gin.WrapH(promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scrapeMetrics 返回/metrics的输出
+func scrapeMetrics(t *testing.T) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/metrics", metricsHandler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("/metrics返回 %d", w.Code)
+	}
+	body, _ := io.ReadAll(w.Body)
+	return string(body)
+}
+
+func TestRecordRequestMetrics(t *testing.T) {
+	saved := conversionPool
+	conversionPool = &workerPool{global: make(chan struct{}, 1), tenants: map[string]chan struct{}{}}
+	t.Cleanup(func() { conversionPool = saved })
+
+	recordRequestMetrics(&AuditEvent{Action: AuditConvert, SourceFormat: "docx", TargetFormat: "pdf", InputBytes: 2048, OutputBytes: 1024, Outcome: AuditSuccess})
+	recordRequestMetrics(&AuditEvent{Action: AuditConvert, SourceFormat: "exe", TargetFormat: "pdf", Outcome: AuditRejected})
+	recordRequestMetrics(&AuditEvent{Action: AuditDownload, OutputBytes: 1024, Outcome: AuditSuccess})
+
+	out := scrapeMetrics(t)
+	for _, want := range []string{
+		`libreoffice_api_conversions_total{outcome="success",source_format="docx",target_format="pdf"}`,
+		// 不支持的格式统一记为other
+		`libreoffice_api_conversions_total{outcome="rejected",source_format="other",target_format="pdf"}`,
+		`libreoffice_api_output_size_bytes_bucket{target_format="pdf",le="1024"}`,
+		`libreoffice_api_downloads_total{outcome="success"}`,
+		"# TYPE libreoffice_api_upload_size_bytes histogram",
+		// 没有标签的指标和按需计算的仪表在没有数据时也输出
+		"libreoffice_api_cleanup_runs_total ",
+		"libreoffice_api_conversions_active ",
+		"libreoffice_api_storage_bytes ",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("指标输出中缺少 %s", want)
+		}
+	}
+}
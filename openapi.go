@@ -0,0 +1,466 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPI文档的版本
+const openAPIVersion = "3.0.3"
+
+// docsPageTemplate 接口调试页面，根据/openapi.json生成请求表单
+//
+//go:embed docs.html
+var docsPageTemplate string
+
+// 启动时生成的OpenAPI文档
+var openAPISpec []byte
+
+// apiOperation 一个接口及其在gin中注册的路由，用于检查文档与实际路由是否一致
+type apiOperation struct {
+	Method    string
+	Route     string // gin路由，如/download/*filename
+	Path      string // OpenAPI路径，如/download/{filename}
+	Operation gin.H
+}
+
+// ginPathParam 匹配gin路由中的:name和*name参数
+var ginPathParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// openAPIPath 将gin路由转换为OpenAPI路径
+func openAPIPath(route string) string {
+	return ginPathParam.ReplaceAllString(route, "{$1}")
+}
+
+// errorCodes 可能返回的错误码，用于错误响应中code字段的枚举
+var errorCodes = []string{
+	CodeBadRequest, CodeInternalError, CodeNotFound, CodeServiceBusy, CodeInvalidParam, CodeStorageError, CodeFeatureDisabled,
+	CodeUnauthenticated, CodeInvalidAPIKey, CodeInvalidToken, CodeForbidden, CodeInsufficientScope,
+	CodeRateLimited, CodeDailyConversionQuota, CodeDailyUploadQuota,
+	CodeMissingFile, CodeFileTooLarge, CodeUnsupportedInputFormat, CodeUnsupportedOutputFormat, CodeContentMismatch,
+	CodeInputFormatNotAllowed, CodeOutputFormatNotAllowed, CodeInvalidTTL, CodeInsufficientStorage,
+	CodeLibreOfficeUnavailable, CodeQueueTimeout, CodeConversionTimeout, CodeConversionFailed,
+	CodeInvalidPath, CodeFileNotFound, CodeFileExpired, CodeSignatureMissing, CodeSignatureInvalid, CodeSignatureExpired,
+	CodeArchiveInvalid, CodeArchiveTooLarge, CodeArchiveRatio, CodeArchiveTooManyFiles, CodeArchiveNested,
+	CodeResourceLimit,
+}
+
+// 错误响应的说明
+var errorDescriptions = map[int]string{
+	http.StatusBadRequest:            "请求参数无效",
+	http.StatusUnauthorized:          "缺少或无效的API密钥、访问令牌",
+	http.StatusForbidden:             "权限不足、租户不允许或下载链接签名无效",
+	http.StatusNotFound:              "文件不存在",
+	http.StatusGone:                  "文件已过期",
+	http.StatusRequestEntityTooLarge: "上传文件过大",
+	http.StatusUnsupportedMediaType:  "文件内容与格式不符或无法识别",
+	http.StatusUnprocessableEntity:   "超出压缩包安全限制或soffice资源限制",
+	http.StatusTooManyRequests:       "超出限流或每日配额，Retry-After响应头给出重试等待时间",
+	http.StatusInternalServerError:   "转换失败或服务内部错误",
+	http.StatusServiceUnavailable:    "服务繁忙，等待转换槽位超时",
+	http.StatusGatewayTimeout:        "转换超过CONVERSION_TIMEOUT_SECONDS未完成，仅在设置了该变量时返回",
+	http.StatusInsufficientStorage:   "磁盘空间不足",
+}
+
+// apiOperations 返回转换、下载、文件管理和管理接口的文档，prefix为空时是旧接口，为/v1时是版本化接口
+func apiOperations(prefix string) []apiOperation {
+	errorSchema := "ErrorResponse"
+	tag := "legacy"
+	idSuffix := "Legacy"
+	if prefix == apiV1Prefix {
+		errorSchema = "APIErrorResponse"
+		tag = "v1"
+		idSuffix = "V1"
+	}
+	errorResponses := func(statuses ...int) gin.H {
+		responses := gin.H{}
+		for _, status := range statuses {
+			responses[strconv.Itoa(status)] = gin.H{
+				"description": errorDescriptions[status],
+				"content":     gin.H{"application/json": gin.H{"schema": schemaRef(errorSchema)}},
+			}
+		}
+		return responses
+	}
+	op := func(id, summary, description string, scope string, responses gin.H, extra gin.H) gin.H {
+		o := gin.H{
+			"operationId": id + idSuffix,
+			"summary":     summary,
+			"tags":        []string{tag},
+			"parameters":  []gin.H{paramRef("Lang"), paramRef("RequestID")},
+			"responses":   responses,
+		}
+		if description != "" {
+			o["description"] = description
+		}
+		if scope != "" {
+			o["security"] = operationSecurity()
+			o["description"] = strings.TrimSpace(description + "\n\n启用认证时需要`" + scope + "`权限。")
+		}
+		for k, v := range extra {
+			if k == "parameters" {
+				o[k] = append(o[k].([]gin.H), v.([]gin.H)...)
+				continue
+			}
+			o[k] = v
+		}
+		return o
+	}
+
+	targetFormats := make([]string, len(supportedOutputFormats))
+	copy(targetFormats, supportedOutputFormats)
+	inputFormats := make([]string, len(supportedInputFormats))
+	for i, ext := range supportedInputFormats {
+		inputFormats[i] = strings.TrimPrefix(ext, ".")
+	}
+
+	convert := op("convert", "转换文档",
+		"上传文档并转换为指定格式，转换结果保存在服务端，通过响应中的download_url下载。支持的输入格式: "+strings.Join(inputFormats, "、")+"。",
+		ScopeConvert,
+		mergeResponses(gin.H{"200": jsonResponse("转换成功", "ConversionResponse")},
+			errorResponses(400, 401, 403, 413, 415, 422, 429, 500, 503, 504, 507)),
+		gin.H{"requestBody": gin.H{
+			"required": true,
+			"content": gin.H{"multipart/form-data": gin.H{
+				"schema": gin.H{
+					"type":     "object",
+					"required": []string{"file"},
+					"properties": gin.H{
+						"file": gin.H{"type": "string", "format": "binary", "description": "要转换的文档文件，扩展名为" + strings.Join(inputFormats, "、") + "之一"},
+						"format": gin.H{
+							"description": "目标格式，可以附加LibreOffice导出过滤器，如`pdf:writer_pdf_Export`",
+							"default":     "txt",
+							"anyOf": []gin.H{
+								{"type": "string", "enum": targetFormats},
+								{"type": "string", "pattern": "^(" + strings.Join(targetFormats, "|") + "):.+$"},
+							},
+						},
+						"ttl_minutes": gin.H{"type": "integer", "minimum": 1, "description": "文件保存时间（分钟），只能比FILE_EXPIRY_HOURS更短"},
+					},
+				},
+			}},
+		}})
+
+	download := op("downloadFile", "下载转换结果", "", ScopeDownload,
+		mergeResponses(gin.H{"200": gin.H{
+			"description": "文件内容",
+			"content":     gin.H{"application/octet-stream": gin.H{"schema": gin.H{"type": "string", "format": "binary"}}},
+		}}, errorResponses(400, 401, 403, 404, 410, 500)),
+		gin.H{"parameters": []gin.H{
+			{"name": "filename", "in": "path", "required": true, "schema": gin.H{"type": "string"}, "description": "文件路径，格式为 日期/文件名，可以包含/", "example": "20231201/example_1701410000000.pdf"},
+			{"name": "expires", "in": "query", "schema": gin.H{"type": "integer", "format": "int64"}, "description": "下载链接过期时间（Unix时间戳），由download_url携带"},
+			{"name": "signature", "in": "query", "schema": gin.H{"type": "string"}, "description": "下载链接签名，开启REQUIRE_SIGNED_DOWNLOADS后为必填"},
+		}})
+
+	filePathParam := gin.H{"name": "path", "in": "path", "required": true, "schema": gin.H{"type": "string"}, "description": "文件路径，格式为 日期/文件名，可以包含/"}
+	listFiles := op("listFiles", "列出请求方转换生成的文件", "只列出请求方所在租户中由请求方创建的文件。未启用认证时无法识别请求方，返回403和feature_disabled。", ScopeDownload,
+		mergeResponses(gin.H{"200": jsonResponse("文件列表", "FileListResponse")}, errorResponses(401, 403, 500)),
+		gin.H{"parameters": []gin.H{
+			{"name": "page", "in": "query", "schema": gin.H{"type": "integer", "minimum": 1, "default": 1}},
+			{"name": "page_size", "in": "query", "schema": gin.H{"type": "integer", "minimum": 1, "default": 20}},
+			{"name": "prefix", "in": "query", "schema": gin.H{"type": "string"}, "description": "按路径前缀过滤，相对于请求方所在租户的存储路径，如日期20231201"},
+		}})
+	fileInfoDescription := "未启用认证时需要提供下载链接中的`expires`和`signature`查询参数。"
+	fileInfo := op("getFileInfo", "查询文件信息", fileInfoDescription, ScopeDownload,
+		mergeResponses(gin.H{"200": jsonResponse("文件信息", "FileInfoResponse")}, errorResponses(401, 403, 404, 500)),
+		gin.H{"parameters": []gin.H{filePathParam}})
+	headFileInfo := op("headFileInfo", "通过响应头查询文件信息", fileInfoDescription, ScopeDownload,
+		mergeResponses(gin.H{"200": gin.H{
+			"description": "文件信息",
+			"headers": gin.H{
+				"X-File-Size":       gin.H{"schema": gin.H{"type": "integer", "format": "int64"}},
+				"X-File-Mime-Type":  gin.H{"schema": gin.H{"type": "string"}},
+				"X-File-Created-At": gin.H{"schema": gin.H{"type": "string"}},
+				"X-File-Expiry":     gin.H{"schema": gin.H{"type": "string"}},
+			},
+		}}, errorResponses(401, 403, 404, 500)),
+		gin.H{"parameters": []gin.H{filePathParam}})
+	deleteFile := op("deleteFile", "删除文件", "仅文件创建者和拥有admin权限的请求方可以删除。未启用认证时无法识别请求方，返回403和feature_disabled。", ScopeDownload,
+		mergeResponses(gin.H{"200": jsonResponse("已删除", "DeleteFileResponse")}, errorResponses(401, 403, 404, 500)),
+		gin.H{"parameters": []gin.H{
+			filePathParam,
+			{"name": "expires", "in": "query", "schema": gin.H{"type": "integer", "format": "int64"}},
+			{"name": "signature", "in": "query", "schema": gin.H{"type": "string"}},
+		}})
+
+	reloadKeys := op("reloadAPIKeys", "重新加载API密钥", "", ScopeAdmin,
+		mergeResponses(gin.H{"200": jsonResponse("已重新加载", "ReloadKeysResponse")}, errorResponses(401, 403, 500)), nil)
+	queryAudit := op("queryAuditLog", "查询审计日志", "属于某个租户的管理员只能查询该租户的记录，结果按时间倒序。未启用认证时返回403。每次最多扫描10个日志文件，更早的记录需要通过`to`参数查询。", ScopeAdmin,
+		mergeResponses(gin.H{"200": jsonResponse("审计记录", "AuditQueryResponse")}, errorResponses(400, 401, 403, 404, 500)),
+		gin.H{"parameters": []gin.H{
+			{"name": "from", "in": "query", "schema": gin.H{"type": "string", "format": "date-time"}},
+			{"name": "to", "in": "query", "schema": gin.H{"type": "string", "format": "date-time"}},
+			{"name": "action", "in": "query", "schema": gin.H{"type": "string", "enum": []string{AuditConvert, AuditDownload}}},
+			{"name": "client", "in": "query", "schema": gin.H{"type": "string"}},
+			{"name": "tenant", "in": "query", "schema": gin.H{"type": "string"}},
+			{"name": "outcome", "in": "query", "schema": gin.H{"type": "string", "enum": []string{AuditSuccess, AuditRejected, AuditFailure}}},
+			{"name": "limit", "in": "query", "schema": gin.H{"type": "integer", "minimum": 1, "maximum": 1000, "default": 100}},
+		}})
+
+	ops := []apiOperation{
+		{Method: http.MethodPost, Route: "/convert", Operation: convert},
+		{Method: http.MethodGet, Route: "/download/*filename", Operation: download},
+		{Method: http.MethodGet, Route: "/files", Operation: listFiles},
+		{Method: http.MethodGet, Route: "/files/*path", Path: "/files/{path}/info", Operation: fileInfo},
+		{Method: http.MethodHead, Route: "/files/*path", Path: "/files/{path}/info", Operation: headFileInfo},
+		{Method: http.MethodDelete, Route: "/files/*path", Operation: deleteFile},
+		{Method: http.MethodPost, Route: "/admin/keys/reload", Operation: reloadKeys},
+		{Method: http.MethodGet, Route: "/admin/audit", Operation: queryAudit},
+	}
+	for i := range ops {
+		ops[i].Route = prefix + ops[i].Route
+		if ops[i].Path == "" {
+			ops[i].Path = openAPIPath(ops[i].Route)
+		} else {
+			ops[i].Path = prefix + ops[i].Path
+		}
+	}
+	return ops
+}
+
+// serviceOperations 返回不区分版本的服务接口的文档
+func serviceOperations() []apiOperation {
+	simple := func(id, summary string, responses gin.H) gin.H {
+		return gin.H{"operationId": id, "summary": summary, "tags": []string{"service"}, "responses": responses}
+	}
+	html := gin.H{"text/html": gin.H{"schema": gin.H{"type": "string"}}}
+	ops := []apiOperation{
+		{Method: http.MethodGet, Route: "/", Operation: simple("index", "首页和接口说明", gin.H{"200": gin.H{"description": "HTML页面", "content": html}})},
+		{Method: http.MethodGet, Route: "/health", Operation: simple("health", "健康检查（兼容旧版本）", gin.H{"200": jsonResponse("服务状态", "HealthResponse")})},
+		{Method: http.MethodGet, Route: "/livez", Operation: simple("livez", "存活检查", gin.H{"200": gin.H{
+			"description": "进程能够处理请求",
+			"content":     gin.H{"application/json": gin.H{"schema": gin.H{"type": "object", "properties": gin.H{"status": gin.H{"type": "string", "enum": []string{"ok"}}}}}},
+		}})},
+		{Method: http.MethodGet, Route: "/readyz", Operation: simple("readyz", "就绪检查", gin.H{
+			"200": jsonResponse("所有检查通过", "ReadinessResponse"),
+			"503": jsonResponse("存在未通过的检查", "ReadinessResponse"),
+		})},
+		{Method: http.MethodGet, Route: "/openapi.json", Operation: simple("openapi", "OpenAPI文档", gin.H{"200": gin.H{
+			"description": "本文档",
+			"content":     gin.H{"application/json": gin.H{"schema": gin.H{"type": "object"}}},
+		}})},
+		{Method: http.MethodGet, Route: "/docs", Operation: simple("docs", "接口调试页面", gin.H{"200": gin.H{"description": "HTML页面", "content": html}})},
+	}
+	if METRICS_ENABLED {
+		metrics := simple("metrics", "Prometheus指标", gin.H{
+			"200": gin.H{
+				"description": "Prometheus文本格式的指标",
+				"content":     gin.H{"text/plain": gin.H{"schema": gin.H{"type": "string"}}},
+			},
+			"401": jsonResponse(errorDescriptions[http.StatusUnauthorized], "ErrorResponse"),
+			"403": jsonResponse(errorDescriptions[http.StatusForbidden], "ErrorResponse"),
+		})
+		metrics["security"] = operationSecurity()
+		metrics["description"] = "启用认证时需要`" + ScopeAdmin + "`权限。"
+		ops = append(ops, apiOperation{Method: http.MethodGet, Route: "/metrics", Operation: metrics})
+	}
+	for i := range ops {
+		ops[i].Path = openAPIPath(ops[i].Route)
+	}
+	return ops
+}
+
+// documentedOperations 返回文档中的所有接口
+func documentedOperations() []apiOperation {
+	ops := serviceOperations()
+	ops = append(ops, apiOperations("")...)
+	ops = append(ops, apiOperations(apiV1Prefix)...)
+	return ops
+}
+
+// buildOpenAPISpec 生成OpenAPI文档，支持的格式、错误码和响应结构都取自代码，与实际行为保持一致
+func buildOpenAPISpec() gin.H {
+	paths := gin.H{}
+	for _, op := range documentedOperations() {
+		item, ok := paths[op.Path].(gin.H)
+		if !ok {
+			item = gin.H{}
+			paths[op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = op.Operation
+	}
+
+	schemas := gin.H{}
+	for _, v := range []interface{}{
+		ConversionResponse{}, ErrorResponse{}, APIErrorResponse{}, HealthResponse{}, ReadinessResponse{},
+		FileInfoResponse{}, FileListResponse{}, DeleteFileResponse{}, ReloadKeysResponse{}, AuditQueryResponse{},
+	} {
+		addSchema(schemas, reflect.TypeOf(v))
+	}
+	for _, name := range []string{"ErrorResponse", "APIError"} {
+		schemas[name].(gin.H)["properties"].(gin.H)["code"] = gin.H{"type": "string", "enum": errorCodes, "description": "错误码，不随语言变化"}
+	}
+
+	return gin.H{
+		"openapi": openAPIVersion,
+		"info": gin.H{
+			"title":       "LibreOffice文档转换API",
+			"version":     "1.0.0",
+			"description": "旧接口（无前缀）的错误响应为ErrorResponse，/v1接口的错误响应为APIErrorResponse。错误信息的语言通过lang参数或Accept-Language请求头选择。",
+		},
+		"tags": []gin.H{
+			{"name": "v1", "description": "版本化接口，新客户端应使用"},
+			{"name": "legacy", "description": "旧接口，保留用于兼容"},
+			{"name": "service", "description": "健康检查、监控和文档"},
+		},
+		"paths": paths,
+		"components": gin.H{
+			"schemas": schemas,
+			"parameters": gin.H{
+				"Lang": gin.H{"name": "lang", "in": "query", "description": "错误信息的语言，优先于Accept-Language请求头",
+					"schema": gin.H{"type": "string", "enum": []string{LangZhCN, LangEn}}},
+				"RequestID": gin.H{"name": requestIDHeader, "in": "header", "description": "请求ID，未提供时由服务生成，响应头中返回",
+					"schema": gin.H{"type": "string", "pattern": validRequestID.String()}},
+			},
+			"securitySchemes": gin.H{
+				"ApiKeyAuth": gin.H{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+				"BearerAuth": gin.H{"type": "http", "scheme": "bearer", "description": "API密钥或JWT访问令牌"},
+			},
+		},
+	}
+}
+
+// operationSecurity 返回需要认证的接口的安全要求，未启用认证时允许不提供凭据
+func operationSecurity() []gin.H {
+	security := []gin.H{{"ApiKeyAuth": []string{}}, {"BearerAuth": []string{}}}
+	if !apiKeysEnabled() && !jwtEnabled() {
+		security = append(security, gin.H{})
+	}
+	return security
+}
+
+// addSchema 根据结构体的json标签生成schema并登记到components中，返回引用
+func addSchema(schemas gin.H, t reflect.Type) gin.H {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return gin.H{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		return gin.H{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return gin.H{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		if t.Size() == 8 {
+			return gin.H{"type": "integer", "format": "int64"}
+		}
+		return gin.H{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return gin.H{"type": "number"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return gin.H{"type": "array", "items": addSchema(schemas, t.Elem())}
+	case t.Kind() == reflect.Map:
+		return gin.H{"type": "object", "additionalProperties": addSchema(schemas, t.Elem())}
+	case t.Kind() != reflect.Struct:
+		return gin.H{}
+	}
+
+	if _, ok := schemas[t.Name()]; ok {
+		return schemaRef(t.Name())
+	}
+	properties := gin.H{}
+	schema := gin.H{"type": "object", "properties": properties}
+	schemas[t.Name()] = schema
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = addSchema(schemas, field.Type)
+		if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			required = append(required, name)
+		}
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schemaRef(t.Name())
+}
+
+func schemaRef(name string) gin.H {
+	return gin.H{"$ref": "#/components/schemas/" + name}
+}
+
+func paramRef(name string) gin.H {
+	return gin.H{"$ref": "#/components/parameters/" + name}
+}
+
+func jsonResponse(description, schema string) gin.H {
+	return gin.H{
+		"description": description,
+		"content":     gin.H{"application/json": gin.H{"schema": schemaRef(schema)}},
+	}
+}
+
+func mergeResponses(a, b gin.H) gin.H {
+	for k, v := range b {
+		a[k] = v
+	}
+	return a
+}
+
+// initOpenAPI 生成OpenAPI文档，需要在读取配置之后调用
+func initOpenAPI() {
+	spec, err := json.Marshal(buildOpenAPISpec())
+	if err != nil {
+		log.Fatalf("生成OpenAPI文档失败: %v", err)
+	}
+	openAPISpec = spec
+}
+
+// checkOpenAPIRoutes 比较文档与实际注册的路由，返回不一致之处
+func checkOpenAPIRoutes(routes gin.RoutesInfo) []string {
+	documented := map[string]bool{}
+	for _, op := range documentedOperations() {
+		documented[op.Method+" "+op.Route] = true
+	}
+	registered := map[string]bool{}
+	var problems []string
+	for _, r := range routes {
+		key := r.Method + " " + r.Path
+		registered[key] = true
+		if !documented[key] {
+			problems = append(problems, "路由未写入OpenAPI文档: "+key)
+		}
+	}
+	for key := range documented {
+		if !registered[key] {
+			problems = append(problems, "OpenAPI文档中的接口未注册路由: "+key)
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// OpenAPI文档处理
+func openAPIHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openAPISpec)
+}
+
+// 接口调试页面处理
+func docsHandler(c *gin.Context) {
+	lang := requestLanguage(c)
+	page := localizeTemplate(docsPageTemplate, lang)
+	page = strings.ReplaceAll(page, "${LANG}", lang)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.String(http.StatusOK, page)
+}
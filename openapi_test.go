@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestOpenAPIRoutesMatchRouter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	saved := METRICS_ENABLED
+	t.Cleanup(func() { METRICS_ENABLED = saved })
+
+	for _, enabled := range []bool{true, false} {
+		METRICS_ENABLED = enabled
+		if problems := checkOpenAPIRoutes(newRouter().Routes()); len(problems) > 0 {
+			t.Errorf("METRICS_ENABLED=%v 时文档与路由不一致:\n%s", enabled, strings.Join(problems, "\n"))
+		}
+	}
+}
+
+// declaredErrorCodes 解析包中的源文件，返回所有以Code开头的字符串常量的值
+func declaredErrorCodes(t *testing.T) []string {
+	t.Helper()
+	paths, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	var codes []string
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatalf("解析%s失败: %v", path, err)
+		}
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					if !strings.HasPrefix(name.Name, "Code") || i >= len(vs.Values) {
+						continue
+					}
+					lit, ok := vs.Values[i].(*ast.BasicLit)
+					if !ok || lit.Kind != token.STRING {
+						continue
+					}
+					value, err := strconv.Unquote(lit.Value)
+					if err != nil {
+						t.Fatalf("无法解析常量%s: %v", name.Name, err)
+					}
+					codes = append(codes, value)
+				}
+			}
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+func TestOpenAPIErrorCodesMatchConstants(t *testing.T) {
+	want := declaredErrorCodes(t)
+	if len(want) == 0 {
+		t.Fatal("未找到错误码常量")
+	}
+
+	data, err := json.Marshal(buildOpenAPISpec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]struct {
+					Enum []string `json:"enum"`
+				} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"ErrorResponse", "APIError"} {
+		got := append([]string(nil), spec.Components.Schemas[name].Properties["code"].Enum...)
+		sort.Strings(got)
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s.code的枚举与错误码常量不一致\n文档: %v\n常量: %v", name, got, want)
+		}
+	}
+}
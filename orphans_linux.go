@@ -0,0 +1,105 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// killOrphanedSoffice 终止由本实例启动但对应转换已不在进行中的soffice进程
+// 通过命令行中的-env:UserInstallation参数识别，marker为该参数去掉转换ID后的前缀，其中包含实例标识；
+// startedBefore不为零时只终止在该时间之前启动的进程
+func killOrphanedSoffice(marker string, isActive func(id string) bool, startedBefore time.Time) []int {
+	procDirs, err := filepath.Glob("/proc/[0-9]*")
+	if err != nil {
+		return nil
+	}
+
+	self := os.Getpid()
+	var killed []int
+	for _, dir := range procDirs {
+		pid, err := strconv.Atoi(filepath.Base(dir))
+		if err != nil || pid == self {
+			continue
+		}
+		cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+		if err != nil {
+			continue
+		}
+		for _, arg := range bytes.Split(cmdline, []byte{0}) {
+			if !bytes.HasPrefix(arg, []byte(marker)) {
+				continue
+			}
+			id := strings.TrimPrefix(string(arg), marker)
+			if isActive(id) || !startedBefore.IsZero() && !processStartedBefore(dir, startedBefore) {
+				break
+			}
+			if syscall.Kill(pid, syscall.SIGKILL) == nil {
+				killed = append(killed, pid)
+			}
+			break
+		}
+	}
+	return killed
+}
+
+// userHZ /proc中以时钟周期表示的时间的单位，Linux上固定为100
+const userHZ = 100
+
+// processStartedBefore 判断进程是否在指定时间之前启动，无法确定时返回false
+func processStartedBefore(procDir string, t time.Time) bool {
+	start, err := processStartTime(procDir)
+	return err == nil && start.Before(t)
+}
+
+// processStartTime 根据/proc/<pid>/stat中的starttime（开机后的时钟周期数）和/proc/stat中的开机时间计算进程启动时间
+func processStartTime(procDir string) (time.Time, error) {
+	stat, err := os.ReadFile(filepath.Join(procDir, "stat"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	// 进程名可能包含空格和括号，从最后一个")"之后开始解析，starttime为第22个字段
+	end := bytes.LastIndexByte(stat, ')')
+	if end < 0 {
+		return time.Time{}, fmt.Errorf("无法解析%s/stat", procDir)
+	}
+	fields := strings.Fields(string(stat[end+1:]))
+	if len(fields) < 20 {
+		return time.Time{}, fmt.Errorf("无法解析%s/stat", procDir)
+	}
+	ticks, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	bootTime, err := systemBootTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return bootTime.Add(time.Duration(ticks) * time.Second / userHZ), nil
+}
+
+// systemBootTime 读取/proc/stat中的开机时间
+func systemBootTime() (time.Time, error) {
+	stat, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, line := range strings.Split(string(stat), "\n") {
+		if value, ok := strings.CutPrefix(line, "btime "); ok {
+			sec, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(sec, 0), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("/proc/stat中没有btime")
+}
@@ -0,0 +1,74 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// startFakeSoffice 启动一个命令行中带有用户配置参数的进程，模拟soffice
+func startFakeSoffice(t *testing.T, installationArg string) *exec.Cmd {
+	t.Helper()
+	// sh -c的第一个额外参数作为$0，会出现在进程的命令行中
+	cmd := exec.Command("sh", "-c", "sleep 60", installationArg)
+	if err := cmd.Start(); err != nil {
+		t.Skipf("无法启动测试进程: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	return cmd
+}
+
+func processAlive(cmd *exec.Cmd) bool {
+	return cmd.ProcessState == nil && syscall.Kill(cmd.Process.Pid, 0) == nil
+}
+
+func TestKillOrphanedSoffice(t *testing.T) {
+	withTestWorkDirs(t, fmt.Sprintf("test-%d", os.Getpid()))
+	marker := profileInstallationArg("")
+
+	orphan := startFakeSoffice(t, profileInstallationArg("orphan"))
+	active := startFakeSoffice(t, profileInstallationArg("active"))
+	savedInstance := INSTANCE_ID
+	INSTANCE_ID = savedInstance + "-other"
+	other := startFakeSoffice(t, profileInstallationArg("orphan"))
+	INSTANCE_ID = savedInstance
+
+	isActive := func(id string) bool { return id == "active" }
+
+	// 定期清理时刚启动的进程未超时，不终止
+	if killed := killOrphanedSoffice(marker, isActive, time.Now().Add(-time.Hour)); len(killed) != 0 {
+		t.Fatalf("不应终止未超时的进程，实际终止 %v", killed)
+	}
+
+	killed := killOrphanedSoffice(marker, isActive, time.Time{})
+	if len(killed) != 1 || killed[0] != orphan.Process.Pid {
+		t.Fatalf("应只终止本实例的遗留进程 %d，实际终止 %v", orphan.Process.Pid, killed)
+	}
+	orphan.Wait()
+	if !processAlive(active) {
+		t.Error("进行中的转换不应被终止")
+	}
+	if !processAlive(other) {
+		t.Error("其他实例的进程不应被终止")
+	}
+}
+
+func TestProcessStartTime(t *testing.T) {
+	cmd := startFakeSoffice(t, "-env:UserInstallation=file:///tmp/unused")
+	start, err := processStartTime(fmt.Sprintf("/proc/%d", cmd.Process.Pid))
+	if err != nil {
+		t.Fatalf("processStartTime: %v", err)
+	}
+	// 开机时间只精确到秒
+	if d := time.Since(start); d < -2*time.Second || d > time.Minute {
+		t.Errorf("进程启动时间 %v 与当前时间相差 %v", start, d)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "time"
+
+// killOrphanedSoffice 非Linux平台无法可靠识别遗留进程，只清理临时目录
+func killOrphanedSoffice(marker string, isActive func(id string) bool, startedBefore time.Time) []int {
+	return nil
+}
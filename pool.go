@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// 转换并发配置
+var (
+	MAX_CONCURRENT_CONVERSIONS int // 同时运行的soffice进程数上限
+	QUEUE_TIMEOUT_SECONDS      int // 等待空闲转换槽位的最长时间
+
+	conversionPool *workerPool
+)
+
+// ErrQueueTimeout 等待转换槽位超时
+var ErrQueueTimeout = errors.New("等待转换队列超时")
+
+// workerPool 限制全局和每个租户同时进行的转换数
+type workerPool struct {
+	global  chan struct{}
+	mu      sync.Mutex
+	tenants map[string]chan struct{}
+	queued  int
+}
+
+// initPoolConfig 读取转换并发相关的环境变量，需在加载租户配置之后调用
+func initPoolConfig() {
+	MAX_CONCURRENT_CONVERSIONS = getEnvInt("MAX_CONCURRENT_CONVERSIONS", runtime.NumCPU())
+	if MAX_CONCURRENT_CONVERSIONS <= 0 {
+		MAX_CONCURRENT_CONVERSIONS = runtime.NumCPU()
+	}
+	QUEUE_TIMEOUT_SECONDS = getEnvInt("QUEUE_TIMEOUT_SECONDS", 300)
+
+	conversionPool = &workerPool{
+		global:  make(chan struct{}, MAX_CONCURRENT_CONVERSIONS),
+		tenants: make(map[string]chan struct{}),
+	}
+	for id, t := range tenants {
+		if t.MaxConcurrent > 0 {
+			conversionPool.tenants[id] = make(chan struct{}, t.MaxConcurrent)
+		}
+	}
+}
+
+// acquire 等待租户和全局的空闲转换槽位，返回释放槽位的函数
+// 先占用租户自己的槽位，避免一个租户排队的请求占满全局槽位
+func (p *workerPool) acquire(ctx context.Context, tenantID string) (func(), error) {
+	p.mu.Lock()
+	p.queued++
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.queued--
+		p.mu.Unlock()
+	}()
+
+	var timeout <-chan time.Time
+	if QUEUE_TIMEOUT_SECONDS > 0 {
+		timer := time.NewTimer(time.Duration(QUEUE_TIMEOUT_SECONDS) * time.Second)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	tenantSlots := p.tenants[tenantID]
+	if tenantSlots != nil {
+		select {
+		case tenantSlots <- struct{}{}:
+		case <-timeout:
+			return nil, ErrQueueTimeout
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	select {
+	case p.global <- struct{}{}:
+	case <-timeout:
+		if tenantSlots != nil {
+			<-tenantSlots
+		}
+		return nil, ErrQueueTimeout
+	case <-ctx.Done():
+		if tenantSlots != nil {
+			<-tenantSlots
+		}
+		return nil, ctx.Err()
+	}
+
+	return func() {
+		<-p.global
+		if tenantSlots != nil {
+			<-tenantSlots
+		}
+	}, nil
+}
+
+// tryAcquire 不等待地占用一个全局转换槽位，没有空闲槽位时返回false
+func (p *workerPool) tryAcquire() (func(), bool) {
+	select {
+	case p.global <- struct{}{}:
+		return func() { <-p.global }, true
+	default:
+		return nil, false
+	}
+}
+
+// stats 返回正在进行和排队中的转换数
+func (p *workerPool) stats() (active, queued int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.global), p.queued
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// LibreOffice用户配置相关设置
+var (
+	UNTRUSTED_DOCUMENTS      bool   // 将所有上传文件视为不可信文档，禁用宏和外部内容
+	SOFFICE_PROFILE_TEMPLATE string // 每次转换前复制到用户配置目录的模板目录
+)
+
+// registryItem registrymodifications.xcu中的一项配置
+type registryItem struct {
+	path  string
+	name  string
+	value string
+}
+
+// untrustedRegistryItems 处理不可信文档时强制使用的配置
+// 宏安全级别设为最高并禁用宏执行，加载时不更新外部链接、不重新计算公式（避免WEBSERVICE等函数访问网络），
+// 不加载外部引用的图片，并禁用OLE对象和DDE链接等活动内容
+var untrustedRegistryItems = []registryItem{
+	{"/org.openoffice.Office.Common/Security/Scripting", "MacroSecurityLevel", "3"},
+	{"/org.openoffice.Office.Common/Security/Scripting", "DisableMacrosExecution", "true"},
+	{"/org.openoffice.Office.Common/Security/Scripting", "BlockUntrustedRefererLinks", "true"},
+	{"/org.openoffice.Office.Common/Security/Scripting", "DisableActiveContent", "true"},
+	{"/org.openoffice.Office.Writer/Content/Update", "Link", "0"}, // 0: 从不更新
+	{"/org.openoffice.Office.Calc/Content/Update", "Link", "1"},   // 1: 从不更新
+	{"/org.openoffice.Office.Calc/Formula/Load", "OOXMLRecalcMode", "1"},
+	{"/org.openoffice.Office.Calc/Formula/Load", "ODFRecalcMode", "1"},
+}
+
+// initProfileConfig 读取LibreOffice用户配置相关的环境变量
+func initProfileConfig() {
+	UNTRUSTED_DOCUMENTS = getEnvBool("UNTRUSTED_DOCUMENTS", true)
+	SOFFICE_PROFILE_TEMPLATE = getEnvString("SOFFICE_PROFILE_TEMPLATE", "")
+
+	if SOFFICE_PROFILE_TEMPLATE != "" {
+		if info, err := os.Stat(SOFFICE_PROFILE_TEMPLATE); err != nil || !info.IsDir() {
+			log.Fatalf("SOFFICE_PROFILE_TEMPLATE不是有效的目录: %s", SOFFICE_PROFILE_TEMPLATE)
+		}
+	}
+	if UNTRUSTED_DOCUMENTS {
+		log.Println("已启用不可信文档模式，转换时禁用宏、外部链接和活动内容")
+	} else {
+		log.Println("警告: 已关闭不可信文档模式，文档中的宏和外部链接可能被执行或访问")
+	}
+}
+
+// prepareProfile 在转换前创建用户配置目录，复制模板并写入不可信文档的安全配置
+func prepareProfile(id string) error {
+	userDir := filepath.Join(profileDirFor(id), "user")
+	if err := os.MkdirAll(userDir, 0700); err != nil {
+		return err
+	}
+	if SOFFICE_PROFILE_TEMPLATE != "" {
+		if err := copyDir(SOFFICE_PROFILE_TEMPLATE, profileDirFor(id)); err != nil {
+			return fmt.Errorf("复制用户配置模板失败: %w", err)
+		}
+	}
+	if !UNTRUSTED_DOCUMENTS {
+		return nil
+	}
+
+	xcuPath := filepath.Join(userDir, "registrymodifications.xcu")
+	existing, err := os.ReadFile(xcuPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.WriteFile(xcuPath, mergeRegistryItems(existing, untrustedRegistryItems), 0600)
+}
+
+// mergeRegistryItems 将配置项追加到registrymodifications.xcu末尾，后出现的配置项优先生效
+func mergeRegistryItems(existing []byte, items []registryItem) []byte {
+	var b bytes.Buffer
+	for _, item := range items {
+		b.WriteString(`<item oor:path="`)
+		xml.EscapeText(&b, []byte(item.path))
+		b.WriteString(`"><prop oor:name="`)
+		xml.EscapeText(&b, []byte(item.name))
+		b.WriteString(`" oor:op="fuse"><value>`)
+		xml.EscapeText(&b, []byte(item.value))
+		b.WriteString("</value></prop></item>\n")
+	}
+
+	closing := []byte("</oor:items>")
+	if i := bytes.LastIndex(existing, closing); i >= 0 {
+		merged := append([]byte{}, existing[:i]...)
+		merged = append(merged, b.Bytes()...)
+		return append(merged, existing[i:]...)
+	}
+
+	var doc bytes.Buffer
+	doc.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	doc.WriteString(`<oor:items xmlns:oor="http://openoffice.org/2001/registry" xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` + "\n")
+	doc.Write(b.Bytes())
+	doc.Write(closing)
+	doc.WriteString("\n")
+	return doc.Bytes()
+}
+
+// copyDir 递归复制目录内容，已存在的文件会被覆盖
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0700)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		in, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
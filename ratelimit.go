@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 限流配置
+var (
+	RATE_LIMIT_PER_MINUTE   float64  // 每分钟允许的转换请求数，0表示不限制
+	RATE_LIMIT_BURST        int      // 令牌桶容量
+	DAILY_CONVERSION_QUOTA  int      // 每日转换次数上限，0表示不限制
+	DAILY_INPUT_BYTES_QUOTA int64    // 每日上传数据量上限（字节），0表示不限制
+	RATE_LIMITS_FILE        string   // 按客户端覆盖上述配置的JSON文件
+	TRUSTED_PROXIES         []string // 可信的反向代理地址或网段，只采信这些代理设置的X-Forwarded-For
+
+	rateLimiter = &clientLimiter{clients: make(map[string]*clientState)}
+)
+
+// 上下文中保存已计入当日用量的上传字节数的键
+const rateLimitChargedBytesKey = "rate_limit_charged_bytes"
+
+// 记录的客户端数量达到该值时清理空闲的客户端，之后的清理阈值为清理后数量的两倍
+const clientSweepThreshold = 10000
+
+// ClientLimits 一个客户端的限流和配额配置
+type ClientLimits struct {
+	RatePerMinute   float64 `json:"rate_per_minute"`
+	Burst           int     `json:"burst"`
+	DailyConversion int     `json:"daily_conversions"`
+	DailyInputBytes int64   `json:"daily_input_bytes"`
+}
+
+// clientState 一个客户端的令牌桶和当日用量
+type clientState struct {
+	tokens     float64
+	lastRefill time.Time
+	conversion int
+	inputBytes int64
+}
+
+// clientLimiter 按客户端身份进行限流和配额统计，数据只保存在当前进程内存中
+type clientLimiter struct {
+	mu        sync.Mutex
+	clients   map[string]*clientState
+	overrides map[string]ClientLimits
+	day       string
+	sweepAt   int
+}
+
+// initRateLimitConfig 读取限流相关的环境变量
+func initRateLimitConfig() {
+	perMinute := getEnvInt("RATE_LIMIT_PER_MINUTE", 0)
+	RATE_LIMIT_PER_MINUTE = float64(perMinute)
+	RATE_LIMIT_BURST = effectiveBurst(RATE_LIMIT_PER_MINUTE, getEnvInt("RATE_LIMIT_BURST", perMinute))
+	DAILY_CONVERSION_QUOTA = getEnvInt("DAILY_CONVERSION_QUOTA", 0)
+	DAILY_INPUT_BYTES_QUOTA = getEnvInt64("DAILY_INPUT_BYTES_QUOTA", 0)
+	RATE_LIMITS_FILE = getEnvString("RATE_LIMITS_FILE", "")
+	TRUSTED_PROXIES = splitList(getEnvString("TRUSTED_PROXIES", ""))
+
+	if err := reloadRateLimits(); err != nil {
+		log.Fatalf("加载限流配置失败: %v", err)
+	}
+}
+
+// reloadRateLimits 重新读取按客户端覆盖的限流配置
+// 文件为以客户端身份（如 key:team-a、jwt:alice、ip:10.0.0.1）为键的JSON对象
+func reloadRateLimits() error {
+	overrides := make(map[string]ClientLimits)
+	if RATE_LIMITS_FILE != "" {
+		data, err := os.ReadFile(RATE_LIMITS_FILE)
+		if err != nil {
+			return fmt.Errorf("读取限流配置文件失败: %w", err)
+		}
+		if err := json.Unmarshal(data, &overrides); err != nil {
+			return fmt.Errorf("解析限流配置文件失败: %w", err)
+		}
+		log.Printf("已加载客户端限流配置 %d 条", len(overrides))
+	}
+	rateLimiter.mu.Lock()
+	rateLimiter.overrides = overrides
+	rateLimiter.mu.Unlock()
+	return nil
+}
+
+// effectiveBurst 令牌桶容量未配置或不大于0时，使用每分钟请求数作为容量
+func effectiveBurst(ratePerMinute float64, burst int) int {
+	if burst <= 0 {
+		return int(math.Ceil(ratePerMinute))
+	}
+	return burst
+}
+
+// limitsFor 返回客户端的限流配置，未单独配置时使用全局配置
+func (l *clientLimiter) limitsFor(client string) ClientLimits {
+	if limits, ok := l.overrides[client]; ok {
+		limits.Burst = effectiveBurst(limits.RatePerMinute, limits.Burst)
+		return limits
+	}
+	return ClientLimits{
+		RatePerMinute:   RATE_LIMIT_PER_MINUTE,
+		Burst:           RATE_LIMIT_BURST,
+		DailyConversion: DAILY_CONVERSION_QUOTA,
+		DailyInputBytes: DAILY_INPUT_BYTES_QUOTA,
+	}
+}
+
+// state 返回客户端的状态，跨天时清空所有客户端的用量
+func (l *clientLimiter) state(client string, now time.Time, limits ClientLimits) *clientState {
+	today := now.Format("20060102")
+	if l.day != today {
+		l.day = today
+		l.clients = make(map[string]*clientState)
+	}
+	st, ok := l.clients[client]
+	if !ok {
+		if len(l.clients) >= max(l.sweepAt, clientSweepThreshold) {
+			l.evictIdle(now)
+			l.sweepAt = 2 * len(l.clients)
+		}
+		st = &clientState{tokens: float64(limits.Burst), lastRefill: now}
+		l.clients[client] = st
+	}
+	return st
+}
+
+// evictIdle 删除令牌桶已装满且没有需要保留的当日用量的客户端，这些客户端再次请求时重新创建的状态与删除前相同
+func (l *clientLimiter) evictIdle(now time.Time) {
+	for client, st := range l.clients {
+		limits := l.limitsFor(client)
+		if limits.RatePerMinute > 0 && st.tokens+now.Sub(st.lastRefill).Seconds()*limits.RatePerMinute/60 < float64(limits.Burst) {
+			continue
+		}
+		if (limits.DailyConversion > 0 || limits.DailyInputBytes > 0) && (st.conversion > 0 || st.inputBytes > 0) {
+			continue
+		}
+		delete(l.clients, client)
+	}
+}
+
+// rateLimitResult 一次限流检查的结果
+type rateLimitResult struct {
+	allowed    bool
+	reason     string
+	code       string
+	retryAfter time.Duration
+	limits     ClientLimits
+	remaining  int
+	resetAt    time.Time
+}
+
+// take 检查并消耗一次转换请求的令牌和配额，contentLength为预计的上传字节数
+func (l *clientLimiter) take(client string, contentLength int64, now time.Time) rateLimitResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limits := l.limitsFor(client)
+	st := l.state(client, now, limits)
+	result := rateLimitResult{allowed: true, limits: limits}
+
+	// 补充令牌
+	if limits.RatePerMinute > 0 {
+		ratePerSecond := limits.RatePerMinute / 60
+		st.tokens = math.Min(float64(limits.Burst), st.tokens+now.Sub(st.lastRefill).Seconds()*ratePerSecond)
+		st.lastRefill = now
+	}
+
+	switch {
+	case limits.DailyConversion > 0 && st.conversion >= limits.DailyConversion:
+		result.allowed = false
+		result.reason = "已超出每日转换次数配额"
+		result.code = CodeDailyConversionQuota
+		result.retryAfter = untilMidnight(now)
+	case limits.DailyInputBytes > 0 && st.inputBytes+contentLength > limits.DailyInputBytes:
+		result.allowed = false
+		result.reason = "已超出每日上传数据量配额"
+		result.code = CodeDailyUploadQuota
+		result.retryAfter = untilMidnight(now)
+	case limits.RatePerMinute > 0 && st.tokens < 1:
+		result.allowed = false
+		result.reason = "请求过于频繁"
+		result.code = CodeRateLimited
+		result.retryAfter = time.Duration((1 - st.tokens) / (limits.RatePerMinute / 60) * float64(time.Second))
+	}
+
+	if result.allowed {
+		if limits.RatePerMinute > 0 {
+			st.tokens--
+		}
+		st.conversion++
+		if contentLength > 0 {
+			st.inputBytes += contentLength
+		}
+	}
+	if limits.RatePerMinute > 0 {
+		// 令牌桶重新装满的时间
+		missing := float64(limits.Burst) - st.tokens
+		result.resetAt = now.Add(time.Duration(missing / (limits.RatePerMinute / 60) * float64(time.Second)))
+	}
+	result.remaining = int(math.Max(0, math.Floor(st.tokens)))
+	return result
+}
+
+// untilMidnight 返回到次日零点、每日配额重置的时间
+func untilMidnight(now time.Time) time.Duration {
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	return midnight.Sub(now)
+}
+
+// correctInputBytes 用实际上传的文件大小修正预计值，修正后超出每日上传数据量配额时返回false
+// 分块上传的请求没有Content-Length，限流时无法预计上传大小，只能在这里检查
+func (l *clientLimiter) correctInputBytes(client string, estimated, actual int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	st, ok := l.clients[client]
+	if !ok {
+		return true
+	}
+	st.inputBytes += actual - max(estimated, 0)
+	limits := l.limitsFor(client)
+	return limits.DailyInputBytes <= 0 || st.inputBytes <= limits.DailyInputBytes
+}
+
+// refund 退还一次失败转换占用的每日配额，令牌桶的令牌不退还
+func (l *clientLimiter) refund(client string, inputBytes int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	st, ok := l.clients[client]
+	if !ok {
+		return
+	}
+	// 跨天后用量已清空，不会减到0以下
+	st.conversion = max(st.conversion-1, 0)
+	st.inputBytes = max(st.inputBytes-max(inputBytes, 0), 0)
+}
+
+// rateLimitEnabled 是否配置了任何限流或配额
+func rateLimitEnabled() bool {
+	return RATE_LIMIT_PER_MINUTE > 0 || DAILY_CONVERSION_QUOTA > 0 || DAILY_INPUT_BYTES_QUOTA > 0 || RATE_LIMITS_FILE != ""
+}
+
+// rateLimitMiddleware 在读取上传内容之前按客户端限流，需放在requireScope之后
+func rateLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !rateLimitEnabled() {
+			c.Next()
+			return
+		}
+
+		client := callerIdentity(c)
+		contentLength := c.Request.ContentLength
+		result := rateLimiter.take(client, contentLength, time.Now())
+
+		if result.limits.RatePerMinute > 0 {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(result.limits.Burst))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(result.remaining))
+			c.Header("X-RateLimit-Reset", strconv.FormatInt(result.resetAt.Unix(), 10))
+		}
+
+		if !result.allowed {
+			rejectRateLimited(c, client, result.reason, result.code, result.retryAfter)
+			return
+		}
+
+		c.Set(rateLimitChargedBytesKey, contentLength)
+		c.Next()
+
+		// 转换失败（4xx/5xx）时退还每日配额，只有成功的转换计入用量
+		if c.Writer.Status() >= http.StatusBadRequest {
+			rateLimiter.refund(client, c.GetInt64(rateLimitChargedBytesKey))
+		}
+	}
+}
+
+// rejectRateLimited 返回429并在Retry-After中给出可以重试的秒数
+func rejectRateLimited(c *gin.Context, client, reason, code string, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	requestLogger(c).Warn("拒绝转换请求: "+reason, "client", client, "code", code)
+	abortWithError(c, http.StatusTooManyRequests, ErrorResponse{
+		Error:   tr(c, "error."+code),
+		Code:    code,
+		Details: tr(c, "detail.retry_after", seconds),
+	})
+}
+
+// recordInputBytes 记录实际上传的文件大小，超出每日上传数据量配额时返回429和false，调用方应直接返回
+func recordInputBytes(c *gin.Context, size int64) bool {
+	if !rateLimitEnabled() {
+		return true
+	}
+	client := callerIdentity(c)
+	estimated := c.GetInt64(rateLimitChargedBytesKey)
+	allowed := rateLimiter.correctInputBytes(client, estimated, size)
+	// 拒绝时由rateLimitMiddleware按实际大小退还
+	c.Set(rateLimitChargedBytesKey, size)
+	if !allowed {
+		rejectRateLimited(c, client, "已超出每日上传数据量配额", CodeDailyUploadQuota, untilMidnight(time.Now()))
+		return false
+	}
+	return true
+}
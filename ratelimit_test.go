@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// withTestRateLimits 使用新的限流器和全局配置，测试结束后恢复
+func withTestRateLimits(t *testing.T, perMinute float64, burst, dailyConversions int, dailyBytes int64) {
+	t.Helper()
+	saved := rateLimiter
+	savedRate, savedBurst := RATE_LIMIT_PER_MINUTE, RATE_LIMIT_BURST
+	savedConversions, savedBytes := DAILY_CONVERSION_QUOTA, DAILY_INPUT_BYTES_QUOTA
+	rateLimiter = &clientLimiter{clients: make(map[string]*clientState), overrides: make(map[string]ClientLimits)}
+	RATE_LIMIT_PER_MINUTE, RATE_LIMIT_BURST = perMinute, burst
+	DAILY_CONVERSION_QUOTA, DAILY_INPUT_BYTES_QUOTA = dailyConversions, dailyBytes
+	t.Cleanup(func() {
+		rateLimiter = saved
+		RATE_LIMIT_PER_MINUTE, RATE_LIMIT_BURST = savedRate, savedBurst
+		DAILY_CONVERSION_QUOTA, DAILY_INPUT_BYTES_QUOTA = savedConversions, savedBytes
+	})
+}
+
+func TestEffectiveBurst(t *testing.T) {
+	tests := []struct {
+		rate  float64
+		burst int
+		want  int
+	}{
+		{10, 5, 5},
+		{10, 0, 10},
+		{10, -1, 10},
+		{2.5, 0, 3},
+		{0, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := effectiveBurst(tt.rate, tt.burst); got != tt.want {
+			t.Errorf("effectiveBurst(%v, %d) = %d，期望 %d", tt.rate, tt.burst, got, tt.want)
+		}
+	}
+}
+
+func TestInitRateLimitConfigBurst(t *testing.T) {
+	withTestRateLimits(t, 0, 0, 0, 0)
+	t.Setenv("RATE_LIMIT_PER_MINUTE", "6")
+	t.Setenv("RATE_LIMIT_BURST", "0")
+	t.Setenv("RATE_LIMITS_FILE", "")
+	initRateLimitConfig()
+	if RATE_LIMIT_BURST != 6 {
+		t.Fatalf("RATE_LIMIT_BURST=0时令牌桶容量为 %d，期望 6", RATE_LIMIT_BURST)
+	}
+
+	// 容量为0时不应拒绝所有请求
+	now := time.Now()
+	for i := 0; i < 6; i++ {
+		if r := rateLimiter.take("key:a", 0, now); !r.allowed {
+			t.Fatalf("第 %d 个请求被拒绝: %s", i+1, r.reason)
+		}
+	}
+	if r := rateLimiter.take("key:a", 0, now); r.allowed || r.code != CodeRateLimited {
+		t.Errorf("超出容量后应返回 %s，实际 %+v", CodeRateLimited, r)
+	}
+}
+
+func TestRateLimitMiddlewareRefundsFailedConversions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	withTestRateLimits(t, 0, 0, 2, 100)
+
+	status := http.StatusOK
+	r := gin.New()
+	r.POST("/convert", rateLimitMiddleware(), func(c *gin.Context) {
+		recordInputBytes(c, 30)
+		c.Status(status)
+	})
+	send := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/convert", strings.NewReader(strings.Repeat("x", 40)))
+		req.RemoteAddr = "10.0.0.8:1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 失败的转换不占用每日配额
+	for _, s := range []int{http.StatusBadRequest, http.StatusUnsupportedMediaType, http.StatusInternalServerError} {
+		status = s
+		if code := send(); code != s {
+			t.Fatalf("状态码 %d，期望 %d", code, s)
+		}
+	}
+	st := rateLimiter.clients["ip:10.0.0.8"]
+	if st.conversion != 0 || st.inputBytes != 0 {
+		t.Fatalf("失败的转换后用量为 %d 次、%d 字节，期望为0", st.conversion, st.inputBytes)
+	}
+
+	// 成功的转换按实际上传大小计入
+	status = http.StatusOK
+	for i := 0; i < 2; i++ {
+		if code := send(); code != http.StatusOK {
+			t.Fatalf("第 %d 次成功转换返回 %d", i+1, code)
+		}
+	}
+	if st.conversion != 2 || st.inputBytes != 60 {
+		t.Errorf("成功转换后用量为 %d 次、%d 字节，期望 2 次、60 字节", st.conversion, st.inputBytes)
+	}
+	if code := send(); code != http.StatusTooManyRequests {
+		t.Errorf("配额用完后返回 %d，期望 429", code)
+	}
+}
+
+// 分块上传的请求没有Content-Length，读取上传文件后按实际大小检查每日上传数据量配额
+func TestRecordInputBytesChunkedUpload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	withTestRateLimits(t, 0, 0, 0, 100)
+
+	r := gin.New()
+	r.POST("/convert", rateLimitMiddleware(), func(c *gin.Context) {
+		if !recordInputBytes(c, 150) {
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/convert", strings.NewReader(strings.Repeat("x", 150)))
+	req.ContentLength = -1
+	req.RemoteAddr = "10.0.0.8:1234"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusTooManyRequests || !strings.Contains(w.Body.String(), CodeDailyUploadQuota) {
+		t.Fatalf("超出配额的分块上传返回 %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("缺少Retry-After响应头")
+	}
+	if st := rateLimiter.clients["ip:10.0.0.8"]; st.conversion != 0 || st.inputBytes != 0 {
+		t.Errorf("被拒绝的请求后用量为 %d 次、%d 字节，期望为0", st.conversion, st.inputBytes)
+	}
+}
+
+func TestClientLimiterEvictsIdleClients(t *testing.T) {
+	withTestRateLimits(t, 1, 1, 0, 0)
+	rateLimiter.overrides["key:quota"] = ClientLimits{DailyConversion: 10}
+
+	start := time.Now()
+	rateLimiter.take("key:quota", 0, start)
+	for i := 0; i < clientSweepThreshold-2; i++ {
+		rateLimiter.take(fmt.Sprintf("ip:10.0.%d.%d", i/256, i%256), 0, start)
+	}
+	rateLimiter.take("ip:192.0.2.1", 0, start.Add(30*time.Second))
+
+	// 达到清理阈值，令牌桶已装满的客户端被删除，有当日用量或令牌尚未补满的客户端保留
+	rateLimiter.take("ip:192.0.2.2", 0, start.Add(time.Minute))
+	if n := len(rateLimiter.clients); n != 3 {
+		t.Fatalf("清理后记录了 %d 个客户端，期望 3", n)
+	}
+	for _, client := range []string{"key:quota", "ip:192.0.2.1", "ip:192.0.2.2"} {
+		if _, ok := rateLimiter.clients[client]; !ok {
+			t.Errorf("不应删除客户端 %s", client)
+		}
+	}
+}
+
+// 只采信TRUSTED_PROXIES中的代理设置的X-Forwarded-For
+func TestTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	saved := TRUSTED_PROXIES
+	t.Cleanup(func() { TRUSTED_PROXIES = saved })
+
+	tests := []struct {
+		proxies []string
+		remote  string
+		want    string
+	}{
+		{nil, "192.0.2.1:1234", "ip:192.0.2.1"},
+		{[]string{"10.0.0.0/8"}, "192.0.2.1:1234", "ip:192.0.2.1"},
+		{[]string{"10.0.0.0/8"}, "10.0.0.1:1234", "ip:198.51.100.7"},
+	}
+	for _, tt := range tests {
+		TRUSTED_PROXIES = tt.proxies
+		r := newRouter()
+		r.GET("/test/identity", func(c *gin.Context) { c.String(http.StatusOK, callerIdentity(c)) })
+		req := httptest.NewRequest(http.MethodGet, "/test/identity", nil)
+		req.RemoteAddr = tt.remote
+		req.Header.Set("X-Forwarded-For", "198.51.100.7")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("TRUSTED_PROXIES=%v，来自%s的请求身份为 %q，期望 %q", tt.proxies, tt.remote, got, tt.want)
+		}
+	}
+}
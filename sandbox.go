@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// soffice沙箱模式
+const (
+	SandboxOff       = "off"       // 直接启动soffice，仅用于开发环境
+	SandboxNamespace = "namespace" // 使用Linux命名空间隔离，无法启用时拒绝启动
+	SandboxAuto      = "auto"      // 支持时使用命名空间隔离，否则回退为不隔离
+)
+
+// 以沙箱辅助进程方式运行本程序时的第一个参数
+const sandboxHelperArg = "__soffice_sandbox"
+
+// 传递沙箱配置给辅助进程的环境变量
+const sandboxSpecEnv = "SOFFICE_SANDBOX_SPEC"
+
+// 沙箱配置
+var (
+	SOFFICE_SANDBOX  string
+	SANDBOX_UID      int
+	SANDBOX_GID      int
+	SANDBOX_RO_PATHS []string
+
+	sandboxActive bool
+)
+
+// 默认以只读方式暴露给soffice的系统目录
+var defaultSandboxROPaths = []string{
+	"/usr", "/lib", "/lib64", "/lib32", "/bin", "/sbin", "/opt",
+	"/etc/fonts", "/etc/ld.so.cache", "/etc/ld.so.conf", "/etc/ld.so.conf.d",
+	"/etc/localtime", "/etc/passwd", "/etc/group", "/etc/nsswitch.conf",
+}
+
+// sandboxSpec 辅助进程搭建沙箱所需的配置
+type sandboxSpec struct {
+	Program string        `json:"program"`
+	Args    []string      `json:"args"`
+	RootDir string        `json:"root_dir"`
+	ROPaths []string      `json:"ro_paths"`
+	RWPaths []string      `json:"rw_paths"`
+	UID     int           `json:"uid"`
+	GID     int           `json:"gid"`
+	Limits  processLimits `json:"limits"`
+	UserNS  bool          `json:"user_ns"`
+}
+
+// initSandboxConfig 读取沙箱相关的环境变量，并在启用时验证沙箱可以启动soffice
+func initSandboxConfig() {
+	SOFFICE_SANDBOX = strings.ToLower(getEnvString("SOFFICE_SANDBOX", SandboxOff))
+	SANDBOX_UID = getEnvInt("SANDBOX_UID", 65534)
+	SANDBOX_GID = getEnvInt("SANDBOX_GID", 65534)
+	SANDBOX_RO_PATHS = splitList(getEnvString("SANDBOX_RO_PATHS", ""))
+
+	switch SOFFICE_SANDBOX {
+	case SandboxOff:
+		log.Println("警告: 未启用soffice沙箱，LibreOffice以服务进程的权限运行")
+		return
+	case SandboxNamespace, SandboxAuto:
+	default:
+		log.Fatalf("无效的SOFFICE_SANDBOX: %q，可选值为off、namespace、auto", SOFFICE_SANDBOX)
+	}
+
+	err := probeSandbox()
+	if err == nil {
+		sandboxActive = true
+		user := fmt.Sprintf("uid=%d, gid=%d", SANDBOX_UID, SANDBOX_GID)
+		if os.Geteuid() != 0 {
+			user = "用户命名空间内以当前用户运行"
+		}
+		log.Printf("已启用soffice沙箱: %s", user)
+		return
+	}
+	if SOFFICE_SANDBOX == SandboxNamespace {
+		log.Fatalf("无法启用soffice沙箱: %v", err)
+	}
+	log.Printf("警告: 无法启用soffice沙箱，回退为不隔离运行: %v", err)
+}
+
+// probeSandbox 在沙箱中运行soffice --version，确认沙箱可用
+func probeSandbox() error {
+	if !sandboxSupported() {
+		return fmt.Errorf("当前系统不支持命名空间沙箱")
+	}
+	id := "probe"
+	defer os.RemoveAll(sandboxRootFor(id))
+	cmd, err := sandboxCommand(id, []string{"--version"}, nil)
+	if err != nil {
+		return err
+	}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// sandboxRootFor 返回转换ID对应的沙箱根目录
+func sandboxRootFor(id string) string {
+	return filepath.Join(TMP_DIR, workDirName(sandboxDirPrefix, id))
+}
+
+// sofficeCommand 构建执行soffice的命令，启用沙箱时只暴露rwPaths和LibreOffice安装目录
+// 两种方式都在执行soffice之前设置资源限制
+func sofficeCommand(id string, args []string, rwPaths []string) (*exec.Cmd, error) {
+	if !sandboxActive {
+		return limitedCommand(SOFFICE_PATH, args, sofficeLimits())
+	}
+	return sandboxCommand(id, args, rwPaths)
+}
+
+// sandboxCommand 构建通过沙箱辅助进程执行soffice的命令
+func sandboxCommand(id string, args []string, rwPaths []string) (*exec.Cmd, error) {
+	program, err := exec.LookPath(SOFFICE_PATH)
+	if err != nil {
+		return nil, fmt.Errorf("找不到soffice: %w", err)
+	}
+	program, err = filepath.Abs(program)
+	if err != nil {
+		return nil, err
+	}
+
+	// soffice通常是指向安装目录下program/soffice的符号链接，整个安装目录都需要暴露
+	roPaths := append(append([]string{}, defaultSandboxROPaths...), SANDBOX_RO_PATHS...)
+	roPaths = append(roPaths, filepath.Dir(program))
+	if real, err := filepath.EvalSymlinks(program); err == nil {
+		installDir := filepath.Dir(real)
+		if filepath.Base(installDir) == "program" {
+			installDir = filepath.Dir(installDir)
+		}
+		roPaths = append(roPaths, installDir)
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	spec := sandboxSpec{
+		Program: program,
+		Args:    args,
+		RootDir: sandboxRootFor(id),
+		ROPaths: roPaths,
+		RWPaths: rwPaths,
+		UID:     SANDBOX_UID,
+		GID:     SANDBOX_GID,
+		Limits:  sofficeLimits(),
+		UserNS:  os.Geteuid() != 0,
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(spec.RootDir, 0700); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(self, sandboxHelperArg)
+	cmd.Env = []string{sandboxSpecEnv + "=" + string(data)}
+	cmd.SysProcAttr = sandboxSysProcAttr(spec.UserNS)
+	return cmd, nil
+}
+
+// runSandboxHelperFromEnv 作为沙箱辅助进程运行，搭建沙箱后替换为soffice进程
+func runSandboxHelperFromEnv() {
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(os.Getenv(sandboxSpecEnv)), &spec); err != nil {
+		fmt.Fprintf(os.Stderr, "解析沙箱配置失败: %v\n", err)
+		os.Exit(126)
+	}
+	if err := runSandboxHelper(spec); err != nil {
+		fmt.Fprintf(os.Stderr, "启动沙箱失败: %v\n", err)
+		os.Exit(126)
+	}
+}
@@ -0,0 +1,240 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// 沙箱中提供的设备文件
+var sandboxDevices = []string{"/dev/null", "/dev/zero", "/dev/random", "/dev/urandom"}
+
+// sandboxSupported 判断当前系统能否创建沙箱所需的命名空间
+// 以root运行时直接创建命名空间，否则需要内核允许非特权用户命名空间
+func sandboxSupported() bool {
+	if os.Geteuid() == 0 {
+		return true
+	}
+	if data, err := os.ReadFile("/proc/sys/kernel/unprivileged_userns_clone"); err == nil && strings.TrimSpace(string(data)) == "0" {
+		return false
+	}
+	data, err := os.ReadFile("/proc/sys/user/max_user_namespaces")
+	if err != nil {
+		return false
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return n > 0
+}
+
+// sandboxSysProcAttr 返回在新的mount、pid、网络、IPC和UTS命名空间中启动辅助进程的参数
+// 新的网络命名空间中只有未启用的回环接口，soffice无法访问网络
+func sandboxSysProcAttr(userNS bool) *syscall.SysProcAttr {
+	attr := &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWNET |
+			syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		Pdeathsig: syscall.SIGKILL,
+	}
+	if userNS {
+		// 非root运行时借助用户命名空间获得挂载权限，命名空间内的root对应当前用户
+		attr.Cloneflags |= syscall.CLONE_NEWUSER
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Geteuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getegid(), Size: 1}}
+		attr.GidMappingsEnableSetgroups = false
+	}
+	return attr
+}
+
+// runSandboxHelper 在新的命名空间中搭建只包含必要目录的根文件系统，
+// 设置资源限制并降低权限后执行soffice，成功时不会返回
+func runSandboxHelper(spec sandboxSpec) error {
+	// no_new_privs和能力集边界是线程属性，必须在最终执行soffice的线程上设置
+	runtime.LockOSThread()
+
+	// 挂载操作不能传播回宿主
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("设置挂载传播失败: %w", err)
+	}
+
+	root := spec.RootDir
+	if err := unix.Mount("tmpfs", root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=0755,size=16m"); err != nil {
+		return fmt.Errorf("挂载沙箱根目录失败: %w", err)
+	}
+
+	tmpDir := filepath.Join(root, "tmp")
+	if err := os.MkdirAll(tmpDir, 01777); err != nil {
+		return err
+	}
+	if err := unix.Mount("tmpfs", tmpDir, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777,size=256m"); err != nil {
+		return fmt.Errorf("挂载/tmp失败: %w", err)
+	}
+
+	for _, p := range spec.ROPaths {
+		if err := bindIntoSandbox(root, p, true); err != nil {
+			return err
+		}
+	}
+	for _, p := range spec.RWPaths {
+		if err := bindIntoSandbox(root, p, false); err != nil {
+			return err
+		}
+	}
+	for _, dev := range sandboxDevices {
+		if err := bindIntoSandbox(root, dev, false); err != nil {
+			return err
+		}
+	}
+
+	procDir := filepath.Join(root, "proc")
+	if err := os.MkdirAll(procDir, 0555); err != nil {
+		return err
+	}
+	if err := unix.Mount("proc", procDir, "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("挂载/proc失败: %w", err)
+	}
+
+	// 读写目录交给沙箱用户
+	if !spec.UserNS {
+		for _, p := range spec.RWPaths {
+			if err := chownTree(p, spec.UID, spec.GID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := pivotRoot(root); err != nil {
+		return err
+	}
+	if err := unix.Sethostname([]byte("sandbox")); err != nil {
+		return fmt.Errorf("设置主机名失败: %w", err)
+	}
+
+	if err := setProcessLimits(0, spec.Limits); err != nil {
+		return err
+	}
+	if err := dropPrivileges(spec); err != nil {
+		return err
+	}
+
+	env := []string{
+		"PATH=/usr/local/bin:/usr/bin:/bin",
+		"HOME=/tmp",
+		"TMPDIR=/tmp",
+		"LANG=C.UTF-8",
+	}
+	argv := append([]string{spec.Program}, spec.Args...)
+	return unix.Exec(spec.Program, argv, env)
+}
+
+// bindIntoSandbox 将宿主上的路径绑定挂载到沙箱中相同的位置，不存在的路径会被忽略
+// 符号链接（如/bin -> usr/bin）在沙箱中重建为相同的符号链接
+func bindIntoSandbox(root, p string, readOnly bool) error {
+	target := filepath.Join(root, p)
+	if link, err := os.Readlink(p); err == nil {
+		if _, err := os.Lstat(target); err == nil {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		return os.Symlink(link, target)
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil
+	}
+	if info.IsDir() {
+		err = os.MkdirAll(target, 0755)
+	} else {
+		if err = os.MkdirAll(filepath.Dir(target), 0755); err == nil {
+			var f *os.File
+			if f, err = os.OpenFile(target, os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+				f.Close()
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("创建挂载点%s失败: %w", p, err)
+	}
+
+	if err := unix.Mount(p, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("挂载%s失败: %w", p, err)
+	}
+	flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_NOSUID)
+	if readOnly {
+		flags |= unix.MS_RDONLY
+	}
+	if !readOnly && info.Mode()&os.ModeDevice == 0 {
+		flags |= unix.MS_NODEV
+	}
+	// 重新挂载时必须保留原挂载点上已有的限制，否则非特权用户命名空间中会失败
+	var st unix.Statfs_t
+	if err := unix.Statfs(target, &st); err == nil {
+		flags |= uintptr(st.Flags) & (unix.MS_NODEV | unix.MS_NOEXEC | unix.MS_NOATIME | unix.MS_NODIRATIME | unix.MS_RELATIME)
+	}
+	if err := unix.Mount("", target, "", flags, ""); err != nil {
+		return fmt.Errorf("重新挂载%s失败: %w", p, err)
+	}
+	return nil
+}
+
+// chownTree 递归修改目录的所有者
+func chownTree(root string, uid, gid int) error {
+	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(p, uid, gid)
+	})
+}
+
+// pivotRoot 将沙箱目录切换为根目录并卸载宿主的根文件系统
+func pivotRoot(root string) error {
+	oldRoot := filepath.Join(root, ".oldroot")
+	if err := os.MkdirAll(oldRoot, 0700); err != nil {
+		return err
+	}
+	if err := unix.PivotRoot(root, oldRoot); err != nil {
+		return fmt.Errorf("切换根目录失败: %w", err)
+	}
+	if err := unix.Chdir("/"); err != nil {
+		return err
+	}
+	if err := unix.Unmount("/.oldroot", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("卸载宿主根目录失败: %w", err)
+	}
+	return os.Remove("/.oldroot")
+}
+
+// dropPrivileges 切换到沙箱用户并禁止重新获得权限
+// 在用户命名空间中无法切换到其他用户，改为清空能力集边界，使soffice不具有任何能力
+func dropPrivileges(spec sandboxSpec) error {
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("设置no_new_privs失败: %w", err)
+	}
+	if spec.UserNS {
+		for c := 0; c <= unix.CAP_LAST_CAP; c++ {
+			if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(c), 0, 0, 0); err != nil && err != unix.EINVAL {
+				return fmt.Errorf("清空能力集失败: %w", err)
+			}
+		}
+		return nil
+	}
+	if err := syscall.Setgroups(nil); err != nil {
+		return fmt.Errorf("清除附加组失败: %w", err)
+	}
+	if err := syscall.Setgid(spec.GID); err != nil {
+		return fmt.Errorf("切换用户组失败: %w", err)
+	}
+	if err := syscall.Setuid(spec.UID); err != nil {
+		return fmt.Errorf("切换用户失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// sandboxSupported 命名空间沙箱只支持Linux
+func sandboxSupported() bool {
+	return false
+}
+
+func sandboxSysProcAttr(userNS bool) *syscall.SysProcAttr {
+	return nil
+}
+
+func runSandboxHelper(spec sandboxSpec) error {
+	return errors.New("当前系统不支持命名空间沙箱")
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 下载链接签名配置
+var (
+	DOWNLOAD_SIGNING_KEY        []byte
+	DOWNLOAD_URL_EXPIRY_MINUTES int
+	REQUIRE_SIGNED_DOWNLOADS    bool
+)
+
+// 签名校验错误
+var (
+	ErrSignatureMissing = errors.New("下载链接缺少签名")
+	ErrSignatureInvalid = errors.New("下载链接签名无效")
+	ErrSignatureExpired = errors.New("下载链接已过期")
+)
+
+// initSigningConfig 读取下载链接签名相关的环境变量，需要在initStorageConfig之后调用
+func initSigningConfig() {
+	REQUIRE_SIGNED_DOWNLOADS = getEnvBool("REQUIRE_SIGNED_DOWNLOADS", false)
+
+	key := os.Getenv("DOWNLOAD_SIGNING_KEY")
+	switch {
+	case key != "":
+		DOWNLOAD_SIGNING_KEY = []byte(key)
+	case STORAGE_BACKEND == "s3" || REQUIRE_SIGNED_DOWNLOADS:
+		// 多副本共用对象存储，或者只允许签名下载时，随机密钥会使其他副本签发的链接和重启前的链接失效
+		log.Fatalf("使用S3存储或设置REQUIRE_SIGNED_DOWNLOADS=true时必须配置DOWNLOAD_SIGNING_KEY")
+	default:
+		// 单实例本地存储可以使用随机密钥，重启后已签发的链接会失效
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			log.Fatalf("生成下载签名密钥失败: %v", err)
+		}
+		DOWNLOAD_SIGNING_KEY = buf
+		log.Println("DOWNLOAD_SIGNING_KEY为空，已随机生成密钥，重启后已签发的下载链接将失效")
+	}
+
+	// 默认与文件过期时间一致，文件永不过期时默认24小时
+	defaultExpiry := 24 * 60
+	if FILE_EXPIRY_HOURS > 0 {
+		defaultExpiry = FILE_EXPIRY_HOURS * 60
+	}
+	DOWNLOAD_URL_EXPIRY_MINUTES = getEnvInt("DOWNLOAD_URL_EXPIRY_MINUTES", defaultExpiry)
+}
+
+// signDownloadPath 计算下载路径和过期时间的HMAC签名
+func signDownloadPath(relativePath string, expires int64) string {
+	mac := hmac.New(sha256.New, DOWNLOAD_SIGNING_KEY)
+	fmt.Fprintf(mac, "%s\n%d", relativePath, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signedDownloadQuery 生成带签名和过期时间的查询参数
+func signedDownloadQuery(relativePath string) (url.Values, time.Time) {
+	expiresAt := time.Now().Add(time.Duration(DOWNLOAD_URL_EXPIRY_MINUTES) * time.Minute)
+	expires := expiresAt.Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", signDownloadPath(relativePath, expires))
+	return query, expiresAt
+}
+
+// buildDownloadURL 根据请求构建带签名的下载地址，/v1接口返回/v1下的下载地址
+func buildDownloadURL(c *gin.Context, relativePath string) (string, time.Time) {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	query, expiresAt := signedDownloadQuery(relativePath)
+	downloadURL := url.URL{
+		Scheme:   scheme,
+		Host:     c.Request.Host,
+		Path:     apiPathPrefix(c) + "/download/" + relativePath,
+		RawQuery: query.Encode(),
+	}
+	return downloadURL.String(), expiresAt
+}
+
+// verifyDownloadSignature 校验下载请求中的签名和过期时间
+func verifyDownloadSignature(relativePath, expiresStr, signature string) error {
+	if expiresStr == "" || signature == "" {
+		return ErrSignatureMissing
+	}
+	expires, err := strconv.ParseInt(expiresStr, 10, 64)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+	expected := signDownloadPath(relativePath, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrSignatureInvalid
+	}
+	if time.Now().Unix() > expires {
+		return ErrSignatureExpired
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func withSigningKey(t *testing.T, key string) {
+	t.Helper()
+	saved := DOWNLOAD_SIGNING_KEY
+	DOWNLOAD_SIGNING_KEY = []byte(key)
+	t.Cleanup(func() { DOWNLOAD_SIGNING_KEY = saved })
+}
+
+func TestSignedDownloadQuery(t *testing.T) {
+	withSigningKey(t, "test-key")
+	savedExpiry := DOWNLOAD_URL_EXPIRY_MINUTES
+	DOWNLOAD_URL_EXPIRY_MINUTES = 30
+	t.Cleanup(func() { DOWNLOAD_URL_EXPIRY_MINUTES = savedExpiry })
+
+	path := "tenants/a/20240101/report_1704067200000.pdf"
+	query, expiresAt := signedDownloadQuery(path)
+	if d := time.Until(expiresAt); d < 29*time.Minute || d > 31*time.Minute {
+		t.Errorf("过期时间应在30分钟后，实际 %v", d)
+	}
+	if err := verifyDownloadSignature(path, query.Get("expires"), query.Get("signature")); err != nil {
+		t.Fatalf("刚签发的链接校验失败: %v", err)
+	}
+}
+
+func TestVerifyDownloadSignature(t *testing.T) {
+	withSigningKey(t, "test-key")
+	path := "20240101/report_1704067200000.pdf"
+	future := time.Now().Add(time.Hour).Unix()
+	past := time.Now().Add(-time.Minute).Unix()
+	valid := signDownloadPath(path, future)
+	// 修改签名的最后一个字符，确保与原签名不同
+	tampered := valid[:len(valid)-1] + "0"
+	if tampered == valid {
+		tampered = valid[:len(valid)-1] + "1"
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		expires   string
+		signature string
+		want      error
+	}{
+		{"有效", path, strconv.FormatInt(future, 10), valid, nil},
+		{"缺少签名", path, strconv.FormatInt(future, 10), "", ErrSignatureMissing},
+		{"缺少过期时间", path, "", valid, ErrSignatureMissing},
+		{"过期时间不是数字", path, "tomorrow", valid, ErrSignatureInvalid},
+		{"篡改路径", "20240101/other_1704067200000.pdf", strconv.FormatInt(future, 10), valid, ErrSignatureInvalid},
+		{"延长过期时间", path, strconv.FormatInt(future+3600, 10), valid, ErrSignatureInvalid},
+		{"篡改签名", path, strconv.FormatInt(future, 10), tampered, ErrSignatureInvalid},
+		{"已过期", path, strconv.FormatInt(past, 10), signDownloadPath(path, past), ErrSignatureExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyDownloadSignature(tt.path, tt.expires, tt.signature)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("verifyDownloadSignature() = %v，期望 %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyDownloadSignatureOtherKey(t *testing.T) {
+	withSigningKey(t, "replica-a")
+	path := "20240101/report_1704067200000.pdf"
+	expires := time.Now().Add(time.Hour).Unix()
+	signature := signDownloadPath(path, expires)
+
+	// 使用相同密钥的其他副本可以校验，密钥不同时拒绝
+	DOWNLOAD_SIGNING_KEY = []byte("replica-a")
+	if err := verifyDownloadSignature(path, strconv.FormatInt(expires, 10), signature); err != nil {
+		t.Errorf("相同密钥校验失败: %v", err)
+	}
+	DOWNLOAD_SIGNING_KEY = []byte("replica-b")
+	if err := verifyDownloadSignature(path, strconv.FormatInt(expires, 10), signature); !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("不同密钥应返回ErrSignatureInvalid，实际 %v", err)
+	}
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// 存储后端配置
+var (
+	STORAGE_BACKEND           string
+	S3_ENDPOINT               string
+	S3_ACCESS_KEY             string
+	S3_SECRET_KEY             string
+	S3_BUCKET                 string
+	S3_REGION                 string
+	S3_PREFIX                 string
+	S3_USE_SSL                bool
+	S3_PRESIGN_DOWNLOAD       bool
+	S3_PRESIGN_EXPIRY_MINUTES int
+	S3_TIMEOUT_SECONDS        int
+
+	// 当前使用的存储后端
+	fileStorage Storage
+)
+
+// ErrFileNotFound 存储中不存在指定文件
+var ErrFileNotFound = errors.New("文件不存在")
+
+// StoredFile 存储中文件的基本信息
+type StoredFile struct {
+	Path    string    // 相对路径，使用"/"分隔，如 20231201/example_1701410000000.pdf
+	Size    int64     // 文件大小（字节）
+	ModTime time.Time // 最后修改时间
+}
+
+// Storage 转换结果的存储后端
+type Storage interface {
+	// Name 返回存储后端名称
+	Name() string
+	// Save 将本地文件保存到存储中的相对路径，ctx用于取消上传和在日志中记录请求ID
+	Save(ctx context.Context, localPath, relativePath string) error
+	// SaveData 将内存中的数据保存到存储中的相对路径
+	SaveData(ctx context.Context, relativePath string, data []byte) error
+	// Stat 获取存储中文件的信息，文件不存在时返回ErrFileNotFound
+	Stat(ctx context.Context, relativePath string) (StoredFile, error)
+	// Open 打开存储中的文件用于读取，ctx取消后读取失败，文件不存在时返回ErrFileNotFound
+	Open(ctx context.Context, relativePath string) (io.ReadCloser, error)
+	// Delete 删除存储中的文件，文件不存在时返回ErrFileNotFound
+	Delete(ctx context.Context, relativePath string) error
+	// Walk 遍历存储中相对路径以prefix开头的文件，prefix为空时遍历所有文件
+	Walk(ctx context.Context, prefix string, fn func(StoredFile) error) error
+	// PresignURL 生成有时效的直接下载地址，不支持时返回空字符串
+	PresignURL(ctx context.Context, relativePath, downloadName string, expiry time.Duration) (string, error)
+}
+
+// initStorageConfig 读取存储相关的环境变量并创建存储后端
+func initStorageConfig() {
+	STORAGE_BACKEND = strings.ToLower(getEnvString("STORAGE_BACKEND", "local"))
+	S3_ENDPOINT = getEnvString("S3_ENDPOINT", "")
+	S3_ACCESS_KEY = os.Getenv("S3_ACCESS_KEY")
+	S3_SECRET_KEY = os.Getenv("S3_SECRET_KEY")
+	S3_BUCKET = getEnvString("S3_BUCKET", "libreoffice-api")
+	S3_REGION = getEnvString("S3_REGION", "")
+	S3_PREFIX = strings.Trim(getEnvString("S3_PREFIX", ""), "/")
+	S3_USE_SSL = getEnvBool("S3_USE_SSL", false)
+	S3_PRESIGN_DOWNLOAD = getEnvBool("S3_PRESIGN_DOWNLOAD", false)
+	S3_PRESIGN_EXPIRY_MINUTES = getEnvInt("S3_PRESIGN_EXPIRY_MINUTES", 15)
+	S3_TIMEOUT_SECONDS = getEnvInt("S3_TIMEOUT_SECONDS", 30)
+
+	switch STORAGE_BACKEND {
+	case "s3":
+		storage, err := newS3Storage()
+		if err != nil {
+			log.Fatalf("初始化S3存储失败: %v", err)
+		}
+		fileStorage = storage
+	case "local", "":
+		STORAGE_BACKEND = "local"
+		fileStorage = newLocalStorage(DATA_DIR)
+	default:
+		log.Fatalf("不支持的存储后端: %s", STORAGE_BACKEND)
+	}
+	log.Printf("使用存储后端: %s", fileStorage.Name())
+}
+
+// cleanStoragePath 规范化相对路径，拒绝目录遍历
+func cleanStoragePath(relativePath string) (string, error) {
+	relativePath = strings.TrimPrefix(filepath.ToSlash(relativePath), "/")
+	if relativePath == "" {
+		return "", errors.New("未指定文件路径")
+	}
+	cleaned := path.Clean(relativePath)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("无效的文件路径: %s", relativePath)
+	}
+	return cleaned, nil
+}
+
+// localStorage 本地文件系统存储
+type localStorage struct {
+	root string
+}
+
+func newLocalStorage(root string) *localStorage {
+	return &localStorage{root: root}
+}
+
+func (s *localStorage) Name() string {
+	return "local(" + s.root + ")"
+}
+
+// LocalPath 返回相对路径对应的本地文件路径
+func (s *localStorage) LocalPath(relativePath string) (string, error) {
+	cleaned, err := cleanStoragePath(relativePath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(cleaned)), nil
+}
+
+func (s *localStorage) Save(ctx context.Context, localPath, relativePath string) error {
+	dst, err := s.LocalPath(relativePath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	return copyFile(ctx, localPath, dst)
+}
+
+func (s *localStorage) SaveData(ctx context.Context, relativePath string, data []byte) error {
+	dst, err := s.LocalPath(relativePath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	return os.WriteFile(dst, data, 0644)
+}
+
+func (s *localStorage) Stat(ctx context.Context, relativePath string) (StoredFile, error) {
+	cleaned, err := cleanStoragePath(relativePath)
+	if err != nil {
+		return StoredFile{}, err
+	}
+	info, err := os.Stat(filepath.Join(s.root, filepath.FromSlash(cleaned)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return StoredFile{}, ErrFileNotFound
+		}
+		return StoredFile{}, err
+	}
+	if info.IsDir() {
+		return StoredFile{}, errors.New("无法下载目录")
+	}
+	return StoredFile{Path: cleaned, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (s *localStorage) Open(ctx context.Context, relativePath string) (io.ReadCloser, error) {
+	p, err := s.LocalPath(relativePath)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, ErrFileNotFound
+	}
+	return f, err
+}
+
+func (s *localStorage) Delete(ctx context.Context, relativePath string) error {
+	p, err := s.LocalPath(relativePath)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil {
+		if os.IsNotExist(err) {
+			return ErrFileNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *localStorage) Walk(ctx context.Context, prefix string, fn func(StoredFile) error) error {
+	// 只遍历前缀所在的目录
+	start := s.root
+	if dir := path.Dir(strings.TrimPrefix(prefix, "/")); dir != "." {
+		cleaned, err := cleanStoragePath(dir)
+		if err != nil {
+			return err
+		}
+		start = filepath.Join(s.root, filepath.FromSlash(cleaned))
+	}
+	return filepath.Walk(start, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if p == start && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !strings.HasPrefix(rel, strings.TrimPrefix(prefix, "/")) {
+			return nil
+		}
+		return fn(StoredFile{Path: rel, Size: info.Size(), ModTime: info.ModTime()})
+	})
+}
+
+func (s *localStorage) PresignURL(ctx context.Context, relativePath, downloadName string, expiry time.Duration) (string, error) {
+	return "", nil
+}
+
+// s3Storage S3兼容的对象存储（AWS S3、MinIO等）
+type s3Storage struct {
+	client  *minio.Client
+	bucket  string
+	prefix  string
+	timeout time.Duration // 单次请求的超时时间，0表示只受ctx限制
+}
+
+func newS3Storage() (*s3Storage, error) {
+	if S3_ENDPOINT == "" {
+		return nil, errors.New("未配置S3_ENDPOINT")
+	}
+	client, err := minio.New(S3_ENDPOINT, &minio.Options{
+		Creds:  credentials.NewStaticV4(S3_ACCESS_KEY, S3_SECRET_KEY, ""),
+		Secure: S3_USE_SSL,
+		Region: S3_REGION,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建S3客户端失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	exists, err := client.BucketExists(ctx, S3_BUCKET)
+	if err != nil {
+		return nil, fmt.Errorf("检查存储桶失败: %w", err)
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, S3_BUCKET, minio.MakeBucketOptions{Region: S3_REGION}); err != nil {
+			return nil, fmt.Errorf("创建存储桶失败: %w", err)
+		}
+		log.Printf("已创建存储桶: %s", S3_BUCKET)
+	}
+
+	return &s3Storage{
+		client:  client,
+		bucket:  S3_BUCKET,
+		prefix:  S3_PREFIX,
+		timeout: time.Duration(S3_TIMEOUT_SECONDS) * time.Second,
+	}, nil
+}
+
+// withTimeout 为单次请求设置超时，避免对象存储无响应时一直阻塞请求
+// 上传和下载的数据流大小不定，只受调用方ctx限制
+func (s *s3Storage) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.timeout)
+}
+
+// isNoSuchKey 判断是否为对象不存在的错误
+func isNoSuchKey(err error) bool {
+	return minio.ToErrorResponse(err).Code == "NoSuchKey"
+}
+
+func (s *s3Storage) Name() string {
+	return fmt.Sprintf("s3(%s/%s)", S3_ENDPOINT, s.bucket)
+}
+
+// objectKey 将相对路径转换为对象键
+func (s *s3Storage) objectKey(relativePath string) (string, error) {
+	cleaned, err := cleanStoragePath(relativePath)
+	if err != nil {
+		return "", err
+	}
+	if s.prefix == "" {
+		return cleaned, nil
+	}
+	return s.prefix + "/" + cleaned, nil
+}
+
+// relativePath 将对象键转换回相对路径
+func (s *s3Storage) relativePath(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, s.prefix+"/")
+}
+
+func (s *s3Storage) Save(ctx context.Context, localPath, relativePath string) error {
+	key, err := s.objectKey(relativePath)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.FPutObject(ctx, s.bucket, key, localPath, minio.PutObjectOptions{
+		ContentType: detectMimeType(localPath),
+	})
+	if err != nil {
+		return fmt.Errorf("上传文件到S3失败: %w", err)
+	}
+	loggerFrom(ctx).Debug("已上传文件", "src", localPath, "dst", fmt.Sprintf("s3://%s/%s", s.bucket, key))
+	return nil
+}
+
+func (s *s3Storage) SaveData(ctx context.Context, relativePath string, data []byte) error {
+	key, err := s.objectKey(relativePath)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err = s.client.PutObject(ctx, s.bucket, key, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: "application/json",
+	})
+	if err != nil {
+		return fmt.Errorf("上传数据到S3失败: %w", err)
+	}
+	return nil
+}
+
+func (s *s3Storage) Stat(ctx context.Context, relativePath string) (StoredFile, error) {
+	key, err := s.objectKey(relativePath)
+	if err != nil {
+		return StoredFile{}, err
+	}
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	info, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if isNoSuchKey(err) {
+			return StoredFile{}, ErrFileNotFound
+		}
+		return StoredFile{}, err
+	}
+	return StoredFile{Path: s.relativePath(info.Key), Size: info.Size, ModTime: info.LastModified}, nil
+}
+
+func (s *s3Storage) Open(ctx context.Context, relativePath string) (io.ReadCloser, error) {
+	// GetObject不会立即请求服务端，先确认对象存在，确认时受超时限制
+	if _, err := s.Stat(ctx, relativePath); err != nil {
+		return nil, err
+	}
+	key, err := s.objectKey(relativePath)
+	if err != nil {
+		return nil, err
+	}
+	return s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+}
+
+func (s *s3Storage) Delete(ctx context.Context, relativePath string) error {
+	// RemoveObject删除不存在的对象也会成功，先确认对象存在，与本地存储的行为一致
+	if _, err := s.Stat(ctx, relativePath); err != nil {
+		return err
+	}
+	key, err := s.objectKey(relativePath)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *s3Storage) Walk(ctx context.Context, prefix string, fn func(StoredFile) error) error {
+	opts := minio.ListObjectsOptions{Recursive: true, Prefix: strings.TrimPrefix(prefix, "/")}
+	if s.prefix != "" {
+		opts.Prefix = s.prefix + "/" + opts.Prefix
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for obj := range s.client.ListObjects(ctx, s.bucket, opts) {
+		if obj.Err != nil {
+			return obj.Err
+		}
+		if err := fn(StoredFile{Path: s.relativePath(obj.Key), Size: obj.Size, ModTime: obj.LastModified}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *s3Storage) PresignURL(ctx context.Context, relativePath, downloadName string, expiry time.Duration) (string, error) {
+	key, err := s.objectKey(relativePath)
+	if err != nil {
+		return "", err
+	}
+	params := url.Values{}
+	if downloadName != "" {
+		params.Set("response-content-disposition", fmt.Sprintf("attachment; filename=%q", downloadName))
+	}
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, key, expiry, params)
+	if err != nil {
+		return "", fmt.Errorf("生成预签名URL失败: %w", err)
+	}
+	return u.String(), nil
+}
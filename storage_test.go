@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 进程内的S3兼容服务，只实现存储后端用到的接口
+type fakeS3 struct {
+	mu      sync.Mutex
+	bucket  string
+	objects map[string][]byte
+	hang    bool // 为true时所有请求都不响应，用于测试超时
+}
+
+func newFakeS3(bucket string) *fakeS3 {
+	return &fakeS3{bucket: bucket, objects: map[string][]byte{}}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	hang := f.hang
+	f.mu.Unlock()
+	if hang {
+		// 读取请求体后服务端才能发现客户端已断开连接
+		io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		f.writeError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if key == "" {
+		switch {
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+			f.list(w, r.URL.Query().Get("prefix"))
+		default:
+			f.writeError(w, http.StatusNotImplemented, "NotImplemented")
+		}
+		return
+	}
+
+	data, exists := f.objects[key]
+	switch r.Method {
+	case http.MethodPut:
+		body, err := readS3Body(r)
+		if err != nil {
+			f.writeError(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		f.objects[key] = body
+		w.Header().Set("ETag", `"fake"`)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodHead, http.MethodGet:
+		if !exists {
+			f.writeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", `"fake"`)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case http.MethodDelete:
+		// 与S3相同，删除不存在的对象也返回成功
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.writeError(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+// readS3Body 读取上传的数据，minio-go通过HTTP上传时使用aws-chunked分块签名格式
+func readS3Body(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+	var data []byte
+	br := bufio.NewReader(r.Body)
+	for {
+		header, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(header), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		chunk := make([]byte, size+2) // 数据后跟\r\n
+		if _, err := io.ReadFull(br, chunk); err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return data, nil
+		}
+		data = append(data, chunk[:size]...)
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix string) {
+	type content struct {
+		Key          string
+		Size         int64
+		LastModified string
+		ETag         string
+	}
+	result := struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		Name        string
+		Prefix      string
+		KeyCount    int
+		MaxKeys     int
+		IsTruncated bool
+		Contents    []content
+	}{Name: f.bucket, Prefix: prefix, MaxKeys: 1000}
+
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		result.Contents = append(result.Contents, content{
+			Key:          key,
+			Size:         int64(len(f.objects[key])),
+			LastModified: time.Now().UTC().Format(time.RFC3339),
+			ETag:         `"fake"`,
+		})
+	}
+	result.KeyCount = len(result.Contents)
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+func (f *fakeS3) writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+	}{Code: code})
+}
+
+// newTestS3Storage 连接到S3兼容服务，设置S3_TEST_ENDPOINT时使用真实的服务（如本地MinIO），否则使用fakeS3
+func newTestS3Storage(t *testing.T) (*s3Storage, *fakeS3) {
+	t.Helper()
+	saved := []string{S3_ENDPOINT, S3_ACCESS_KEY, S3_SECRET_KEY, S3_BUCKET, S3_REGION, S3_PREFIX}
+	savedTimeout := S3_TIMEOUT_SECONDS
+	t.Cleanup(func() {
+		S3_ENDPOINT, S3_ACCESS_KEY, S3_SECRET_KEY, S3_BUCKET, S3_REGION, S3_PREFIX = saved[0], saved[1], saved[2], saved[3], saved[4], saved[5]
+		S3_TIMEOUT_SECONDS = savedTimeout
+	})
+
+	var fake *fakeS3
+	if endpoint := os.Getenv("S3_TEST_ENDPOINT"); endpoint != "" {
+		S3_ENDPOINT = endpoint
+		S3_ACCESS_KEY = os.Getenv("S3_TEST_ACCESS_KEY")
+		S3_SECRET_KEY = os.Getenv("S3_TEST_SECRET_KEY")
+		S3_BUCKET = getEnvString("S3_TEST_BUCKET", "libreoffice-api-test")
+	} else {
+		fake = newFakeS3("test-bucket")
+		server := httptest.NewServer(fake)
+		t.Cleanup(server.Close)
+		S3_ENDPOINT = strings.TrimPrefix(server.URL, "http://")
+		S3_ACCESS_KEY, S3_SECRET_KEY = "test", "testsecret"
+		S3_BUCKET = fake.bucket
+	}
+	S3_REGION = "us-east-1"
+	// 每个测试使用独立的前缀，连接真实服务时互不影响
+	S3_PREFIX = "test-" + strings.ReplaceAll(t.Name(), "/", "-") + "-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	S3_TIMEOUT_SECONDS = 5
+
+	storage, err := newS3Storage()
+	if err != nil {
+		t.Fatalf("创建S3存储失败: %v", err)
+	}
+	return storage, fake
+}
+
+// testStorageBackend 校验存储后端的公共行为，本地存储和S3存储应当一致
+func testStorageBackend(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	if err := s.SaveData(ctx, "tenants/a/20240101/report.pdf", []byte("pdf-data")); err != nil {
+		t.Fatalf("SaveData: %v", err)
+	}
+	src := filepath.Join(t.TempDir(), "source.txt")
+	if err := os.WriteFile(src, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(ctx, src, "20240101/hello.txt"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	info, err := s.Stat(ctx, "/tenants/a/20240101/report.pdf")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Path != "tenants/a/20240101/report.pdf" || info.Size != int64(len("pdf-data")) {
+		t.Errorf("Stat返回 %+v", info)
+	}
+
+	r, err := s.Open(ctx, "20240101/hello.txt")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil || string(data) != "hello" {
+		t.Errorf("Open读取到 %q, %v", data, err)
+	}
+
+	var paths []string
+	if err := s.Walk(ctx, "", func(f StoredFile) error {
+		paths = append(paths, f.Path)
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	sort.Strings(paths)
+	if strings.Join(paths, ",") != "20240101/hello.txt,tenants/a/20240101/report.pdf" {
+		t.Errorf("Walk返回 %v", paths)
+	}
+
+	for prefix, want := range map[string]string{
+		"tenants/a/":     "tenants/a/20240101/report.pdf",
+		"tenants/a/2024": "tenants/a/20240101/report.pdf",
+		"2024":           "20240101/hello.txt",
+		"tenants/b/":     "",
+		"missing/dir/":   "",
+	} {
+		var got []string
+		if err := s.Walk(ctx, prefix, func(f StoredFile) error {
+			got = append(got, f.Path)
+			return nil
+		}); err != nil {
+			t.Errorf("Walk(%q): %v", prefix, err)
+		}
+		if strings.Join(got, ",") != want {
+			t.Errorf("Walk(%q)返回 %v，期望 %q", prefix, got, want)
+		}
+	}
+
+	for _, p := range []string{"missing.pdf", "20240101/missing.txt"} {
+		if _, err := s.Stat(ctx, p); !errors.Is(err, ErrFileNotFound) {
+			t.Errorf("Stat(%s) = %v，应返回ErrFileNotFound", p, err)
+		}
+		if _, err := s.Open(ctx, p); !errors.Is(err, ErrFileNotFound) {
+			t.Errorf("Open(%s) = %v，应返回ErrFileNotFound", p, err)
+		}
+		if err := s.Delete(ctx, p); !errors.Is(err, ErrFileNotFound) {
+			t.Errorf("Delete(%s) = %v，应返回ErrFileNotFound", p, err)
+		}
+	}
+
+	if err := s.Delete(ctx, "20240101/hello.txt"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Stat(ctx, "20240101/hello.txt"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("删除后Stat = %v，应返回ErrFileNotFound", err)
+	}
+	if err := s.Delete(ctx, "20240101/hello.txt"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("重复删除 = %v，应返回ErrFileNotFound", err)
+	}
+
+	for _, p := range []string{"../secret", "a/../../secret", ""} {
+		if _, err := s.Stat(ctx, p); err == nil || errors.Is(err, ErrFileNotFound) {
+			t.Errorf("Stat(%q)应拒绝无效路径，返回 %v", p, err)
+		}
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	testStorageBackend(t, newLocalStorage(t.TempDir()))
+}
+
+func TestS3Storage(t *testing.T) {
+	s, _ := newTestS3Storage(t)
+	testStorageBackend(t, s)
+	t.Cleanup(func() {
+		s.Walk(context.Background(), "", func(f StoredFile) error {
+			return s.Delete(context.Background(), f.Path)
+		})
+	})
+}
+
+func TestS3StorageTimeout(t *testing.T) {
+	s, fake := newTestS3Storage(t)
+	if fake == nil {
+		t.Skip("只在使用fakeS3时测试超时")
+	}
+	s.timeout = 200 * time.Millisecond
+	fake.mu.Lock()
+	fake.hang = true
+	fake.mu.Unlock()
+
+	start := time.Now()
+	for name, call := range map[string]func() error{
+		"Stat":     func() error { _, err := s.Stat(context.Background(), "a.pdf"); return err },
+		"Delete":   func() error { return s.Delete(context.Background(), "a.pdf") },
+		"SaveData": func() error { return s.SaveData(context.Background(), "a.pdf", []byte("x")) },
+	} {
+		if err := call(); err == nil {
+			t.Errorf("%s在服务端无响应时应返回错误", name)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("请求没有按超时时间返回，耗时 %v", elapsed)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 租户配置
+var (
+	TENANTS_FILE string
+
+	tenants map[string]*Tenant
+)
+
+// Tenant 一个租户的配置，未设置的项使用全局配置
+type Tenant struct {
+	ID                   string   `json:"id"`
+	Name                 string   `json:"name,omitempty"`
+	StoragePrefix        string   `json:"storage_prefix,omitempty"`         // 存储路径前缀，默认为 tenants/<id>
+	MaxContentLength     int64    `json:"max_content_length,omitempty"`     // 最大上传文件大小（字节）
+	FileExpiryHours      int      `json:"file_expiry_hours,omitempty"`      // 文件过期时间（小时），-1表示永不过期
+	AllowedInputFormats  []string `json:"allowed_input_formats,omitempty"`  // 允许的输入格式，为空表示不额外限制
+	AllowedOutputFormats []string `json:"allowed_output_formats,omitempty"` // 允许的输出格式，为空表示不额外限制
+	MaxConcurrent        int      `json:"max_concurrent,omitempty"`         // 同时进行的转换数上限
+}
+
+// initTenantConfig 读取租户配置文件
+func initTenantConfig() {
+	TENANTS_FILE = getEnvString("TENANTS_FILE", "")
+	tenants = make(map[string]*Tenant)
+	if TENANTS_FILE == "" {
+		return
+	}
+
+	data, err := os.ReadFile(TENANTS_FILE)
+	if err != nil {
+		log.Fatalf("读取租户配置文件失败: %v", err)
+	}
+	var list []*Tenant
+	if err := json.Unmarshal(data, &list); err != nil {
+		log.Fatalf("解析租户配置文件失败: %v", err)
+	}
+
+	prefixes := make(map[string]string)
+	for _, t := range list {
+		if t.ID == "" || strings.ContainsAny(t.ID, "/\\") {
+			log.Fatalf("无效的租户id: %q", t.ID)
+		}
+		if _, ok := tenants[t.ID]; ok {
+			log.Fatalf("租户id重复: %s", t.ID)
+		}
+		if t.StoragePrefix == "" {
+			t.StoragePrefix = path.Join("tenants", t.ID)
+		}
+		prefix, err := cleanStoragePath(t.StoragePrefix)
+		if err != nil {
+			log.Fatalf("租户%s的存储路径前缀无效: %v", t.ID, err)
+		}
+		for other, otherPrefix := range prefixes {
+			if strings.HasPrefix(prefix+"/", otherPrefix+"/") || strings.HasPrefix(otherPrefix+"/", prefix+"/") {
+				log.Fatalf("租户%s和%s的存储路径前缀重叠", t.ID, other)
+			}
+		}
+		prefixes[t.ID] = prefix
+		t.StoragePrefix = prefix
+		t.AllowedInputFormats = normalizeFormats(t.AllowedInputFormats)
+		t.AllowedOutputFormats = normalizeFormats(t.AllowedOutputFormats)
+		tenants[t.ID] = t
+	}
+	log.Printf("已加载租户 %d 个", len(tenants))
+}
+
+// normalizeFormats 统一格式列表为不带点的小写扩展名
+func normalizeFormats(formats []string) []string {
+	var result []string
+	for _, f := range formats {
+		result = append(result, strings.TrimPrefix(strings.ToLower(f), "."))
+	}
+	return result
+}
+
+// lookupTenant 根据id查找租户
+func lookupTenant(id string) *Tenant {
+	return tenants[id]
+}
+
+// currentTenant 返回请求方所属的租户，nil表示默认租户
+func currentTenant(c *gin.Context) *Tenant {
+	if p := currentPrincipal(c); p != nil && p.Tenant != "" {
+		return lookupTenant(p.Tenant)
+	}
+	return nil
+}
+
+// tenantID 返回租户id，默认租户为空字符串
+func (t *Tenant) tenantID() string {
+	if t == nil {
+		return ""
+	}
+	return t.ID
+}
+
+// storagePrefix 返回租户的存储路径前缀，默认租户为空
+func (t *Tenant) storagePrefix() string {
+	if t == nil {
+		return ""
+	}
+	return t.StoragePrefix
+}
+
+// maxContentLength 返回租户的最大上传文件大小
+func (t *Tenant) maxContentLength() int64 {
+	if t == nil || t.MaxContentLength <= 0 {
+		return MAX_CONTENT_LENGTH
+	}
+	return t.MaxContentLength
+}
+
+// fileExpiryHours 返回租户的文件过期时间（小时），小于等于0表示永不过期
+func (t *Tenant) fileExpiryHours() int {
+	if t == nil || t.FileExpiryHours == 0 {
+		return FILE_EXPIRY_HOURS
+	}
+	return t.FileExpiryHours
+}
+
+// allowsInputFormat 判断租户是否允许该输入格式
+func (t *Tenant) allowsInputFormat(ext string) bool {
+	return t == nil || containsFormat(t.AllowedInputFormats, ext)
+}
+
+// allowsOutputFormat 判断租户是否允许该输出格式
+func (t *Tenant) allowsOutputFormat(ext string) bool {
+	return t == nil || containsFormat(t.AllowedOutputFormats, ext)
+}
+
+func containsFormat(allowed []string, ext string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	ext = strings.TrimPrefix(strings.ToLower(ext), ".")
+	for _, f := range allowed {
+		if f == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// tenantOfPath 返回存储路径所属的租户，nil表示默认租户
+func tenantOfPath(relativePath string) *Tenant {
+	for _, t := range tenants {
+		if strings.HasPrefix(relativePath, t.StoragePrefix+"/") {
+			return t
+		}
+	}
+	return nil
+}
+
+// tenantCanAccess 判断请求方是否可以访问该存储路径，不允许跨租户访问
+func tenantCanAccess(c *gin.Context, relativePath string) bool {
+	return tenantOfPath(relativePath).tenantID() == currentTenant(c).tenantID()
+}
+
+// validateTenant 校验请求方声明的租户是否存在
+func validateTenant(p *Principal) error {
+	if p.Tenant == "" || lookupTenant(p.Tenant) != nil {
+		return nil
+	}
+	return newLocalizedError("detail.unknown_tenant", p.Tenant)
+}
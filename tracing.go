@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 链路追踪导出方式
+const (
+	TracesExporterNone   = "none"   // 不导出，仍然传递请求中的trace上下文
+	TracesExporterOTLP   = "otlp"   // 通过OTLP/HTTP导出到采集器，地址等参数使用OTEL_EXPORTER_OTLP_*环境变量
+	TracesExporterStdout = "stdout" // 以JSON格式输出到标准输出，用于本地调试
+)
+
+// 链路追踪配置，使用OpenTelemetry规范中的环境变量名
+var (
+	OTEL_TRACES_EXPORTER string
+	OTEL_SERVICE_NAME    string
+
+	tracerProvider *sdktrace.TracerProvider
+	tracer         = otel.Tracer("libreoffice-api")
+)
+
+// initTracingConfig 读取链路追踪相关的环境变量并设置全局TracerProvider
+func initTracingConfig() {
+	OTEL_TRACES_EXPORTER = strings.ToLower(getEnvString("OTEL_TRACES_EXPORTER", TracesExporterNone))
+	OTEL_SERVICE_NAME = getEnvString("OTEL_SERVICE_NAME", "libreoffice-api")
+
+	// 无论是否导出都解析和传递W3C traceparent，日志中可以记录上游的trace_id
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch OTEL_TRACES_EXPORTER {
+	case TracesExporterNone:
+		return
+	case TracesExporterOTLP:
+		exporter, err = otlptracehttp.New(context.Background())
+	case TracesExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		log.Fatalf("无效的OTEL_TRACES_EXPORTER: %q，可选值为none、otlp、stdout", OTEL_TRACES_EXPORTER)
+	}
+	if err != nil {
+		log.Fatalf("创建链路追踪导出器失败: %v", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(OTEL_SERVICE_NAME),
+		attribute.String("libreoffice.version", libreofficeVersion),
+	))
+	if err != nil {
+		log.Fatalf("创建链路追踪资源失败: %v", err)
+	}
+	// 采样策略可以通过OTEL_TRACES_SAMPLER和OTEL_TRACES_SAMPLER_ARG配置，默认跟随上游，没有上游时全部采样
+	tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	log.Printf("已启用链路追踪，导出方式: %s", OTEL_TRACES_EXPORTER)
+}
+
+// shutdownTracing 导出剩余的span并关闭TracerProvider
+func shutdownTracing(ctx context.Context) {
+	if tracerProvider == nil {
+		return
+	}
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		log.Printf("关闭链路追踪失败: %v", err)
+	}
+}
+
+// tracingMiddleware 从请求头中提取trace上下文，并为每个请求创建服务端span
+func tracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := tracer.Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+				semconv.ClientAddress(c.ClientIP()),
+				attribute.String("request_id", requestID(c)),
+			),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if p := currentPrincipal(c); p != nil {
+			span.SetAttributes(attribute.String("client.id", p.ID))
+		}
+		if status >= 500 {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+		}
+	}
+}
+
+// startSpan 在当前请求的trace中创建子span
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan 结束span，err不为空时记录错误
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
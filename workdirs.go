@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 临时目录中由本服务创建的子目录前缀
+const (
+	workDirPrefix    = "work_"    // 每次转换的工作目录
+	profileDirPrefix = "profile_" // 每次转换独立的LibreOffice用户配置目录
+	sandboxDirPrefix = "sandbox_" // 每次转换的soffice沙箱根目录
+)
+
+var (
+	WORK_DIR_STALE_MINUTES int
+	// 实例标识，写入临时目录名和soffice的用户配置路径，清理时只处理本实例创建的目录和进程
+	INSTANCE_ID string
+
+	// 正在进行的转换，键为转换ID
+	activeWork sync.Map
+)
+
+// initWorkDirConfig 读取临时目录清理相关的环境变量
+func initWorkDirConfig() {
+	WORK_DIR_STALE_MINUTES = getEnvInt("WORK_DIR_STALE_MINUTES", 120)
+	if WORK_DIR_STALE_MINUTES <= 0 {
+		WORK_DIR_STALE_MINUTES = 120
+	}
+
+	// 默认使用主机名，容器重启后不变，启动时可以清理上次运行遗留的目录和进程；
+	// 同一主机上共用TMP_DIR的多个实例需要配置不同的INSTANCE_ID
+	hostname, _ := os.Hostname()
+	INSTANCE_ID = sanitizeInstanceID(getEnvString("INSTANCE_ID", hostname))
+}
+
+// sanitizeInstanceID 将实例标识转换为可以用在目录名中的形式，"_"用于分隔实例标识和转换ID
+func sanitizeInstanceID(id string) string {
+	id = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.' {
+			return r
+		}
+		return '-'
+	}, id)
+	if id == "" {
+		return "default"
+	}
+	return id
+}
+
+// workDirName 生成临时目录名，格式为 <前缀><实例标识>_<转换ID>
+func workDirName(prefix, id string) string {
+	return prefix + INSTANCE_ID + "_" + id
+}
+
+// workDirFor 返回转换ID对应的工作目录
+func workDirFor(id string) string {
+	return filepath.Join(TMP_DIR, workDirName(workDirPrefix, id))
+}
+
+// profileDirFor 返回转换ID对应的LibreOffice用户配置目录
+func profileDirFor(id string) string {
+	return filepath.Join(TMP_DIR, workDirName(profileDirPrefix, id))
+}
+
+// profileInstallationArg 生成让soffice使用独立用户配置目录的参数
+func profileInstallationArg(id string) string {
+	p := filepath.ToSlash(profileDirFor(id))
+	if !strings.HasPrefix(p, "/") {
+		// Windows路径需要写成 file:///C:/...
+		p = "/" + p
+	}
+	u := url.URL{Scheme: "file", Path: p}
+	return "-env:UserInstallation=" + u.String()
+}
+
+// beginWork 登记一次转换并创建工作目录，清理任务不会删除登记中的目录
+func beginWork(id string) (string, error) {
+	activeWork.Store(id, time.Now())
+	workDir := workDirFor(id)
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		activeWork.Delete(id)
+		return "", err
+	}
+	return workDir, nil
+}
+
+// endWork 删除转换的工作目录、用户配置目录和沙箱根目录并取消登记
+func endWork(ctx context.Context, id string) {
+	defer activeWork.Delete(id)
+	for _, dir := range []string{workDirFor(id), profileDirFor(id), sandboxRootFor(id)} {
+		if err := os.RemoveAll(dir); err != nil {
+			loggerFrom(ctx).Warn("清理临时目录时出错", "dir", dir, "error", err)
+		} else {
+			loggerFrom(ctx).Debug("已清理临时目录", "dir", dir)
+		}
+	}
+}
+
+// isActiveWork 判断转换是否仍在进行
+func isActiveWork(id string) bool {
+	_, ok := activeWork.Load(id)
+	return ok
+}
+
+// workIDFromDirName 从本实例创建的临时目录名中解析转换ID，其他实例的目录返回false
+func workIDFromDirName(name string) (string, bool) {
+	for _, prefix := range []string{workDirPrefix, profileDirPrefix, sandboxDirPrefix} {
+		if id, ok := strings.CutPrefix(name, workDirName(prefix, "")); ok && id != "" {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// sweepOrphanedWork 清理本实例异常退出后遗留的工作目录、用户配置目录和soffice进程
+// 启动时清理本实例所有未登记的目录和进程，运行中只清理超过WORK_DIR_STALE_MINUTES的目录和进程
+func sweepOrphanedWork(startup bool) {
+	var staleBefore time.Time
+	if !startup {
+		staleBefore = time.Now().Add(-time.Duration(WORK_DIR_STALE_MINUTES) * time.Minute)
+	}
+
+	// profileInstallationArg("")是本实例所有soffice进程用户配置参数的公共前缀
+	killed := killOrphanedSoffice(profileInstallationArg(""), isActiveWork, staleBefore)
+	for _, pid := range killed {
+		log.Printf("已终止遗留的soffice进程: pid=%d", pid)
+	}
+
+	entries, err := os.ReadDir(TMP_DIR)
+	if err != nil {
+		log.Printf("读取临时目录失败: %v", err)
+		return
+	}
+
+	var removed int
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		id, ok := workIDFromDirName(entry.Name())
+		if !ok || isActiveWork(id) {
+			continue
+		}
+		if !startup {
+			info, err := entry.Info()
+			if err != nil || info.ModTime().After(staleBefore) {
+				continue
+			}
+		}
+		dir := filepath.Join(TMP_DIR, entry.Name())
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("删除遗留临时目录失败: %s, %v", dir, err)
+			continue
+		}
+		removed++
+		log.Printf("已删除遗留临时目录: %s", dir)
+	}
+
+	if removed > 0 || len(killed) > 0 {
+		log.Printf("遗留临时文件清理完成: 删除目录 %d 个, 终止进程 %d 个", removed, len(killed))
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// withTestWorkDirs 使用临时目录作为TMP_DIR并设置实例标识，测试结束后恢复
+func withTestWorkDirs(t *testing.T, instanceID string) {
+	t.Helper()
+	savedTmp, savedInstance, savedStale := TMP_DIR, INSTANCE_ID, WORK_DIR_STALE_MINUTES
+	TMP_DIR, INSTANCE_ID, WORK_DIR_STALE_MINUTES = t.TempDir(), instanceID, 120
+	t.Cleanup(func() {
+		TMP_DIR, INSTANCE_ID, WORK_DIR_STALE_MINUTES = savedTmp, savedInstance, savedStale
+	})
+}
+
+func TestSanitizeInstanceID(t *testing.T) {
+	tests := map[string]string{
+		"web-1":             "web-1",
+		"pod_a.example.com": "pod-a.example.com",
+		"a b/c":             "a-b-c",
+		"":                  "default",
+	}
+	for in, want := range tests {
+		if got := sanitizeInstanceID(in); got != want {
+			t.Errorf("sanitizeInstanceID(%q) = %q，期望 %q", in, got, want)
+		}
+	}
+}
+
+func TestWorkIDFromDirName(t *testing.T) {
+	withTestWorkDirs(t, "web-1")
+	tests := []struct {
+		name   string
+		wantID string
+		wantOK bool
+	}{
+		{"work_web-1_123e4567", "123e4567", true},
+		{"profile_web-1_123e4567", "123e4567", true},
+		{"sandbox_web-1_123e4567", "123e4567", true},
+		{"work_web-2_123e4567", "", false},  // 其他实例
+		{"work_web-10_123e4567", "", false}, // 实例标识以本实例标识开头
+		{"work_123e4567", "", false},        // 没有实例标识的旧目录
+		{"work_web-1_", "", false},
+		{"uploads", "", false},
+	}
+	for _, tt := range tests {
+		id, ok := workIDFromDirName(tt.name)
+		if id != tt.wantID || ok != tt.wantOK {
+			t.Errorf("workIDFromDirName(%q) = %q, %v，期望 %q, %v", tt.name, id, ok, tt.wantID, tt.wantOK)
+		}
+	}
+}
+
+func TestSweepOrphanedWork(t *testing.T) {
+	withTestWorkDirs(t, "web-1")
+	old := time.Now().Add(-3 * time.Hour)
+	dirs := map[string]time.Time{
+		"work_web-1_orphan-old":    old,
+		"profile_web-1_orphan-old": old,
+		"work_web-1_orphan-new":    time.Now(),
+		"work_web-1_active":        old,
+		"work_web-2_other":         old,
+		"profile_web-2_other":      old,
+	}
+	for name, modTime := range dirs {
+		dir := filepath.Join(TMP_DIR, name)
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(dir, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	activeWork.Store("active", time.Now())
+	t.Cleanup(func() { activeWork.Delete("active") })
+
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(TMP_DIR, name))
+		return err == nil
+	}
+
+	// 运行中只清理本实例超时的目录
+	sweepOrphanedWork(false)
+	for name, want := range map[string]bool{
+		"work_web-1_orphan-old":    false,
+		"profile_web-1_orphan-old": false,
+		"work_web-1_orphan-new":    true,
+		"work_web-1_active":        true,
+		"work_web-2_other":         true,
+		"profile_web-2_other":      true,
+	} {
+		if exists(name) != want {
+			t.Errorf("定期清理后 %s 存在=%v，期望 %v", name, exists(name), want)
+		}
+	}
+
+	// 启动时清理本实例所有未登记的目录，仍不处理其他实例的目录
+	sweepOrphanedWork(true)
+	for name, want := range map[string]bool{
+		"work_web-1_orphan-new": false,
+		"work_web-1_active":     true,
+		"work_web-2_other":      true,
+		"profile_web-2_other":   true,
+	} {
+		if exists(name) != want {
+			t.Errorf("启动清理后 %s 存在=%v，期望 %v", name, exists(name), want)
+		}
+	}
+}